@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/app"
+	"github.com/Danny-Dasilva/gdiff/internal/config"
+	"github.com/Danny-Dasilva/gdiff/internal/git"
+	"github.com/charmbracelet/x/term"
+)
+
+// comparePaths reports whether the arguments name two existing files or
+// directories to compare outside git, as in gdiff a.txt b.txt
+func comparePaths(args []string) bool {
+	if len(args) != 2 {
+		return false
+	}
+	for _, path := range args {
+		if _, err := os.Stat(path); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// runCompare browses the differences between two files or directories
+// read-only, which needs no repository and lets gdiff serve as a git
+// difftool. Like the pager, it prints the diff when stdout is not a
+// terminal or with --print.
+func runCompare(colorblind, printOnly bool, cfg config.Config, a, b string) error {
+	ctx := context.Background()
+	opts := git.DefaultDiffOptions()
+
+	interactive := term.IsTerminal(os.Stdout.Fd()) && os.Getenv("TERM") != "dumb"
+	if printOnly || !interactive {
+		out, err := git.NoIndexDiff(ctx, a, b, opts)
+		if err != nil {
+			return err
+		}
+		return printDiff(os.Stdout, out, interactive && os.Getenv("NO_COLOR") == "")
+	}
+
+	diffs, err := git.CompareNoIndex(ctx, a, b, opts)
+	if err != nil {
+		return err
+	}
+	if len(diffs) == 0 {
+		fmt.Fprintf(os.Stderr, "%s and %s are identical\n", a, b)
+		return nil
+	}
+
+	_, err = tea.NewProgram(app.NewStatic(colorblind, cfg, diffs, a+" ↔ "+b)).Run()
+	return err
+}
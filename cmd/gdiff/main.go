@@ -5,15 +5,39 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	tea "charm.land/bubbletea/v2"
 	"github.com/Danny-Dasilva/gdiff/internal/app"
+	"github.com/Danny-Dasilva/gdiff/internal/config"
 	"github.com/Danny-Dasilva/gdiff/internal/git"
 )
 
+// subcommands run without the TUI, for scripts and CI
+var subcommands = map[string]func(args []string) error{
+	"show":    runShow,
+	"stage":   func(args []string) error { return runStage("stage", args) },
+	"unstage": func(args []string) error { return runStage("unstage", args) },
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				if err != flag.ErrHelp {
+					fmt.Fprintln(os.Stderr, "Error:", err)
+				}
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	colorblind := flag.Bool("colorblind", false, "use blue/orange colors instead of red/green for colorblind accessibility")
 	directory := flag.String("C", "", "run as if started in this directory")
+	printOnly := flag.Bool("print", false, "print the diff (piped on stdin or between two paths) instead of browsing it")
+	pickMode := flag.Bool("pick", false, "pick files or hunks and print them instead of staging")
+	pickFormat := flag.String("pick-format", "paths", "output of --pick: "+strings.Join(app.PickFormats, ", "))
 	flag.Parse()
 
 	if *directory != "" {
@@ -23,12 +47,52 @@ func main() {
 		}
 	}
 
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: ignoring config:", err)
+		cfg = config.DefaultConfig()
+	}
+
+	// Two paths are compared outside git, as by git difftool
+	if comparePaths(flag.Args()) {
+		if err := runCompare(*colorblind, *printOnly, cfg, flag.Arg(0), flag.Arg(1)); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Pick mode prints the chosen files or hunks for shell pipelines
+	if *pickMode {
+		if !git.IsGitRepo(context.Background()) {
+			fmt.Fprintln(os.Stderr, "Error: not a git repository")
+			os.Exit(1)
+		}
+		if err := runPick(*colorblind, cfg, *pickFormat); err != nil {
+			if err != errNothingPicked {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+
+	// A diff piped in, e.g. from git log -p with gdiff as core.pager, is
+	// browsed read-only and needs no repository
+	if stdinIsPiped() {
+		if err := runPager(*colorblind, *printOnly, cfg); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if !git.IsGitRepo(context.Background()) {
 		fmt.Fprintln(os.Stderr, "Error: not a git repository")
 		os.Exit(1)
 	}
 
-	if _, err := tea.NewProgram(app.New(*colorblind)).Run(); err != nil {
+	if _, err := tea.NewProgram(app.NewWithConfig(*colorblind, cfg)).Run(); err != nil {
 		fmt.Fprintln(os.Stderr, "Error:", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/app"
+	"github.com/Danny-Dasilva/gdiff/internal/config"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/term"
+)
+
+// stdinIsPiped reports whether stdin is a pipe or file rather than a
+// terminal or device, as when gdiff runs as git's pager
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
+
+// runPager browses a diff read from stdin, for use as core.pager,
+// pager.diff or interactive.diffFilter. Input that holds no diff, output
+// that is not a terminal and dumb terminals get the input printed back
+// instead, keeping its lines one for one as diffFilter requires.
+func runPager(colorblind, printOnly bool, cfg config.Config) error {
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("reading stdin: %w", err)
+	}
+	text := string(input)
+
+	interactive := term.IsTerminal(os.Stdout.Fd()) && os.Getenv("TERM") != "dumb"
+	color := interactive && os.Getenv("NO_COLOR") == ""
+	diffs := diff.Parse(ansi.Strip(text))
+	if printOnly || len(diffs) == 0 || !interactive {
+		return printDiff(os.Stdout, text, color)
+	}
+
+	// stdin is taken by the diff, so keys come from the terminal itself
+	tty, _, err := tea.OpenTTY()
+	if err != nil {
+		return printDiff(os.Stdout, text, color)
+	}
+	defer tty.Close()
+
+	_, err = tea.NewProgram(app.NewStatic(colorblind, cfg, diffs, "stdin"), tea.WithInput(tty)).Run()
+	return err
+}
+
+// printDiff writes a diff back out line for line, coloring it when color
+// is wanted and the input is not colored already
+func printDiff(w io.Writer, text string, color bool) error {
+	if !color || strings.Contains(text, "\x1b[") {
+		_, err := io.WriteString(w, text)
+		return err
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if sgr := lineColor(line); sgr != "" && line != "" {
+			lines[i] = "\x1b[" + sgr + "m" + line + "\x1b[m"
+		}
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// lineColor returns the SGR parameters git uses for a diff line
+func lineColor(line string) string {
+	switch {
+	case strings.HasPrefix(line, "diff "), strings.HasPrefix(line, "index "),
+		strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "):
+		return "1"
+	case strings.HasPrefix(line, "commit "):
+		return "33"
+	case strings.HasPrefix(line, "@@"):
+		return "36"
+	case strings.HasPrefix(line, "+"):
+		return "32"
+	case strings.HasPrefix(line, "-"):
+		return "31"
+	}
+	return ""
+}
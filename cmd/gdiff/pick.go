@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/app"
+	"github.com/Danny-Dasilva/gdiff/internal/config"
+)
+
+// errNothingPicked ends pick mode with a non-zero status when the user
+// quits without choosing, so vim $(gdiff --pick) can be guarded with &&
+var errNothingPicked = fmt.Errorf("nothing picked")
+
+// runPick runs the TUI on the terminal itself, keeping stdout free for the
+// picked files or hunks in format, one of app.PickFormats
+func runPick(colorblind bool, cfg config.Config, format string) error {
+	if !slices.Contains(app.PickFormats, format) {
+		return fmt.Errorf("unknown pick format %q (want %s)", format, strings.Join(app.PickFormats, ", "))
+	}
+
+	in, out, err := tea.OpenTTY()
+	if err != nil {
+		return fmt.Errorf("pick mode needs a terminal: %w", err)
+	}
+	defer in.Close()
+	defer out.Close()
+
+	final, err := tea.NewProgram(app.NewPick(colorblind, cfg), tea.WithInput(in), tea.WithOutput(out)).Run()
+	if err != nil {
+		return err
+	}
+	m, ok := final.(app.Model)
+	if !ok || !m.Picked() {
+		return errNothingPicked
+	}
+
+	text, err := m.PickOutput(context.Background(), format)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.WriteString(text)
+	return err
+}
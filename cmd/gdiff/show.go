@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Danny-Dasilva/gdiff/internal/git"
+	"github.com/Danny-Dasilva/gdiff/internal/render"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// runShow implements gdiff show, which renders a diff without the TUI for
+// CI logs, code review tools and scripts:
+//
+//	gdiff show [--render=ansi|html|json] [--width N] [--staged] [-|rev|A..B] [-- paths]
+//
+// The diff comes from the revision or range given, otherwise from the
+// working tree or, with --staged, the index. "-" reads it from stdin, as
+// does a piped stdin when nothing else selects a diff.
+func runShow(args []string) error {
+	fs := flag.NewFlagSet("show", flag.ContinueOnError)
+	format := fs.String("render", "ansi", "output format: "+strings.Join(render.Formats, ", "))
+	width := fs.Int("width", render.DefaultWidth, "total width of ansi output")
+	staged := fs.Bool("staged", false, "show staged changes instead of the working tree")
+	colorblind := fs.Bool("colorblind", false, "use blue/orange colors instead of red/green")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gdiff show [flags] [-|rev|A..B] [-- paths]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !slices.Contains(render.Formats, *format) {
+		return fmt.Errorf("unknown format %q (want %s)", *format, strings.Join(render.Formats, ", "))
+	}
+
+	src, err := parseShowArgs(fs.Args(), *staged, stdinIsPiped())
+	if err != nil {
+		return err
+	}
+	diffs, title, err := showDiffs(src, *staged)
+	if err != nil {
+		return err
+	}
+	return render.Render(os.Stdout, *format, diffs, render.Options{
+		Width:      *width,
+		Colorblind: *colorblind,
+		Title:      title,
+	})
+}
+
+// showSource is where gdiff show takes its diff from
+type showSource struct {
+	stdin bool
+	rev   string
+	paths []string
+}
+
+// parseShowArgs picks the diff source from the positional arguments. A
+// piped stdin is only read when no revision, path or --staged is given,
+// so scripts and CI jobs whose stdin is an open pipe still get the
+// revision they ask for.
+func parseShowArgs(rest []string, staged, piped bool) (showSource, error) {
+	// flag stops at the first positional argument, so "--" may still be
+	// among the rest when a revision comes first
+	var src showSource
+	if i := slices.Index(rest, "--"); i >= 0 {
+		rest, src.paths = rest[:i], rest[i+1:]
+	}
+	switch len(rest) {
+	case 0:
+	case 1:
+		src.rev = rest[0]
+	default:
+		src.rev, src.paths = rest[0], append(rest[1:], src.paths...)
+	}
+
+	if src.rev == "-" {
+		if staged || len(src.paths) > 0 {
+			return showSource{}, fmt.Errorf("- reads a diff from stdin and takes no paths or --staged")
+		}
+		return showSource{stdin: true}, nil
+	}
+	src.stdin = piped && src.rev == "" && len(src.paths) == 0 && !staged
+	return src, nil
+}
+
+// showDiffs loads the diff for gdiff show and a title naming its source
+func showDiffs(src showSource, staged bool) ([]diff.FileDiff, string, error) {
+	if src.stdin {
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, "", fmt.Errorf("reading stdin: %w", err)
+		}
+		return diff.Parse(ansi.Strip(string(input))), "stdin", nil
+	}
+
+	ctx := context.Background()
+	if !git.IsGitRepo(ctx) {
+		return nil, "", fmt.Errorf("not a git repository")
+	}
+	opts := git.DefaultDiffOptions()
+	if src.rev != "" {
+		diffs, err := git.GetRevisionDiff(ctx, src.rev, opts, src.paths...)
+		return diffs, src.rev, err
+	}
+
+	title := "working tree"
+	if staged {
+		title = "staged changes"
+	}
+	if len(src.paths) == 0 {
+		diffs, err := git.GetAllDiffs(ctx, staged, opts)
+		return diffs, title, err
+	}
+	var diffs []diff.FileDiff
+	for _, path := range src.paths {
+		fds, err := git.GetFileDiff(ctx, path, staged, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		diffs = append(diffs, fds...)
+	}
+	return diffs, title, nil
+}
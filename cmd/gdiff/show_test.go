@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseShowArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		staged  bool
+		piped   bool
+		want    showSource
+		wantErr bool
+	}{
+		{name: "working tree", want: showSource{}},
+		{name: "piped diff", piped: true, want: showSource{stdin: true}},
+		{name: "revision beats a piped stdin", args: []string{"HEAD~1"}, piped: true, want: showSource{rev: "HEAD~1"}},
+		{name: "paths beat a piped stdin", args: []string{"--", "a.go"}, piped: true, want: showSource{paths: []string{"a.go"}}},
+		{name: "staged beats a piped stdin", staged: true, piped: true, want: showSource{}},
+		{name: "range and paths", args: []string{"main..feature", "--", "a.go", "b.go"}, want: showSource{rev: "main..feature", paths: []string{"a.go", "b.go"}}},
+		{name: "paths without --", args: []string{"HEAD", "a.go"}, want: showSource{rev: "HEAD", paths: []string{"a.go"}}},
+		{name: "explicit stdin", args: []string{"-"}, want: showSource{stdin: true}},
+		{name: "stdin takes no paths", args: []string{"-", "--", "a.go"}, wantErr: true},
+		{name: "stdin takes no --staged", args: []string{"-"}, staged: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseShowArgs(tt.args, tt.staged, tt.piped)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseShowArgs(%q) = %+v, want an error", tt.args, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseShowArgs(%q) = %+v, want %+v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
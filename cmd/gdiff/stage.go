@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Danny-Dasilva/gdiff/internal/git"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+)
+
+// stagePatch is one patch of a staging command, with the call that applies it
+type stagePatch struct {
+	text  string
+	apply func(ctx context.Context) error
+}
+
+// runStage implements gdiff stage and gdiff unstage, which stage or unstage
+// part of a file from scripts and editor integrations:
+//
+//	gdiff stage FILE [--hunk N | --lines A-B | --match RE | --chars L:S-E] [--dry-run]
+//
+// Hunks count from 1 in the file's diff. Line numbers are those of the new
+// side: the working tree when staging, the index when unstaging. --chars
+// selects columns S to E, counted from 1, of added line L. Without a
+// selector the whole file is staged or unstaged.
+func runStage(name string, args []string) error {
+	unstage := name == "unstage"
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	hunkNum := fs.Int("hunk", 0, "`N`th hunk of the file, counting from 1")
+	lines := fs.String("lines", "", "changed lines within new-file lines `A-B` (or a single line A)")
+	match := fs.String("match", "", "changed lines matching the regular expression `RE`")
+	chars := fs.String("chars", "", "columns S to E of added line L, as `L:S-E`")
+	dryRun := fs.Bool("dry-run", false, "print the patch and check it applies, without applying it")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gdiff %s FILE [--hunk N | --lines A-B | --match RE | --chars L:S-E] [--dry-run]\n", name)
+		fs.PrintDefaults()
+	}
+
+	// Accept flags before and after the file name
+	var files []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		files = append(files, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(files) != 1 {
+		fs.Usage()
+		return fmt.Errorf("%s takes exactly one file", name)
+	}
+	path := files[0]
+
+	selectors := 0
+	for _, set := range []bool{*hunkNum != 0, *lines != "", *match != "", *chars != ""} {
+		if set {
+			selectors++
+		}
+	}
+	if selectors > 1 {
+		return fmt.Errorf("use only one of --hunk, --lines, --match and --chars")
+	}
+	if *chars != "" && unstage {
+		return fmt.Errorf("--chars can only stage")
+	}
+
+	ctx := context.Background()
+	if !git.IsGitRepo(ctx) {
+		return fmt.Errorf("not a git repository")
+	}
+
+	if selectors == 0 {
+		if *dryRun {
+			fmt.Printf("would %s %s\n", name, path)
+			return nil
+		}
+		if unstage {
+			return git.UnstageFile(ctx, path)
+		}
+		return git.StageFile(ctx, path)
+	}
+
+	diffs, err := git.GetFileDiff(ctx, path, unstage, git.DefaultDiffOptions())
+	if err != nil {
+		return err
+	}
+	if len(diffs) == 0 || len(diffs[0].Hunks) == 0 {
+		if unstage {
+			return fmt.Errorf("%s has no staged changes", path)
+		}
+		return fmt.Errorf("%s has no unstaged changes", path)
+	}
+	fd := diffs[0]
+
+	var patches []stagePatch
+	switch {
+	case *hunkNum != 0:
+		patches, err = hunkPatches(fd, *hunkNum, unstage)
+	case *lines != "":
+		patches, err = rangePatches(fd, *lines, unstage)
+	case *match != "":
+		patches, err = matchPatches(fd, *match, unstage)
+	case *chars != "":
+		patches, err = charPatches(fd, *chars)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		for _, p := range patches {
+			fmt.Print(p.text)
+			if err := git.CheckPatch(ctx, p.text, unstage); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Apply from the bottom of the file up so earlier hunks keep their
+	// line numbers
+	for i := len(patches) - 1; i >= 0; i-- {
+		if err := patches[i].apply(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hunkPatches selects the nth hunk of a file
+func hunkPatches(fd diff.FileDiff, n int, unstage bool) ([]stagePatch, error) {
+	if n < 1 || n > len(fd.Hunks) {
+		return nil, fmt.Errorf("%s has %d hunks, not %d", fd.NewPath, len(fd.Hunks), n)
+	}
+	hunk := fd.Hunks[n-1]
+	return []stagePatch{{
+		text: git.HunkPatch(fd.NewPath, hunk),
+		apply: func(ctx context.Context) error {
+			if unstage {
+				return git.UnstageHunk(ctx, fd.NewPath, hunk)
+			}
+			return git.StageHunk(ctx, fd.NewPath, hunk)
+		},
+	}}, nil
+}
+
+// rangePatches selects the changed lines within a new-file line range
+func rangePatches(fd diff.FileDiff, spec string, unstage bool) ([]stagePatch, error) {
+	from, to, err := parseRange(spec)
+	if err != nil {
+		return nil, fmt.Errorf("--lines: %w", err)
+	}
+	return linePatches(fd, unstage, func(h diff.Hunk) []int {
+		return diff.SelectRange(h, from, to)
+	}, fmt.Sprintf("lines %s", spec))
+}
+
+// matchPatches selects the changed lines matching a regular expression
+func matchPatches(fd diff.FileDiff, expr string, unstage bool) ([]stagePatch, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("--match: %w", err)
+	}
+	return linePatches(fd, unstage, func(h diff.Hunk) []int {
+		return diff.SelectMatching(h, re)
+	}, fmt.Sprintf("lines matching %q", expr))
+}
+
+// linePatches builds a patch for each hunk with selected lines
+func linePatches(fd diff.FileDiff, unstage bool, selectLines func(diff.Hunk) []int, what string) ([]stagePatch, error) {
+	var patches []stagePatch
+	for _, hunk := range fd.Hunks {
+		indices := selectLines(hunk)
+		if len(indices) == 0 {
+			continue
+		}
+		patches = append(patches, stagePatch{
+			text: git.LinesPatch(fd.NewPath, hunk, indices, unstage),
+			apply: func(ctx context.Context) error {
+				if unstage {
+					return git.UnstageLines(ctx, fd.NewPath, hunk, indices)
+				}
+				return git.StageLines(ctx, fd.NewPath, hunk, indices)
+			},
+		})
+	}
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no changed %s in %s", what, fd.NewPath)
+	}
+	return patches, nil
+}
+
+// charPatches selects columns of one added line
+func charPatches(fd diff.FileDiff, spec string) ([]stagePatch, error) {
+	lineSpec, colSpec, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("--chars: want L:S-E, got %q", spec)
+	}
+	num, err := strconv.Atoi(lineSpec)
+	if err != nil {
+		return nil, fmt.Errorf("--chars: bad line %q", lineSpec)
+	}
+	start, end, err := parseRange(colSpec)
+	if err != nil {
+		return nil, fmt.Errorf("--chars: %w", err)
+	}
+
+	for _, hunk := range fd.Hunks {
+		idx := diff.FindAddedLine(hunk, num)
+		if idx < 0 {
+			continue
+		}
+		// Columns count from 1 and include E; staging takes [start, end)
+		charStart, charEnd := start-1, end
+		if charEnd > len([]rune(hunk.Lines[idx].Content)) {
+			return nil, fmt.Errorf("--chars: line %d has only %d columns", num, len([]rune(hunk.Lines[idx].Content)))
+		}
+		return []stagePatch{{
+			text: git.BuildCharacterPatch(fd.NewPath, hunk, idx, charStart, charEnd),
+			apply: func(ctx context.Context) error {
+				return git.StageCharacters(ctx, fd.NewPath, hunk, idx, charStart, charEnd)
+			},
+		}}, nil
+	}
+	return nil, fmt.Errorf("--chars: line %d of %s is not an added line", num, fd.NewPath)
+}
+
+// parseRange parses "A-B" or "A" into a range of positive numbers
+func parseRange(spec string) (int, int, error) {
+	fromSpec, toSpec, ok := strings.Cut(spec, "-")
+	if !ok {
+		toSpec = fromSpec
+	}
+	from, err1 := strconv.Atoi(fromSpec)
+	to, err2 := strconv.Atoi(toSpec)
+	if err1 != nil || err2 != nil || from < 1 || to < from {
+		return 0, 0, fmt.Errorf("bad range %q, want A-B with 1 <= A <= B", spec)
+	}
+	return from, to, nil
+}
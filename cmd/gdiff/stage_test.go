@@ -0,0 +1,177 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/Danny-Dasilva/gdiff/internal/testutil"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+)
+
+// TestMain runs gdiff's main instead of the tests when GDIFF_RUN_MAIN is
+// set, so tests can check the exit codes of subcommands
+func TestMain(m *testing.M) {
+	if os.Getenv("GDIFF_RUN_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		spec     string
+		from, to int
+		wantErr  bool
+	}{
+		{spec: "3", from: 3, to: 3},
+		{spec: "2-5", from: 2, to: 5},
+		{spec: "4-4", from: 4, to: 4},
+		{spec: "0-2", wantErr: true},
+		{spec: "5-2", wantErr: true},
+		{spec: "-3", wantErr: true},
+		{spec: "2-", wantErr: true},
+		{spec: "a-b", wantErr: true},
+		{spec: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			from, to, err := parseRange(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseRange(%q) = %d, %d, want an error", tt.spec, from, to)
+				}
+				return
+			}
+			if err != nil || from != tt.from || to != tt.to {
+				t.Errorf("parseRange(%q) = %d, %d, %v, want %d, %d", tt.spec, from, to, err, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestCharPatchesErrors(t *testing.T) {
+	fd := diff.Parse(`diff --git a/f.txt b/f.txt
+--- a/f.txt
++++ b/f.txt
+@@ -1,3 +1,3 @@
+ a
+-hello world
++hello there world
+ b
+`)[0]
+
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr string
+	}{
+		{name: "past the end", spec: "2:10-18", wantErr: "only 17 columns"},
+		{name: "context line", spec: "1:1-1", wantErr: "not an added line"},
+		{name: "column zero", spec: "2:0-3", wantErr: "bad range"},
+		{name: "bad line", spec: "x:1-2", wantErr: "bad line"},
+		{name: "no columns", spec: "2", wantErr: "want L:S-E"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := charPatches(fd, tt.spec); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("charPatches(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestStageChars stages column ranges of a line in a real repository and
+// checks what ends up in the index
+func TestStageChars(t *testing.T) {
+	testutil.InitRepo(t, map[string]string{"f.txt": "a\nhello world\nb\nc\nx := compute(a)\nd\n"})
+	writeFile(t, "f.txt", "a\nhello World\nb\nc\ny := compute(a, b)\nd\n")
+
+	tests := []struct {
+		name  string
+		chars string
+		want  string // the index after staging
+	}{
+		{name: "insertion", chars: "5:15-17", want: "a\nhello world\nb\nc\nx := compute(a, b)\nd\n"},
+		{name: "replacement", chars: "5:1", want: "a\nhello world\nb\nc\ny := compute(a)\nd\n"},
+		{name: "last column", chars: "5:18-18", want: "a\nhello world\nb\nc\nx := compute(a)\nd\n"},
+		{name: "whole line", chars: "5:1-18", want: "a\nhello world\nb\nc\ny := compute(a, b)\nd\n"},
+		{name: "earlier line", chars: "2:7", want: "a\nhello World\nb\nc\nx := compute(a)\nd\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.Git(t, "reset", "-q")
+			if out, code := runGdiff(t, "stage", "f.txt", "--chars", tt.chars); code != 0 {
+				t.Fatalf("exit code %d\n%s", code, out)
+			}
+			if got := testutil.Git(t, "show", ":f.txt"); got != tt.want {
+				t.Errorf("index =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// writeFile replaces the content of a file in the working tree
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runGdiff runs gdiff's main in a subprocess and returns its stdout and
+// exit code
+func runGdiff(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "GDIFF_RUN_MAIN=1")
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return string(out), exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), 0
+}
+
+func TestStageDryRunExitCodes(t *testing.T) {
+	testutil.InitRepo(t, map[string]string{"f.txt": "a\nb\nc\n"})
+	writeFile(t, "f.txt", "a\nB\nc\n")
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  string
+	}{
+		{name: "hunk", args: []string{"stage", "f.txt", "--hunk", "1", "--dry-run"}, wantOut: "+B\n"},
+		{name: "flags before the file", args: []string{"stage", "--dry-run", "--lines", "2", "f.txt"}, wantOut: "+B\n"},
+		{name: "whole file", args: []string{"stage", "--dry-run", "f.txt"}, wantOut: "would stage f.txt\n"},
+		{name: "missing hunk", args: []string{"stage", "f.txt", "--hunk", "2", "--dry-run"}, wantCode: 1},
+		{name: "no changed lines", args: []string{"stage", "f.txt", "--lines", "3", "--dry-run"}, wantCode: 1},
+		{name: "nothing staged", args: []string{"unstage", "f.txt", "--hunk", "1", "--dry-run"}, wantCode: 1},
+		{name: "two selectors", args: []string{"stage", "f.txt", "--hunk", "1", "--lines", "2", "--dry-run"}, wantCode: 1},
+		{name: "two files", args: []string{"stage", "f.txt", "g.txt", "--dry-run"}, wantCode: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, code := runGdiff(t, tt.args...)
+			if code != tt.wantCode {
+				t.Fatalf("exit code = %d, want %d\n%s", code, tt.wantCode, out)
+			}
+			if !strings.Contains(out, tt.wantOut) {
+				t.Errorf("output = %q, want it to contain %q", out, tt.wantOut)
+			}
+		})
+	}
+
+	if staged := testutil.Git(t, "diff", "--cached"); staged != "" {
+		t.Errorf("--dry-run should leave the index alone:\n%s", staged)
+	}
+}
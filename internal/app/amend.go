@@ -0,0 +1,33 @@
+package app
+
+import (
+	"context"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/git"
+)
+
+// headLoadedMsg carries the commit an amend is about to rewrite
+type headLoadedMsg struct {
+	head git.HeadCommit
+	err  error
+}
+
+// loadHead loads HEAD for the amend modal
+func (m Model) loadHead() tea.Cmd {
+	return func() tea.Msg {
+		head, err := git.GetHeadCommit(context.Background())
+		return headLoadedMsg{head: head, err: err}
+	}
+}
+
+// stagedPaths returns the files with staged changes
+func (m Model) stagedPaths() []string {
+	var paths []string
+	for _, f := range m.files {
+		if f.Staged {
+			paths = append(paths, f.Path)
+		}
+	}
+	return paths
+}
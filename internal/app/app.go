@@ -2,19 +2,26 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"charm.land/bubbles/v2/key"
 	"charm.land/bubbles/v2/spinner"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/commitmsg"
+	"github.com/Danny-Dasilva/gdiff/internal/config"
 	"github.com/Danny-Dasilva/gdiff/internal/git"
 	"github.com/Danny-Dasilva/gdiff/internal/types"
 	"github.com/Danny-Dasilva/gdiff/internal/ui/commit"
 	"github.com/Danny-Dasilva/gdiff/internal/ui/commitinput"
+	"github.com/Danny-Dasilva/gdiff/internal/ui/diffopts"
+	"github.com/Danny-Dasilva/gdiff/internal/ui/diffstat"
 	"github.com/Danny-Dasilva/gdiff/internal/ui/diffview"
 	"github.com/Danny-Dasilva/gdiff/internal/ui/filetree"
 	"github.com/Danny-Dasilva/gdiff/internal/ui/helpoverlay"
+	"github.com/Danny-Dasilva/gdiff/internal/ui/prompt"
+	"github.com/Danny-Dasilva/gdiff/internal/ui/stagepreview"
 	"github.com/Danny-Dasilva/gdiff/internal/ui/statusbar"
 	"github.com/Danny-Dasilva/gdiff/pkg/diff"
 )
@@ -26,6 +33,10 @@ type Model struct {
 	statusBar   statusbar.Model
 	commitModal commit.Model
 	helpOverlay helpoverlay.Model
+	diffStat    diffstat.Model
+	diffOptions diffopts.Model
+	prompt      prompt.Model
+	preview     stagepreview.Model
 
 	files          []diff.FileEntry
 	currentFile    string
@@ -35,6 +46,44 @@ type Model struct {
 	diffCache      map[string][]diff.FileDiff
 	cancelDiffLoad context.CancelFunc
 
+	// diffOpts are the options diffs are loaded with
+	diffOpts git.DiffOptions
+
+	// allFiles shows every changed file in one continuous diff;
+	// pendingJump is the file to scroll to once that diff has loaded
+	allFiles    bool
+	pendingJump string
+
+	// static is a diff browsed read-only instead of the repository's
+	// changes; staticLabel names where it came from
+	static      []diff.FileDiff
+	staticLabel string
+
+	// picking is pick mode, where Enter quits with the chosen files and
+	// hunks in picks
+	picking bool
+	picks   []pick
+
+	// foreign is set while a patch file or commit is open for applying;
+	// its diff is shown as static
+	foreign *foreignDiff
+
+	// threeWay shows the current file's HEAD / index / worktree diff,
+	// loaded into threeWayDiff
+	threeWay     bool
+	threeWayDiff *diff.ThreeWay
+
+	// pendingConfirm holds a destructive action awaiting y/n confirmation
+	pendingConfirm *confirmAction
+
+	// pendingStage is the stage shown in the preview overlay, run once
+	// the preview is confirmed
+	pendingStage tea.Cmd
+
+	// commitTemplate and ticketPattern prefill new commit messages
+	commitTemplate string
+	ticketPattern  string
+
 	width            int
 	height           int
 	sidebarCollapsed bool
@@ -44,24 +93,46 @@ type Model struct {
 }
 
 func New(colorblind bool) Model {
+	return NewWithConfig(colorblind, config.DefaultConfig())
+}
+
+// NewWithConfig creates the application model with settings from a
+// configuration file
+func NewWithConfig(colorblind bool, cfg config.Config) Model {
 	keyMap := types.DefaultKeyMap()
 
-	return Model{
+	m := Model{
 		commitInput: commitinput.New(),
 		fileTree:    filetree.New(keyMap),
 		diffView:    diffview.New(keyMap, colorblind),
 		statusBar:   statusbar.New(keyMap),
 		commitModal: commit.New(keyMap),
 		helpOverlay: helpoverlay.New(),
+		diffStat:    diffstat.New(keyMap),
+		diffOptions: diffopts.New(keyMap, git.DefaultDiffOptions()),
+		prompt:      prompt.New(),
+		preview:     stagepreview.New(),
+		diffOpts:    git.DefaultDiffOptions(),
 		focused:     types.PaneFileTree,
 		keyMap:      keyMap,
 		diffCache:   make(map[string][]diff.FileDiff),
 		borderStyle: lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("240")),
 		titleStyle:  lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("39")),
 	}
+	m.diffView.SetWordDiffExtensions(cfg.WordDiffExtensions)
+	m.commitTemplate, m.ticketPattern = cfg.CommitTemplate, cfg.TicketPattern
+	m.commitModal.SetLintRules(cfg.CommitLint)
+	m.commitInput.SetLintRules(cfg.CommitLint)
+	return m
 }
 
 func (m Model) Init() tea.Cmd {
+	if m.static != nil {
+		files := staticFiles(m.static)
+		return func() tea.Msg {
+			return types.StatusLoadedMsg{Files: files}
+		}
+	}
 	return tea.Batch(
 		m.statusBar.StartSpinner("Loading status..."),
 		m.loadStatus(),
@@ -77,6 +148,24 @@ func (m Model) loadStatus() tea.Cmd {
 	}
 }
 
+func (m Model) loadStats() tea.Cmd {
+	if m.static != nil {
+		stats := staticStats(m.static)
+		return func() tea.Msg {
+			return types.StatsLoadedMsg{Unstaged: stats}
+		}
+	}
+	return func() tea.Msg {
+		ctx := context.Background()
+		staged, err := git.GetDiffStats(ctx, true)
+		if err != nil {
+			return types.StatsLoadedMsg{Err: err}
+		}
+		unstaged, err := git.GetDiffStats(ctx, false)
+		return types.StatsLoadedMsg{Staged: staged, Unstaged: unstaged, Err: err}
+	}
+}
+
 func (m Model) loadBranch() tea.Cmd {
 	return func() tea.Msg {
 		ctx := context.Background()
@@ -89,24 +178,102 @@ type branchLoadedMsg struct {
 	branch string
 }
 
-func diffCacheKey(path string, staged bool) string {
+// diffCacheKey is the cache key of a file's diff. Options other than the
+// defaults are appended after a NUL, which cannot occur in a path.
+func diffCacheKey(path string, staged bool, opts git.DiffOptions) string {
 	if staged {
-		return "staged:" + path
+		path = "staged:" + path
+	}
+	if opts != git.DefaultDiffOptions() {
+		path += "\x00" + opts.String()
 	}
 	return path
 }
 
+// allDiffsCacheKey is the cache key for the continuous all-files diff
+func allDiffsCacheKey(staged bool, opts git.DiffOptions) string {
+	return diffCacheKey("all:", staged, opts)
+}
+
+// invalidateFileCache drops every cached diff of a file, and the all-files
+// diffs, whatever options they were loaded with
 func (m *Model) invalidateFileCache(path string) {
-	delete(m.diffCache, path)
-	delete(m.diffCache, "staged:"+path)
+	stale := map[string]bool{
+		path:             true,
+		"staged:" + path: true,
+		"all:":           true,
+		"staged:all:":    true,
+	}
+	for k := range m.diffCache {
+		base, _, _ := strings.Cut(k, "\x00")
+		if stale[base] {
+			delete(m.diffCache, k)
+		}
+	}
+}
+
+// loadAllDiffs loads the diff of every changed file for the all-files view
+func (m *Model) loadAllDiffs(staged bool) tea.Cmd {
+	if m.cancelDiffLoad != nil {
+		m.cancelDiffLoad()
+	}
+
+	if cached, ok := m.diffCache[allDiffsCacheKey(staged, m.diffOpts)]; ok {
+		m.cancelDiffLoad = nil
+		return func() tea.Msg {
+			return types.DiffLoadedMsg{Diffs: cached, All: true}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelDiffLoad = cancel
+	opts := m.diffOpts
+
+	return func() tea.Msg {
+		diffs, err := git.GetAllDiffs(ctx, staged, opts)
+		if ctx.Err() != nil {
+			return nil
+		}
+		return types.DiffLoadedMsg{Diffs: diffs, All: true, Err: err}
+	}
+}
+
+// reloadDiff refreshes the diff pane after the status changed, keeping the
+// current file when it still has changes in the active view
+func (m *Model) reloadDiff() tea.Cmd {
+	if m.allFiles {
+		return m.loadAllDiffs(m.showStaged)
+	}
+	// The three-way view shows both sections of a file, so it stays on the
+	// file while any of its changes remain
+	if m.threeWay {
+		for _, f := range m.files {
+			if f.Path == m.currentFile {
+				return m.loadThreeWay(f.Path)
+			}
+		}
+	}
+	for _, f := range m.files {
+		if f.Path == m.currentFile && f.Staged == m.showStaged {
+			return m.loadDiff(f.Path, f.Staged)
+		}
+	}
+	if len(m.files) > 0 {
+		f := m.files[0]
+		return m.loadDiff(f.Path, f.Staged)
+	}
+	return nil
 }
 
 func (m *Model) loadDiff(path string, staged bool) tea.Cmd {
+	if m.threeWay {
+		return m.loadThreeWay(path)
+	}
 	if m.cancelDiffLoad != nil {
 		m.cancelDiffLoad()
 	}
 
-	cacheKey := diffCacheKey(path, staged)
+	cacheKey := diffCacheKey(path, staged, m.diffOpts)
 	if cached, ok := m.diffCache[cacheKey]; ok {
 		m.cancelDiffLoad = nil
 		return func() tea.Msg {
@@ -116,9 +283,10 @@ func (m *Model) loadDiff(path string, staged bool) tea.Cmd {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	m.cancelDiffLoad = cancel
+	opts := m.diffOpts
 
 	return func() tea.Msg {
-		diffs, err := git.GetFileDiff(ctx, path, staged)
+		diffs, err := git.GetFileDiff(ctx, path, staged, opts)
 		if ctx.Err() != nil {
 			return nil
 		}
@@ -126,6 +294,20 @@ func (m *Model) loadDiff(path string, staged bool) tea.Cmd {
 	}
 }
 
+// loadFileLines reads the contents the diff view expands context from: the
+// index in the staged view, the working tree otherwise
+func (m Model) loadFileLines(paths ...string) tea.Cmd {
+	var cmds []tea.Cmd
+	staged := m.showStaged
+	for _, path := range paths {
+		cmds = append(cmds, func() tea.Msg {
+			lines, err := git.GetFileLines(context.Background(), path, staged)
+			return types.FileLinesLoadedMsg{Path: path, Lines: lines, Err: err}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
 const largeDiffThreshold = 5000
 
 func (m Model) checkLargeDiff(diffs []diff.FileDiff) bool {
@@ -154,6 +336,244 @@ func (m Model) unstageFile(path string) tea.Cmd {
 	}
 }
 
+// confirmAction is a destructive operation that runs only after the user
+// answers y to the prompt shown in the status bar
+type confirmAction struct {
+	prompt string
+	cmd    tea.Cmd
+}
+
+func (m Model) runBatch(op types.BatchOp, files []diff.FileEntry) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		paths := make([]string, 0, len(files))
+		for _, f := range files {
+			paths = append(paths, f.Path)
+		}
+
+		var result git.BatchResult
+		switch op {
+		case types.BatchStage:
+			result = git.StageFiles(ctx, paths)
+		case types.BatchUnstage:
+			result = git.UnstageFiles(ctx, paths)
+		case types.BatchDiscard:
+			result = git.DiscardFiles(ctx, files)
+		case types.BatchStash:
+			result = git.StashFiles(ctx, files, "gdiff: stashed "+pluralFiles(len(files)))
+		}
+		return types.BatchCompleteMsg{Op: op, Paths: result.Paths, Errs: result.Errs}
+	}
+}
+
+// commitInputKey handles a key while the inline commit box has focus.
+// Only ctrl+c and pane switching reach the global bindings; everything
+// else goes to the text input.
+func (m Model) commitInputKey(msg tea.KeyPressMsg) (Model, tea.Cmd) {
+	switch {
+	case msg.String() == "ctrl+c":
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keyMap.SwitchPane):
+		m.switchFocus()
+		return m, nil
+
+	case msg.String() == "enter":
+		if err := m.commitInput.LintError(); err != nil {
+			m.statusBar.SetMessage("Commit blocked: " + err.String())
+			return m, nil
+		}
+		value := m.commitInput.Value()
+		if value == "" {
+			return m, nil
+		}
+		m.commitInput.Reset()
+		m.focused = types.PaneFileTree
+		m.updateLayout()
+		return m, tea.Batch(
+			m.statusBar.StartSpinner("Committing..."),
+			m.doCommit(value, false, git.AmendOptions{}),
+		)
+
+	case msg.String() == "esc":
+		m.focused = types.PaneFileTree
+		m.updateLayout()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.commitInput, cmd = m.commitInput.Update(msg)
+	return m, cmd
+}
+
+// targetFiles returns the marked files, or the file under the cursor when
+// nothing is marked
+func (m Model) targetFiles() []diff.FileEntry {
+	if marked := m.fileTree.MarkedFiles(); len(marked) > 0 {
+		return marked
+	}
+	if f := m.fileTree.SelectedFile(); f != nil {
+		return []diff.FileEntry{*f}
+	}
+	return nil
+}
+
+// discardPrompt asks to confirm a discard, naming the untracked files it
+// would delete
+func discardPrompt(files []diff.FileEntry) string {
+	var untracked []string
+	for _, f := range files {
+		if !f.Staged && f.WorkStatus == diff.StatusUntracked {
+			untracked = append(untracked, f.Path)
+		}
+	}
+	prompt := "Discard changes in " + pluralFiles(len(files))
+	if len(untracked) > 0 {
+		prompt += " and delete untracked " + strings.Join(untracked, ", ")
+	}
+	return prompt + "? (y/N)"
+}
+
+func pluralFiles(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
+
+// batchSummary renders a one-line report of a batch operation, listing
+// per-file failures after the success count
+func batchSummary(msg types.BatchCompleteMsg) string {
+	verbs := map[types.BatchOp]string{
+		types.BatchStage:   "Staged",
+		types.BatchUnstage: "Unstaged",
+		types.BatchDiscard: "Discarded",
+		types.BatchStash:   "Stashed",
+	}
+	verb := verbs[msg.Op]
+
+	result := git.BatchResult{Paths: msg.Paths, Errs: msg.Errs}
+	if result.Failed() == 0 {
+		if len(msg.Paths) == 1 {
+			return verb + ": " + msg.Paths[0]
+		}
+		return verb + " " + pluralFiles(len(msg.Paths))
+	}
+
+	ok := len(msg.Paths) - result.Failed()
+	return fmt.Sprintf("%s %d/%d files, %d error(s): %v", verb, ok, len(msg.Paths), result.Failed(), result.Err())
+}
+
+func (m Model) stageHunk(path string, hunk diff.Hunk, unstage bool) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		if unstage {
+			err := git.UnstageHunk(ctx, path, hunk)
+			return types.UnstageCompleteMsg{Path: path, Err: err}
+		}
+		err := git.StageHunk(ctx, path, hunk)
+		return types.StageCompleteMsg{Path: path, Err: err}
+	}
+}
+
+// stageLines stages or unstages the selected lines. Hunks are applied
+// bottom-up so earlier patches do not shift the positions of later ones.
+func (m Model) stageLines(infos []diffview.LineStagingInfo, unstage bool) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		var err error
+		for i := len(infos) - 1; i >= 0 && err == nil; i-- {
+			info := infos[i]
+			if unstage {
+				err = git.UnstageLines(ctx, info.Path, info.Hunk, info.LineIndices)
+			} else {
+				err = git.StageLines(ctx, info.Path, info.Hunk, info.LineIndices)
+			}
+		}
+		path := infos[0].Path
+		if unstage {
+			return types.UnstageCompleteMsg{Path: path, Err: err}
+		}
+		return types.StageCompleteMsg{Path: path, Err: err}
+	}
+}
+
+// diffStagingKey handles s/u/S/U/space, the stage preview and hunk editing
+// in the diff pane. Staging applies to the unstaged view and unstaging to the staged
+// view.
+func (m *Model) diffStagingKey(msg tea.KeyPressMsg) (tea.Cmd, bool) {
+	var unstage, hunk, toggle, preview, edit bool
+	switch {
+	case key.Matches(msg, m.keyMap.StageItem):
+	case key.Matches(msg, m.keyMap.UnstageItem):
+		unstage = true
+	case key.Matches(msg, m.keyMap.StageHunk):
+		hunk = true
+	case key.Matches(msg, m.keyMap.UnstageHunk):
+		unstage, hunk = true, true
+	case key.Matches(msg, m.keyMap.SpaceToggle):
+		unstage, hunk, toggle = m.showStaged, true, true
+	case key.Matches(msg, m.keyMap.PreviewStage):
+		preview = true
+	case key.Matches(msg, m.keyMap.EditHunk):
+		edit = true
+	default:
+		return nil, false
+	}
+
+	// Hunks from a whitespace-insensitive diff do not match the index
+	// byte for byte, so patches built from them would not apply
+	if m.diffOpts.IgnoresWhitespace() {
+		m.statusBar.SetMessage("Hunk and line staging is disabled while whitespace is ignored (o to change)")
+		return nil, true
+	}
+
+	if preview {
+		return m.previewStage(), true
+	}
+	if edit {
+		return m.editHunk(), true
+	}
+
+	if m.threeWay {
+		return m.threeWayStage(unstage, hunk, toggle), true
+	}
+
+	if unstage != m.showStaged {
+		if unstage {
+			m.statusBar.SetMessage("Switch to the staged view (t) to unstage")
+		} else {
+			m.statusBar.SetMessage("Switch to the unstaged view (t) to stage")
+		}
+		return nil, true
+	}
+
+	if m.diffView.IsInCharMode() {
+		if unstage {
+			m.statusBar.SetMessage("Character unstaging is not supported")
+			return nil, true
+		}
+		if info := m.diffView.GetCharStagingInfo(); info != nil {
+			m.diffView.ExitVisualMode()
+			return m.stageCharacters(info.Path, info.Hunk, info.HunkLineIndex, info.CharStart, info.CharEnd), true
+		}
+		return nil, true
+	}
+
+	if hunk {
+		if info := m.diffView.GetHunkStagingInfo(); info != nil {
+			return m.stageHunk(info.Path, info.Hunk, unstage), true
+		}
+		return nil, true
+	}
+
+	if infos := m.diffView.GetLineStagingInfo(); len(infos) > 0 {
+		m.diffView.ExitVisualMode()
+		return m.stageLines(infos, unstage), true
+	}
+	return nil, true
+}
+
 func (m Model) stageCharacters(path string, hunk diff.Hunk, lineIndex, charStart, charEnd int) tea.Cmd {
 	return func() tea.Msg {
 		ctx := context.Background()
@@ -162,12 +582,12 @@ func (m Model) stageCharacters(path string, hunk diff.Hunk, lineIndex, charStart
 	}
 }
 
-func (m Model) doCommit(message string, amend bool) tea.Cmd {
+func (m Model) doCommit(message string, amend bool, opts git.AmendOptions) tea.Cmd {
 	return func() tea.Msg {
 		ctx := context.Background()
 		args := []string{"commit", "-m", message}
 		if amend {
-			args = append(args, "--amend")
+			args = git.AmendArgs(message, opts)
 		}
 		out, err := git.RunGitCommand(ctx, args...)
 		if err != nil {
@@ -209,6 +629,80 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	if m.diffStat.Visible() {
+		switch msg := msg.(type) {
+		case tea.WindowSizeMsg:
+			m.width = msg.Width
+			m.height = msg.Height
+			m.updateLayout()
+			m.diffStat.SetSize(msg.Width, msg.Height)
+			return m, nil
+		case tea.KeyPressMsg:
+			switch {
+			case key.Matches(msg, m.keyMap.ToggleDiffStat), key.Matches(msg, m.keyMap.Escape):
+				m.diffStat.Hide()
+				return m, nil
+			case key.Matches(msg, m.keyMap.Quit):
+				return m, tea.Quit
+			}
+			var cmd tea.Cmd
+			m.diffStat, cmd = m.diffStat.Update(msg)
+			return m, cmd
+		}
+	}
+
+	if m.diffOptions.Visible() {
+		switch msg := msg.(type) {
+		case tea.WindowSizeMsg:
+			m.width = msg.Width
+			m.height = msg.Height
+			m.updateLayout()
+			m.diffOptions.SetSize(msg.Width, msg.Height)
+			return m, nil
+		case tea.KeyPressMsg:
+			switch {
+			case key.Matches(msg, m.keyMap.DiffOptions), key.Matches(msg, m.keyMap.Escape):
+				m.diffOptions.Hide()
+				return m, nil
+			case key.Matches(msg, m.keyMap.Quit):
+				return m, tea.Quit
+			}
+			var cmd tea.Cmd
+			m.diffOptions, cmd = m.diffOptions.Update(msg)
+			return m, cmd
+		}
+	}
+
+	if m.prompt.Visible() {
+		switch msg := msg.(type) {
+		case tea.WindowSizeMsg:
+			m.width = msg.Width
+			m.height = msg.Height
+			m.updateLayout()
+			m.prompt.SetSize(msg.Width, msg.Height)
+			return m, nil
+		case tea.KeyPressMsg:
+			var cmd tea.Cmd
+			m.prompt, cmd = m.prompt.Update(msg)
+			return m, cmd
+		}
+	}
+
+	if m.preview.Visible() {
+		switch msg := msg.(type) {
+		case tea.WindowSizeMsg:
+			m.width = msg.Width
+			m.height = msg.Height
+			m.updateLayout()
+			m.preview.SetSize(msg.Width, msg.Height)
+			return m, nil
+		case tea.KeyPressMsg:
+			var cmd tea.Cmd
+			m.preview, cmd = m.preview.Update(msg)
+			return m, cmd
+		}
+	}
+
 	if m.commitModal.Visible() {
 		var cmd tea.Cmd
 		m.commitModal, cmd = m.commitModal.Update(msg)
@@ -219,7 +713,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg := msg.(type) {
 		case commit.ConfirmMsg:
 			cmds = append(cmds, m.statusBar.StartSpinner("Committing..."))
-			cmds = append(cmds, m.doCommit(msg.Message, msg.Amend))
+			cmds = append(cmds, m.doCommit(msg.Message, msg.Amend, msg.Options))
 		case commit.CancelMsg:
 			m.statusBar.SetMessage("Commit cancelled")
 		}
@@ -234,6 +728,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.updateLayout()
 		m.commitModal.SetSize(msg.Width, msg.Height)
 		m.helpOverlay.SetSize(msg.Width, msg.Height)
+		m.diffStat.SetSize(msg.Width, msg.Height)
+		m.diffOptions.SetSize(msg.Width, msg.Height)
+		m.prompt.SetSize(msg.Width, msg.Height)
+		m.preview.SetSize(msg.Width, msg.Height)
 
 	case spinner.TickMsg:
 		cmd := m.statusBar.Update(msg)
@@ -323,6 +821,51 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Batch(cmds...)
 
 	case tea.KeyPressMsg:
+		if m.pendingConfirm != nil {
+			// Any key but y cancels; ctrl+c still quits
+			action := m.pendingConfirm
+			m.pendingConfirm = nil
+			switch msg.String() {
+			case "y":
+				m.statusBar.ClearMessage()
+				return m, action.cmd
+			case "ctrl+c":
+				return m, tea.Quit
+			}
+			m.statusBar.SetMessage("Cancelled")
+			return m, nil
+		}
+
+		// Letters typed into the inline commit box are text, not bindings
+		if m.focused == types.PaneCommitInput {
+			return m.commitInputKey(msg)
+		}
+
+		// The second key of a motion like ]c belongs to the diff view, not
+		// to the global bindings
+		if m.focused == types.PaneDiffView && m.diffView.HasPendingKey() {
+			var cmd tea.Cmd
+			m.diffView, cmd = m.diffView.Update(msg)
+			return m, cmd
+		}
+
+		if mm, ok := m.markHunkKey(msg); ok {
+			return mm, nil
+		}
+
+		if pm, cmd, ok := m.pickKey(msg); ok {
+			return pm, cmd
+		}
+
+		if pm, cmd, ok := m.patchKey(msg); ok {
+			return pm, cmd
+		}
+
+		if m.readOnlyKey(msg) {
+			m.statusBar.SetMessage("Read-only: browsing " + m.staticLabel)
+			return m, nil
+		}
+
 		switch {
 		case key.Matches(msg, m.keyMap.Quit):
 			return m, tea.Quit
@@ -332,6 +875,16 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.helpOverlay.Toggle()
 			return m, nil
 
+		case key.Matches(msg, m.keyMap.ToggleDiffStat):
+			m.diffStat.SetSize(m.width, m.height)
+			m.diffStat.Toggle()
+			return m, nil
+
+		case key.Matches(msg, m.keyMap.DiffOptions):
+			m.diffOptions.SetSize(m.width, m.height)
+			m.diffOptions.Toggle()
+			return m, nil
+
 		case key.Matches(msg, m.keyMap.SwitchPane):
 			m.switchFocus()
 			return m, nil
@@ -346,11 +899,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case key.Matches(msg, m.keyMap.Commit):
 			m.commitModal.Show(false)
-			return m, nil
+			return m, m.loadCommitInfo()
 
 		case key.Matches(msg, m.keyMap.CommitAmend):
-			m.commitModal.Show(true)
-			return m, nil
+			return m, m.loadHead()
 
 		case key.Matches(msg, m.keyMap.Push):
 			return m, tea.Batch(
@@ -364,15 +916,52 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.doPush(true),
 			)
 
-		case key.Matches(msg, m.keyMap.StageItem):
-			if m.focused == types.PaneDiffView && m.diffView.IsInCharMode() {
-				if info := m.diffView.GetCharStagingInfo(); info != nil {
-					return m, m.stageCharacters(m.currentFile, info.Hunk, info.HunkLineIndex, info.CharStart, info.CharEnd)
-				}
+		case key.Matches(msg, m.keyMap.ToggleLayout):
+			m.diffView.ToggleLayout()
+			m.statusBar.SetMessage("Layout: " + m.diffView.LayoutName())
+			return m, nil
+
+		case key.Matches(msg, m.keyMap.ToggleWrap):
+			m.diffView.ToggleWrap()
+			if m.diffView.Wrap() {
+				m.statusBar.SetMessage("Soft-wrap on")
+			} else {
+				m.statusBar.SetMessage("Soft-wrap off (zh/zl to scroll)")
 			}
+			return m, nil
+
+		case key.Matches(msg, m.keyMap.ToggleWordDiff):
+			m.diffView.ToggleWordDiff()
+			m.statusBar.SetMessage("Word diff: " + m.diffView.WordDiffName())
+			return m, nil
+
+		case key.Matches(msg, m.keyMap.ToggleThreeWay):
+			return m, m.toggleThreeWay()
+
+		case key.Matches(msg, m.keyMap.ToggleAllFiles):
+			if m.threeWay {
+				m.leaveThreeWay()
+			}
+			m.allFiles = !m.allFiles
+			if m.allFiles {
+				m.statusBar.SetMessage("Showing all files")
+				m.pendingJump = m.currentFile
+				return m, tea.Batch(
+					m.statusBar.StartSpinner("Loading diff..."),
+					m.loadAllDiffs(m.showStaged),
+				)
+			}
+			m.statusBar.SetMessage("Showing single file")
+			if m.currentFile != "" {
+				return m, m.loadDiff(m.currentFile, m.showStaged)
+			}
+			return m, nil
 
 		case key.Matches(msg, m.keyMap.StageFile):
 			if m.focused == types.PaneFileTree {
+				if m.fileTree.HasMarks() {
+					return m, m.runBatch(types.BatchStage, m.fileTree.MarkedFiles())
+				}
 				if f := m.fileTree.SelectedFile(); f != nil {
 					return m, m.stageFile(f.Path)
 				}
@@ -380,12 +969,38 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case key.Matches(msg, m.keyMap.UnstageFile):
 			if m.focused == types.PaneFileTree {
+				if m.fileTree.HasMarks() {
+					return m, m.runBatch(types.BatchUnstage, m.fileTree.MarkedFiles())
+				}
 				if f := m.fileTree.SelectedFile(); f != nil {
 					return m, m.unstageFile(f.Path)
 				}
 			}
 
+		case key.Matches(msg, m.keyMap.RevertItem):
+			if m.focused == types.PaneFileTree {
+				if files := m.targetFiles(); len(files) > 0 {
+					m.pendingConfirm = &confirmAction{
+						prompt: discardPrompt(files),
+						cmd:    m.runBatch(types.BatchDiscard, files),
+					}
+					m.statusBar.SetMessage(m.pendingConfirm.prompt)
+					return m, nil
+				}
+			}
+
+		case key.Matches(msg, m.keyMap.Stash):
+			if m.focused == types.PaneFileTree {
+				if files := m.targetFiles(); len(files) > 0 {
+					return m, tea.Batch(
+						m.statusBar.StartSpinner("Stashing..."),
+						m.runBatch(types.BatchStash, files),
+					)
+				}
+			}
+
 		case key.Matches(msg, m.keyMap.ToggleStagedView):
+			m.threeWay, m.threeWayDiff = false, nil
 			m.showStaged = !m.showStaged
 			if m.showStaged {
 				m.statusBar.SetMessage("Showing staged changes")
@@ -394,6 +1009,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.statusBar.SetMessage("Showing unstaged changes")
 				m.statusBar.SetMode("NORMAL")
 			}
+			if m.allFiles {
+				m.pendingJump = m.currentFile
+				return m, m.loadAllDiffs(m.showStaged)
+			}
 			if m.currentFile != "" {
 				return m, m.loadDiff(m.currentFile, m.showStaged)
 			}
@@ -402,31 +1021,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case msg.String() == "i":
 			m.focused = types.PaneCommitInput
 			m.updateLayout()
-			return m, m.commitInput.Focus()
+			return m, tea.Batch(m.commitInput.Focus(), m.loadCommitInfo())
 		}
 
 		switch m.focused {
-		case types.PaneCommitInput:
-			switch msg.String() {
-			case "enter":
-				if msg := m.commitInput.Value(); msg != "" {
-					cmds = append(cmds, m.statusBar.StartSpinner("Committing..."))
-					cmds = append(cmds, m.doCommit(msg, false))
-					m.commitInput.Reset()
-					m.focused = types.PaneFileTree
-					m.updateLayout()
-				}
-			case "esc":
-				m.focused = types.PaneFileTree
-				m.updateLayout()
-			default:
-				var cmd tea.Cmd
-				m.commitInput, cmd = m.commitInput.Update(msg)
-				if cmd != nil {
-					cmds = append(cmds, cmd)
-				}
-			}
-
 		case types.PaneFileTree:
 			var cmd tea.Cmd
 			m.fileTree, cmd = m.fileTree.Update(msg)
@@ -435,6 +1033,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case types.PaneDiffView:
+			if cmd, ok := m.diffStagingKey(msg); ok {
+				return m, cmd
+			}
 			var cmd tea.Cmd
 			m.diffView, cmd = m.diffView.Update(msg)
 			if cmd != nil {
@@ -450,11 +1051,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.files = msg.Files
 			m.fileTree.SetFiles(msg.Files)
 			m.updateCounts()
+			cmds = append(cmds, m.loadStats())
 
-			if len(msg.Files) > 0 {
-				f := msg.Files[0]
+			if len(msg.Files) > 0 || m.allFiles {
 				cmds = append(cmds, m.statusBar.StartSpinner("Loading diff..."))
-				cmds = append(cmds, m.loadDiff(f.Path, f.Staged))
+				cmds = append(cmds, m.reloadDiff())
 			}
 		}
 
@@ -462,15 +1063,106 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.statusBar.StopSpinner()
 		if msg.Err != nil {
 			m.statusBar.SetMessage("Error loading diff: " + msg.Err.Error())
+		} else if msg.All {
+			if !m.allFiles {
+				break
+			}
+			m.diffCache[allDiffsCacheKey(m.showStaged, m.diffOpts)] = msg.Diffs
+			if m.checkLargeDiff(msg.Diffs) {
+				m.statusBar.SetMessage("Warning: Large diff - character highlighting disabled")
+			}
+			m.diffView.SetDiff("", msg.Diffs)
+			if m.pendingJump != "" {
+				m.diffView.JumpToFile(m.pendingJump)
+				m.pendingJump = ""
+			}
+			m.currentFile = m.diffView.CursorPath()
+			cmds = append(cmds, m.loadFileLines(m.diffView.PendingFileLines()...))
 		} else {
 			m.currentFile = msg.Path
-			m.diffCache[diffCacheKey(msg.Path, m.showStaged)] = msg.Diffs
+			m.diffCache[diffCacheKey(msg.Path, m.showStaged, m.diffOpts)] = msg.Diffs
 
 			if m.checkLargeDiff(msg.Diffs) {
 				m.statusBar.SetMessage("Warning: Large diff - character highlighting disabled")
 			}
 			m.diffView.SetDiff(msg.Path, msg.Diffs)
+			cmds = append(cmds, m.loadFileLines(m.diffView.PendingFileLines()...))
+		}
+
+	case threeWayLoadedMsg:
+		m.statusBar.StopSpinner()
+		if msg.err != nil {
+			m.statusBar.SetMessage("Error loading diff: " + msg.err.Error())
+			break
+		}
+		if !m.threeWay {
+			break
+		}
+		m.currentFile = msg.path
+		m.threeWayDiff = &msg.diff
+		m.diffView.SetThreeWay(msg.path, msg.diff)
+
+	case diffopts.ChangedMsg:
+		m.diffOpts = msg.Options
+		m.statusBar.SetMessage("Diff options: " + m.diffOpts.String())
+		if m.allFiles {
+			cmds = append(cmds, m.loadAllDiffs(m.showStaged))
+		} else if m.currentFile != "" {
+			cmds = append(cmds, m.loadDiff(m.currentFile, m.showStaged))
+		}
+
+	case stagePreviewMsg:
+		m.pendingStage = msg.stage
+		m.preview.SetSize(m.width, m.height)
+		m.preview.Show(msg.path, msg.lines, msg.err)
+
+	case stagepreview.ConfirmMsg:
+		m.diffView.ExitVisualMode()
+		cmds = append(cmds, m.pendingStage)
+		m.pendingStage = nil
+
+	case stagepreview.CancelMsg:
+		m.pendingStage = nil
+		m.statusBar.SetMessage("Stage cancelled")
+
+	case prompt.SubmitMsg:
+		var cmd tea.Cmd
+		m, cmd = m.promptSubmitted(msg)
+		cmds = append(cmds, cmd)
+
+	case foreignLoadedMsg:
+		m.statusBar.StopSpinner()
+		if msg.err != nil {
+			m.statusBar.SetMessage("Error: " + msg.err.Error())
+			break
+		}
+		cmds = append(cmds, m.openForeign(msg))
+
+	case patchAppliedMsg:
+		m.statusBar.StopSpinner()
+		m.diffView.SetConflicts(msg.conflicts)
+		m.statusBar.SetMessage(applySummary(msg))
+
+	case patchExportedMsg:
+		if msg.err != nil {
+			m.statusBar.SetMessage("Export failed: " + msg.err.Error())
+			break
+		}
+		m.statusBar.SetMessage("Exported patch to " + msg.path)
+
+	case types.FileLinesRequestMsg:
+		if m.static != nil {
+			m.statusBar.SetMessage("Expanding context needs the repository files")
+			break
 		}
+		cmds = append(cmds, m.loadFileLines(msg.Path))
+
+	case types.FileLinesLoadedMsg:
+		if msg.Err != nil {
+			m.statusBar.SetMessage("Error loading context: " + msg.Err.Error())
+			break
+		}
+		m.diffView.SetFileLines(msg.Path, msg.Lines)
 
 	case types.FocusChangedMsg:
 		switch msg.Pane {
@@ -486,6 +1178,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.statusBar.SetFocusedPane(m.focused)
 
 	case types.SpaceToggleMsg:
+		if m.static != nil {
+			m.statusBar.SetMessage("Read-only: browsing " + m.staticLabel)
+			break
+		}
 		if msg.Staged {
 			cmds = append(cmds, m.unstageFile(msg.Path))
 		} else {
@@ -493,9 +1189,30 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case types.FileSelectedMsg:
+		if m.allFiles {
+			m.currentFile = msg.Path
+			if msg.Staged == m.showStaged {
+				m.diffView.JumpToFile(msg.Path)
+				break
+			}
+			// The file lives in the other section: switch views first
+			m.showStaged = msg.Staged
+			if m.showStaged {
+				m.statusBar.SetMode("STAGED")
+			} else {
+				m.statusBar.SetMode("NORMAL")
+			}
+			m.pendingJump = msg.Path
+			cmds = append(cmds, m.loadAllDiffs(m.showStaged))
+			break
+		}
 		cmds = append(cmds, m.statusBar.StartSpinner("Loading diff..."))
 		cmds = append(cmds, m.loadDiff(msg.Path, msg.Staged))
 
+	case types.CursorFileChangedMsg:
+		m.currentFile = msg.Path
+		m.fileTree.SelectPath(msg.Path, m.showStaged)
+
 	case types.StageCompleteMsg:
 		if msg.Err != nil {
 			m.statusBar.SetMessage("Stage error: " + msg.Err.Error())
@@ -505,6 +1222,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, m.loadStatus())
 		}
 
+	case hunkEditedMsg:
+		m.statusBar.SetMessage(hunkEditedMessage(msg))
+		if msg.err == nil {
+			m.invalidateFileCache(msg.path)
+			cmds = append(cmds, m.loadStatus())
+		}
+
 	case types.UnstageCompleteMsg:
 		if msg.Err != nil {
 			m.statusBar.SetMessage("Unstage error: " + msg.Err.Error())
@@ -514,6 +1238,34 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, m.loadStatus())
 		}
 
+	case types.BatchCompleteMsg:
+		m.statusBar.StopSpinner()
+		m.statusBar.SetMessage(batchSummary(msg))
+		m.fileTree.ClearMarks()
+		for _, p := range msg.Paths {
+			m.invalidateFileCache(p)
+		}
+		cmds = append(cmds, m.loadStatus())
+
+	case headLoadedMsg:
+		if msg.err != nil {
+			m.statusBar.SetMessage("Nothing to amend: " + applyError(msg.err))
+			break
+		}
+		m.commitModal.ShowAmend(msg.head, m.stagedPaths())
+		cmds = append(cmds, m.loadCommitInfo())
+
+	case commitInfoMsg:
+		if msg.err != nil {
+			m.statusBar.SetMessage("Commit template: " + msg.err.Error())
+		}
+		if m.commitModal.Visible() {
+			m.commitModal.SetInfo(msg.info)
+		}
+		if m.focused == types.PaneCommitInput && m.commitInput.Value() == "" {
+			m.commitInput.SetValue(commitmsg.Subject(msg.info.Template))
+		}
+
 	case types.CommitCompleteMsg:
 		m.statusBar.StopSpinner()
 		if msg.Err != nil {
@@ -532,6 +1284,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.statusBar.SetMessage("Pushed successfully")
 		}
 
+	case types.StatsLoadedMsg:
+		if msg.Err == nil {
+			m.fileTree.SetStats(msg.Staged, msg.Unstaged)
+			m.diffStat.SetStats(msg.Staged, msg.Unstaged)
+		}
+
 	case branchLoadedMsg:
 		m.statusBar.SetBranch(msg.branch)
 	}
@@ -631,6 +1389,21 @@ func (m Model) View() tea.View {
 	if m.commitModal.Visible() {
 		return m.newView(m.commitModal.View())
 	}
+	if m.diffStat.Visible() {
+		return m.newView(m.diffStat.View())
+	}
+
+	if m.diffOptions.Visible() {
+		return m.newView(m.diffOptions.View())
+	}
+
+	if m.prompt.Visible() {
+		return m.newView(m.prompt.View())
+	}
+
+	if m.preview.Visible() {
+		return m.newView(m.preview.View())
+	}
 
 	base := lipgloss.Color("#1e1e2e")
 	surface := lipgloss.Color("#313244")
@@ -719,6 +1492,11 @@ func (m Model) View() tea.View {
 		Padding(0, 1)
 
 	subtitleText := " Git Diff TUI "
+	if m.foreign != nil {
+		subtitleText = " " + m.staticLabel + " (patch) "
+	} else if m.static != nil {
+		subtitleText = " " + m.staticLabel + " (read-only) "
+	}
 	subtitleStyle := lipgloss.NewStyle().
 		Foreground(subtext).
 		Background(surface).
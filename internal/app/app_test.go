@@ -2,12 +2,24 @@ package app
 
 import (
 	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	tea "charm.land/bubbletea/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/commitmsg"
+	"github.com/Danny-Dasilva/gdiff/internal/config"
+	"github.com/Danny-Dasilva/gdiff/internal/git"
+	"github.com/Danny-Dasilva/gdiff/internal/testutil"
 	"github.com/Danny-Dasilva/gdiff/internal/types"
+	"github.com/Danny-Dasilva/gdiff/internal/ui/prompt"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
 )
 
 // TestCancelFuncFieldExists verifies the Model has a cancelDiffLoad field
@@ -164,3 +176,525 @@ func TestLoadDiffCreatesNewCancelFunc(t *testing.T) {
 		t.Error("cancelDiffLoad should be set after loadDiff")
 	}
 }
+
+// TestDiffCacheKeyCoversOptions verifies that diffs loaded with different
+// options are cached separately and invalidated together
+func TestDiffCacheKeyCoversOptions(t *testing.T) {
+	m := New(false)
+
+	defaults := git.DefaultDiffOptions()
+	ignoring := defaults
+	ignoring.IgnoreAllSpace = true
+
+	if diffCacheKey("a.go", false, defaults) != "a.go" {
+		t.Error("default options should keep the plain path key")
+	}
+	if diffCacheKey("a.go", false, defaults) == diffCacheKey("a.go", false, ignoring) {
+		t.Error("options should be part of the cache key")
+	}
+
+	m.diffCache[diffCacheKey("a.go", false, defaults)] = nil
+	m.diffCache[diffCacheKey("a.go", true, ignoring)] = nil
+	m.diffCache[allDiffsCacheKey(false, ignoring)] = nil
+	m.diffCache[diffCacheKey("b.go", false, ignoring)] = nil
+
+	m.invalidateFileCache("a.go")
+	if len(m.diffCache) != 1 {
+		t.Errorf("expected only b.go to stay cached, got %d entries", len(m.diffCache))
+	}
+	if _, ok := m.diffCache[diffCacheKey("b.go", false, ignoring)]; !ok {
+		t.Error("b.go should stay cached")
+	}
+}
+
+// TestStaticModelIsReadOnly verifies that a piped diff is browsed without
+// the repository and that git actions are refused
+func TestStaticModelIsReadOnly(t *testing.T) {
+	diffs := diff.Parse(`diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1 +1 @@
+-old
++new
+commit 0123456789abcdef
+
+    second commit
+
+diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1 +1,2 @@
+ new
++more
+`)
+	if len(diffs) != 2 || len(diffs[0].Hunks[0].Lines) != 3 {
+		t.Fatalf("commit headers should not leak into hunks: %+v", diffs)
+	}
+
+	m := NewStatic(false, config.DefaultConfig(), diffs, "stdin")
+	m.width, m.height = 120, 40
+	m.updateLayout()
+
+	status, ok := m.Init()().(types.StatusLoadedMsg)
+	if !ok || len(status.Files) != 1 || status.Files[0].Path != "a.go" {
+		t.Fatalf("expected one file entry for a.go, got %+v", status)
+	}
+
+	// The diff comes from the cache, never from git
+	loaded, ok := m.loadAllDiffs(false)().(types.DiffLoadedMsg)
+	if !ok || len(loaded.Diffs) != 2 {
+		t.Fatalf("expected the piped diff, got %+v", loaded)
+	}
+	if stats := staticStats(diffs); stats["a.go"] != [2]int{2, 1} {
+		t.Errorf("stats = %v, want 2 added and 1 removed", stats["a.go"])
+	}
+
+	newModel, _ := m.Update(tea.KeyPressMsg{Code: 'c', Text: "c"})
+	if newModel.(Model).commitModal.Visible() {
+		t.Error("commit should be refused in read-only mode")
+	}
+}
+
+// TestPickModeOutputsMarkedHunk verifies that marking a hunk in pick mode
+// and pressing Enter quits with it in every output format
+func TestPickModeOutputsMarkedHunk(t *testing.T) {
+	diffs := diff.Parse(`diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,3 +1,3 @@
+ package a
+-var x = 1
++var x = 2
+ // end
+`)
+	m := NewPick(false, config.DefaultConfig())
+	m.width, m.height = 120, 40
+	m.updateLayout()
+	m.diffView.SetDiff("a.go", diffs)
+	m.switchFocus()
+
+	var cmd tea.Cmd
+	for _, k := range []tea.KeyPressMsg{
+		{Code: 'j', Text: "j"},
+		{Code: 'm', Text: "m"},
+		{Code: tea.KeyEnter},
+	} {
+		var model tea.Model
+		model, cmd = m.Update(k)
+		m = model.(Model)
+	}
+	if cmd == nil || !m.Picked() {
+		t.Fatal("Enter should quit with the marked hunk")
+	}
+
+	want := map[string]string{
+		"paths": "a.go\n",
+		"lines": "a.go:2\n",
+		"patch": "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,3 +1,3 @@\n package a\n-var x = 1\n+var x = 2\n // end\n",
+	}
+	for format, w := range want {
+		got, err := m.PickOutput(context.Background(), format)
+		if err != nil || got != w {
+			t.Errorf("%s output = %q, %v; want %q", format, got, err, w)
+		}
+	}
+}
+
+// TestOpenedPatchAppliesAndExports verifies that a patch opened with I
+// applies only its marked hunk and exports it again, and that Esc returns
+// to the repository's changes
+func TestOpenedPatchAppliesAndExports(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("a.txt", []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	patch := `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+-1
++one
+ 2
+@@ -9,2 +9,2 @@
+ 9
+-10
++ten
+`
+	if err := os.WriteFile("fix.patch", []byte(patch), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(false)
+	m.width, m.height = 120, 40
+	m.updateLayout()
+
+	loaded, ok := loadForeign("fix.patch")().(foreignLoadedMsg)
+	if !ok || loaded.err != nil || len(loaded.diffs) != 1 {
+		t.Fatalf("loadForeign() = %+v", loaded)
+	}
+	model, _ := m.Update(loaded)
+	m = model.(Model)
+	if m.foreign == nil || m.focused != types.PaneDiffView {
+		t.Fatal("the patch should open in the diff view")
+	}
+	m.diffView.SetDiff("a.txt", loaded.diffs)
+
+	// Mark the second hunk only
+	for _, k := range []tea.KeyPressMsg{
+		{Code: '}', Text: "}"},
+		{Code: '}', Text: "}"},
+		{Code: 'm', Text: "m"},
+	} {
+		model, _ = m.Update(k)
+		m = model.(Model)
+	}
+	if picked := m.diffView.PickedHunks(); len(picked) != 1 || picked[0].Hunk.NewStart != 9 {
+		t.Fatalf("picked = %+v, want the second hunk", picked)
+	}
+
+	applied, ok := applyForeign(m.foreignChanges(), false)().(patchAppliedMsg)
+	if !ok || applied.applied != 1 || len(applied.conflicts) != 0 {
+		t.Fatalf("applyForeign() = %+v", applied)
+	}
+	data, _ := os.ReadFile("a.txt")
+	if !strings.HasPrefix(string(data), "1\n") || !strings.HasSuffix(string(data), "9\nten\n") {
+		t.Errorf("a.txt after apply:\n%s", data)
+	}
+
+	// Applied again, the hunk no longer matches and is flagged inline
+	model, _ = m.Update(applyForeign(m.foreignChanges(), false)())
+	m = model.(Model)
+	if c := m.diffView.Conflicts(); len(c) != 1 || c[0].Header != "@@ -9,2 +9,2 @@" || !strings.Contains(c[0].Reason, "a.txt") {
+		t.Errorf("conflicts = %+v, want the second hunk", c)
+	}
+	if !strings.Contains(m.diffView.View(), "does not apply") {
+		t.Error("the conflict should be shown under the hunk header")
+	}
+
+	// Exporting to an existing file asks first
+	out := filepath.Join(t.TempDir(), "out.patch")
+	if err := os.WriteFile(out, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, _ = m.promptSubmitted(prompt.SubmitMsg{ID: promptExport, Value: out})
+	if m.pendingConfirm == nil {
+		t.Fatal("export over an existing file should ask to overwrite")
+	}
+	model, cmd := m.Update(tea.KeyPressMsg{Code: 'y', Text: "y"})
+	m = model.(Model)
+	exported, ok := cmd().(patchExportedMsg)
+	if !ok || exported.err != nil {
+		t.Fatalf("export = %+v", exported)
+	}
+	data, _ = os.ReadFile(out)
+	if want := "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -9,2 +9,2 @@\n 9\n-10\n+ten\n"; string(data) != want {
+		t.Errorf("exported patch =\n%s\nwant:\n%s", data, want)
+	}
+
+	model, _ = m.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	m = model.(Model)
+	if m.foreign != nil || m.static != nil || len(m.diffView.PickedHunks()) != 0 {
+		t.Error("Esc should close the patch")
+	}
+}
+
+// TestPendingConfirmKeys verifies that a y/N confirmation runs only on y,
+// that any other key cancels it and that ctrl+c still quits
+func TestPendingConfirmKeys(t *testing.T) {
+	ran := false
+	confirm := func() Model {
+		m := New(false)
+		m.pendingConfirm = &confirmAction{prompt: "Discard? (y/N)", cmd: func() tea.Msg {
+			ran = true
+			return nil
+		}}
+		return m
+	}
+
+	model, cmd := confirm().Update(tea.KeyPressMsg{Code: 'q', Text: "q"})
+	if m := model.(Model); m.pendingConfirm != nil || cmd != nil {
+		t.Errorf("q should cancel the confirmation without quitting, cmd = %v", cmd)
+	}
+
+	model, cmd = confirm().Update(tea.KeyPressMsg{Code: 'c', Mod: tea.ModCtrl})
+	if cmd == nil {
+		t.Fatal("ctrl+c should quit")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok || model.(Model).pendingConfirm != nil {
+		t.Error("ctrl+c should quit and drop the confirmation")
+	}
+
+	_, cmd = confirm().Update(tea.KeyPressMsg{Code: 'y', Text: "y"})
+	if cmd == nil {
+		t.Fatal("y should run the action")
+	}
+	cmd()
+	if !ran {
+		t.Error("y should run the action")
+	}
+}
+
+// TestChangeJumpBeatsCommitKey verifies that the c of ]c reaches the diff
+// view instead of opening the commit modal
+func TestChangeJumpBeatsCommitKey(t *testing.T) {
+	m := New(false)
+	m.width, m.height = 120, 40
+	m.updateLayout()
+	m.diffView.SetDiff("a.go", diff.Parse(`diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,2 +1,2 @@
+ one
+-two
++TWO
+@@ -10,2 +10,2 @@
+ ten
+-eleven
++ELEVEN
+`))
+	m.switchFocus()
+
+	changed := func() string {
+		infos := m.diffView.GetLineStagingInfo()
+		if len(infos) != 1 {
+			return ""
+		}
+		return infos[0].Hunk.Lines[infos[0].LineIndices[0]].Content
+	}
+	for _, k := range []rune{']', 'c', ']', 'c'} {
+		model, _ := m.Update(tea.KeyPressMsg{Code: k, Text: string(k)})
+		m = model.(Model)
+	}
+	if m.commitModal.Visible() {
+		t.Fatal("]c should not open the commit modal")
+	}
+	if got := changed(); got != "eleven" {
+		t.Errorf("after ]c ]c the cursor is on %q, want the second change", got)
+	}
+}
+
+// TestThreeWayStagesInPlace verifies that the three-way view tags staged
+// and unstaged lines and that s stages an unstaged line without leaving it
+func TestThreeWayStagesInPlace(t *testing.T) {
+	testutil.InitRepo(t, map[string]string{"a.txt": "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"})
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile("a.txt", []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("1\nTWO\n3\n4\n5\n6\n7\n8\n9\n10\n")
+	testutil.Git(t, "add", "a.txt")
+	write("1\nTWO\n3\n4\n5\n6\n7\n8\nNINE\n10\n")
+
+	m := New(false)
+	m.width, m.height = 120, 40
+	m.updateLayout()
+	m.currentFile = "a.txt"
+	m.threeWay = true
+	model, _ := m.Update(m.loadThreeWay("a.txt")())
+	m = model.(Model)
+	m.switchFocus()
+
+	view := m.diffView.View()
+	for _, want := range []string{"S", "U", "TWO", "NINE"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("three-way view lacks %q:\n%s", want, view)
+		}
+	}
+
+	// Move to the unstaged NINE and stage it
+	for range 20 {
+		infos := m.diffView.GetLineStagingInfo()
+		if len(infos) == 1 && slices.ContainsFunc(infos[0].LineIndices, func(i int) bool {
+			return infos[0].Hunk.Lines[i].Content == "NINE"
+		}) {
+			break
+		}
+		model, _ = m.Update(tea.KeyPressMsg{Code: 'j', Text: "j"})
+		m = model.(Model)
+	}
+	model, cmd := m.Update(tea.KeyPressMsg{Code: 's', Text: "s"})
+	m = model.(Model)
+	if cmd == nil {
+		t.Fatal("s should stage the line")
+	}
+	if msg, ok := cmd().(types.StageCompleteMsg); !ok || msg.Err != nil {
+		t.Fatalf("stage = %+v", msg)
+	}
+	if got := testutil.Git(t, "diff", "--cached", "--", "a.txt"); !strings.Contains(got, "+NINE") || !strings.Contains(got, "+TWO") {
+		t.Errorf("index should hold both changes:\n%s", got)
+	}
+	if got := testutil.Git(t, "diff", "--", "a.txt"); got != "" {
+		t.Errorf("nothing should stay unstaged:\n%s", got)
+	}
+
+	// The view reloads the same file as three-way
+	m.files = []diff.FileEntry{{Path: "a.txt", Staged: true}}
+	tw, ok := m.reloadDiff()().(threeWayLoadedMsg)
+	if !ok || tw.diff.Unstaged != nil || len(tw.diff.File.Hunks) == 0 {
+		t.Errorf("reload = %+v, want a three-way diff with everything staged", tw)
+	}
+}
+
+func TestStagePreviewConfirmsBeforeStaging(t *testing.T) {
+	testutil.InitRepo(t, map[string]string{"a.go": "package a\n\nvar x = 1\n"})
+	if err := os.WriteFile("a.go", []byte("package a\n\nvar x = 2\nvar y = 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(false)
+	m.width, m.height = 120, 40
+	m.updateLayout()
+	m.currentFile = "a.go"
+	model, _ := m.Update(m.loadDiff("a.go", false)())
+	m = model.(Model)
+	m.switchFocus()
+
+	// Move to the added "var y" line
+	for range 10 {
+		infos := m.diffView.GetLineStagingInfo()
+		if len(infos) == 1 && infos[0].Hunk.Lines[infos[0].LineIndices[0]].Content == "var y = 3" {
+			break
+		}
+		model, _ = m.Update(tea.KeyPressMsg{Code: 'j', Text: "j"})
+		m = model.(Model)
+	}
+
+	preview := func() {
+		t.Helper()
+		model, cmd := m.Update(tea.KeyPressMsg{Code: 'K', Text: "K"})
+		m = model.(Model)
+		if cmd == nil {
+			t.Fatal("K should compute a preview")
+		}
+		model, _ = m.Update(cmd())
+		m = model.(Model)
+		if !m.preview.Visible() || !strings.Contains(m.View().Content, "var y = 3") {
+			t.Fatalf("preview should show the staged line:\n%s", m.View().Content)
+		}
+	}
+
+	preview()
+	model, cmd := m.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	m = model.(Model)
+	model, _ = m.Update(cmd())
+	m = model.(Model)
+	if got := testutil.Git(t, "diff", "--cached"); got != "" {
+		t.Fatalf("cancelling should stage nothing:\n%s", got)
+	}
+
+	preview()
+	model, cmd = m.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	m = model.(Model)
+	model, cmd = m.Update(cmd())
+	m = model.(Model)
+	if cmd == nil {
+		t.Fatal("confirming should stage")
+	}
+	if msg, ok := cmd().(types.StageCompleteMsg); !ok || msg.Err != nil {
+		t.Fatalf("stage = %+v", msg)
+	}
+	if got := testutil.Git(t, "diff", "--cached"); !strings.Contains(got, "+var y = 3") || strings.Contains(got, "x = 2") {
+		t.Errorf("only var y should be staged:\n%s", got)
+	}
+}
+
+func TestHunkEditedMessage(t *testing.T) {
+	applyErr := &git.GitError{Stderr: "error: patch failed: a.txt:1\nerror: a.txt: patch does not apply\n"}
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, "Staged edited hunk: a.txt"},
+		{git.ErrEmptyEdit, "Nothing staged: the edited hunk has no changes left"},
+		{applyErr, "Edited hunk not staged: patch failed: a.txt:1"},
+	}
+	for _, tt := range tests {
+		if got := hunkEditedMessage(hunkEditedMsg{path: "a.txt", err: tt.err}); got != tt.want {
+			t.Errorf("hunkEditedMessage(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestInlineCommitPrefillsTemplate(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if out, err := exec.Command("git", "init", "-q", "-b", "feature/OPS-42-retry").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	cfg := config.DefaultConfig()
+	cfg.CommitTemplate = "{ticket}: "
+	m := NewWithConfig(false, cfg)
+	m.width, m.height = 120, 40
+	m.updateLayout()
+
+	model, cmd := m.Update(tea.KeyPressMsg{Code: 'i', Text: "i"})
+	m = model.(Model)
+	var info commitInfoMsg
+	for _, msg := range cmd().(tea.BatchMsg) {
+		if msg == nil {
+			continue
+		}
+		if got, ok := msg().(commitInfoMsg); ok {
+			info = got
+		}
+	}
+	model, _ = m.Update(info)
+	m = model.(Model)
+	if got := m.commitInput.Value(); got != "OPS-42: " {
+		t.Errorf("inline message = %q, want OPS-42: ", got)
+	}
+}
+
+func TestInlineCommitBlockedByLintError(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.CommitLint.ForbiddenWords = commitmsg.ListRule{Level: commitmsg.Error, Values: []string{"wip"}}
+	m := NewWithConfig(false, cfg)
+	m.width, m.height = 120, 40
+	m.updateLayout()
+	m.focused = types.PaneCommitInput
+	m.commitInput.Focus()
+	m.commitInput.SetValue("WIP: parser")
+
+	model, cmd := m.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	m = model.(Model)
+	if cmd != nil || m.focused != types.PaneCommitInput {
+		t.Fatal("a lint error should block the inline commit")
+	}
+	if got := m.statusBar.View(); !strings.Contains(got, "Commit blocked") {
+		t.Errorf("the error should be shown, status bar:\n%s", got)
+	}
+}
+
+func TestInlineCommitTakesLetterKeys(t *testing.T) {
+	m := New(false)
+	m.width, m.height = 120, 40
+	m.updateLayout()
+	m.focused = types.PaneCommitInput
+	m.commitInput.Focus()
+	layout := m.diffView.LayoutName()
+
+	// A key that reached a global binding, q included, would be missing
+	// from the message
+	const typed = "wWDoLFTEfKeXIqcCpPtZ?"
+	for _, r := range typed {
+		model, _ := m.Update(tea.KeyPressMsg{Code: r, Text: string(r)})
+		m = model.(Model)
+	}
+
+	if got := m.commitInput.Value(); got != typed {
+		t.Errorf("commit message = %q, want %q", got, typed)
+	}
+	if m.focused != types.PaneCommitInput {
+		t.Errorf("focus moved to pane %v", m.focused)
+	}
+	if m.diffStat.Visible() || m.diffOptions.Visible() || m.helpOverlay.Visible() || m.prompt.Visible() {
+		t.Error("typing opened an overlay")
+	}
+	if m.diffView.LayoutName() != layout || m.allFiles || m.showStaged || m.threeWay {
+		t.Error("typing changed the view")
+	}
+}
@@ -0,0 +1,24 @@
+package app
+
+import (
+	"context"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/commitmsg"
+)
+
+// commitInfoMsg carries the template, identity and co-authors for a
+// commit message
+type commitInfoMsg struct {
+	info commitmsg.Info
+	err  error
+}
+
+// loadCommitInfo loads what the commit dialogs prefill and offer
+func (m Model) loadCommitInfo() tea.Cmd {
+	template, pattern := m.commitTemplate, m.ticketPattern
+	return func() tea.Msg {
+		info, err := commitmsg.Load(context.Background(), template, pattern)
+		return commitInfoMsg{info: info, err: err}
+	}
+}
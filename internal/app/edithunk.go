@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/git"
+)
+
+// hunkEditedMsg reports the result of staging a hunk edited in $EDITOR
+type hunkEditedMsg struct {
+	path string
+	err  error
+}
+
+// editHunk opens the hunk under the cursor as a patch in $EDITOR, like
+// git add -e, suspending the TUI until the editor exits. What is saved is
+// recounted and staged.
+func (m *Model) editHunk() tea.Cmd {
+	if m.threeWay {
+		m.statusBar.SetMessage("Hunk editing is not available in the three-way view")
+		return nil
+	}
+	if m.showStaged {
+		m.statusBar.SetMessage("Switch to the unstaged view (t) to edit a hunk")
+		return nil
+	}
+	info := m.diffView.GetHunkStagingInfo()
+	if info == nil {
+		return nil
+	}
+
+	path := info.Path
+	tmpPath, err := git.CreateTempHunkFile(path, info.Hunk)
+	if err != nil {
+		return func() tea.Msg {
+			return hunkEditedMsg{path: path, err: err}
+		}
+	}
+	return tea.ExecProcess(git.EditorCmd(tmpPath), func(err error) tea.Msg {
+		defer os.Remove(tmpPath)
+		if err != nil {
+			return hunkEditedMsg{path: path, err: fmt.Errorf("editor: %w", err)}
+		}
+		edited, err := os.ReadFile(tmpPath)
+		if err != nil {
+			return hunkEditedMsg{path: path, err: err}
+		}
+		return hunkEditedMsg{path: path, err: git.StageEditedHunk(context.Background(), string(edited))}
+	})
+}
+
+// hunkEditedMessage describes a hunkEditedMsg for the status bar
+func hunkEditedMessage(msg hunkEditedMsg) string {
+	switch {
+	case errors.Is(msg.err, git.ErrEmptyEdit):
+		return "Nothing staged: the edited hunk has no changes left"
+	case msg.err != nil:
+		return "Edited hunk not staged: " + applyError(msg.err)
+	}
+	return "Staged edited hunk: " + msg.path
+}
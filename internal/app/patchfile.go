@@ -0,0 +1,351 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/git"
+	"github.com/Danny-Dasilva/gdiff/internal/types"
+	"github.com/Danny-Dasilva/gdiff/internal/ui/diffview"
+	"github.com/Danny-Dasilva/gdiff/internal/ui/prompt"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+)
+
+// Prompt IDs
+const (
+	promptExport = "export"
+	promptOpen   = "open"
+)
+
+// foreignDiff is a patch file, commit or range opened in the static view
+// so its hunks, lines or characters can be applied to the working tree or
+// index. It keeps the view state to restore when it is closed.
+type foreignDiff struct {
+	rev        string // commit or A..B range the diff comes from, "" for a patch file
+	allFiles   bool
+	showStaged bool
+}
+
+// foreignChange is one piece of an opened patch to apply: a hunk, or some
+// of its lines or characters
+type foreignChange struct {
+	path  string
+	hunk  diff.Hunk
+	patch string
+}
+
+// foreignLoadedMsg carries a patch file or commit read for the patch view
+type foreignLoadedMsg struct {
+	label string
+	rev   string
+	diffs []diff.FileDiff
+	err   error
+}
+
+// patchAppliedMsg reports how the changes of a patch applied; conflicts
+// holds the hunk of each change that did not
+type patchAppliedMsg struct {
+	applied   int
+	conflicts []diffview.Conflict
+	lines     []string // path:line of each conflict
+	toIndex   bool
+}
+
+// patchExportedMsg reports a written patch file
+type patchExportedMsg struct {
+	path string
+	err  error
+}
+
+// patchKey handles export, open and, in the patch view, apply and close
+func (m Model) patchKey(msg tea.KeyPressMsg) (Model, tea.Cmd, bool) {
+	if m.focused != types.PaneFileTree && m.focused != types.PaneDiffView {
+		return m, nil, false
+	}
+	switch {
+	case key.Matches(msg, m.keyMap.ExportPatch):
+		name := "gdiff.patch"
+		if m.foreign != nil && m.foreign.rev != "" {
+			name = strings.NewReplacer("/", "-", "~", "-", "^", "-").Replace(m.foreign.rev) + ".patch"
+		}
+		m.prompt.SetSize(m.width, m.height)
+		return m, m.prompt.Show(promptExport, "Export selection as patch to:", name), true
+
+	case key.Matches(msg, m.keyMap.OpenPatch) && m.static == nil:
+		m.prompt.SetSize(m.width, m.height)
+		return m, m.prompt.Show(promptOpen, "Open patch file, commit or A..B range:", ""), true
+	}
+
+	if m.foreign == nil {
+		return m, nil, false
+	}
+	switch {
+	case key.Matches(msg, m.keyMap.ApplyPatch), key.Matches(msg, m.keyMap.ApplyPatchIndex):
+		toIndex := key.Matches(msg, m.keyMap.ApplyPatchIndex)
+		changes := m.foreignChanges()
+		if len(changes) == 0 {
+			m.statusBar.SetMessage("Nothing to apply")
+			return m, nil, true
+		}
+		m.diffView.ExitVisualMode()
+		target := "worktree"
+		if toIndex {
+			target = "index"
+		}
+		return m, tea.Batch(m.statusBar.StartSpinner("Applying to "+target+"..."), applyForeign(changes, toIndex)), true
+
+	case key.Matches(msg, m.keyMap.Escape) && !m.diffView.IsInVisualMode():
+		return m, m.closeForeign(), true
+	}
+	return m, nil, false
+}
+
+// promptSubmitted runs the action a prompt was opened for
+func (m Model) promptSubmitted(msg prompt.SubmitMsg) (Model, tea.Cmd) {
+	if msg.Value == "" {
+		return m, nil
+	}
+	switch msg.ID {
+	case promptExport:
+		path := expandHome(msg.Value)
+		if _, err := os.Stat(path); err == nil {
+			m.pendingConfirm = &confirmAction{
+				prompt: "Overwrite " + msg.Value + "? (y/N)",
+				cmd:    m.exportPatch(path),
+			}
+			m.statusBar.SetMessage(m.pendingConfirm.prompt)
+			return m, nil
+		}
+		return m, m.exportPatch(path)
+
+	case promptOpen:
+		return m, tea.Batch(m.statusBar.StartSpinner("Opening "+msg.Value+"..."), loadForeign(msg.Value))
+	}
+	return m, nil
+}
+
+// expandHome replaces a leading ~/ with the home directory
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// exportPatch writes the current selection as a patch file. Selections
+// from a commit get the commit's format-patch mail header.
+func (m Model) exportPatch(path string) tea.Cmd {
+	picks := m.collectPicks()
+	var rev string
+	if m.foreign != nil {
+		rev = m.foreign.rev
+	}
+	return func() tea.Msg {
+		if len(picks) == 0 {
+			return patchExportedMsg{path: path, err: errors.New("nothing selected")}
+		}
+		ctx := context.Background()
+		var b strings.Builder
+		// A single commit keeps its author and message, for git am
+		if rev != "" && !strings.Contains(rev, "..") {
+			header, err := git.FormatPatchHeader(ctx, rev)
+			if err != nil {
+				return patchExportedMsg{path: path, err: err}
+			}
+			b.WriteString(header)
+		}
+		for _, p := range picks {
+			patch, err := m.pickPatch(ctx, p)
+			if err != nil {
+				return patchExportedMsg{path: path, err: err}
+			}
+			b.WriteString(patch)
+		}
+		err := os.WriteFile(path, []byte(b.String()), 0o644)
+		return patchExportedMsg{path: path, err: err}
+	}
+}
+
+// loadForeign reads a patch file or, when no such file exists, the diff of
+// a commit or range
+func loadForeign(source string) tea.Cmd {
+	return func() tea.Msg {
+		path := expandHome(source)
+		if data, err := os.ReadFile(path); err == nil {
+			diffs := diff.Parse(string(data))
+			if len(diffs) == 0 {
+				return foreignLoadedMsg{err: fmt.Errorf("no diff in %s", source)}
+			}
+			return foreignLoadedMsg{label: filepath.Base(path), diffs: diffs}
+		}
+
+		ctx := context.Background()
+		if !git.ResolveCommit(ctx, source) && !git.ResolveRange(ctx, source) {
+			return foreignLoadedMsg{err: fmt.Errorf("%s is not a patch file, commit or range", source)}
+		}
+		diffs, err := git.GetRevisionDiff(ctx, source, git.DefaultDiffOptions())
+		if err == nil && len(diffs) == 0 {
+			err = fmt.Errorf("%s has no changes", source)
+		}
+		return foreignLoadedMsg{label: source, rev: source, diffs: diffs, err: err}
+	}
+}
+
+// openForeign shows a loaded patch or commit in the static view
+func (m *Model) openForeign(msg foreignLoadedMsg) tea.Cmd {
+	m.foreign = &foreignDiff{rev: msg.rev, allFiles: m.allFiles, showStaged: m.showStaged}
+	m.static = msg.diffs
+	m.staticLabel = msg.label
+	m.allFiles = true
+	m.showStaged = false
+	m.threeWay, m.threeWayDiff = false, nil
+	m.diffCache = map[string][]diff.FileDiff{allDiffsCacheKey(false, m.diffOpts): msg.diffs}
+	m.diffView.ClearPicks()
+	m.diffView.SetConflicts(nil)
+	m.fileTree.ClearMarks()
+	m.statusBar.SetMode("PATCH")
+	m.focused = types.PaneDiffView
+	m.fileTree.SetFocused(false)
+	m.diffView.SetFocused(true)
+	m.statusBar.SetFocusedPane(m.focused)
+	m.statusBar.SetMessage("m: mark hunk · v/V: select · a: apply to worktree · A: apply to index · esc: close")
+
+	files := staticFiles(msg.diffs)
+	return func() tea.Msg {
+		return types.StatusLoadedMsg{Files: files}
+	}
+}
+
+// closeForeign leaves the patch view and reloads the repository's changes
+func (m *Model) closeForeign() tea.Cmd {
+	f := m.foreign
+	m.foreign = nil
+	m.static = nil
+	m.staticLabel = ""
+	m.allFiles = f.allFiles
+	m.showStaged = f.showStaged
+	m.diffCache = make(map[string][]diff.FileDiff)
+	m.diffView.ClearPicks()
+	m.diffView.SetConflicts(nil)
+	m.fileTree.ClearMarks()
+	if m.showStaged {
+		m.statusBar.SetMode("STAGED")
+	} else {
+		m.statusBar.SetMode("NORMAL")
+	}
+	m.statusBar.ClearMessage()
+	return tea.Batch(m.statusBar.StartSpinner("Loading status..."), m.loadStatus())
+}
+
+// foreignChanges returns what a/A apply: the visual selection of lines or
+// characters, else the marked hunks, else every hunk of the patch
+func (m Model) foreignChanges() []foreignChange {
+	var changes []foreignChange
+	if m.diffView.IsInCharMode() {
+		if info := m.diffView.GetCharStagingInfo(); info != nil {
+			if fd, ok := m.foreignFile(info.Path); ok {
+				changes = append(changes, foreignChange{
+					path:  info.Path,
+					hunk:  info.Hunk,
+					patch: git.ForeignCharacterPatch(fd, info.Hunk, info.HunkLineIndex, info.CharStart, info.CharEnd),
+				})
+			}
+		}
+		return changes
+	}
+	if m.diffView.IsInVisualMode() {
+		for _, info := range m.diffView.GetLineStagingInfo() {
+			if fd, ok := m.foreignFile(info.Path); ok {
+				changes = append(changes, foreignChange{
+					path:  info.Path,
+					hunk:  info.Hunk,
+					patch: git.ForeignLinesPatch(fd, info.Hunk, info.LineIndices),
+				})
+			}
+		}
+		return changes
+	}
+
+	picked := m.diffView.PickedHunks()
+	for _, fd := range m.static {
+		for _, h := range fd.Hunks {
+			if len(picked) > 0 && !slices.ContainsFunc(picked, func(p diffview.HunkStagingInfo) bool {
+				return p.Path == fd.NewPath && p.Hunk.Header == h.Header
+			}) {
+				continue
+			}
+			changes = append(changes, foreignChange{path: fd.NewPath, hunk: h, patch: git.FilePatch(fd, []diff.Hunk{h})})
+		}
+	}
+	return changes
+}
+
+// foreignFile returns the file diff of the opened patch for a path
+func (m Model) foreignFile(path string) (diff.FileDiff, bool) {
+	for _, fd := range m.static {
+		if fd.NewPath == path {
+			return fd, true
+		}
+	}
+	return diff.FileDiff{}, false
+}
+
+// applyForeign applies changes one at a time, from the last up so earlier
+// hunks of a file keep their line numbers, and reports each that did not
+// apply
+func applyForeign(changes []foreignChange, toIndex bool) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		result := patchAppliedMsg{toIndex: toIndex}
+		for i := len(changes) - 1; i >= 0; i-- {
+			c := changes[i]
+			if err := git.ApplyPatch(ctx, c.patch, toIndex); err != nil {
+				result.conflicts = append([]diffview.Conflict{{Path: c.path, Header: c.hunk.Header, Reason: applyError(err)}}, result.conflicts...)
+				result.lines = append([]string{fmt.Sprintf("%s:%d", c.path, max(c.hunk.NewStart, 1))}, result.lines...)
+				continue
+			}
+			result.applied++
+		}
+		return result
+	}
+}
+
+// applyError returns the first line of git apply's complaint, such as
+// "patch failed: a.go:12"
+func applyError(err error) string {
+	var gitErr *git.GitError
+	if !errors.As(err, &gitErr) {
+		return err.Error()
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(gitErr.Stderr), "\n")
+	return strings.TrimPrefix(line, "error: ")
+}
+
+// applySummary describes a patchAppliedMsg for the status bar
+func applySummary(msg patchAppliedMsg) string {
+	target := "worktree"
+	if msg.toIndex {
+		target = "index"
+	}
+	summary := "Applied " + pluralChanges(msg.applied) + " to the " + target
+	if len(msg.lines) > 0 {
+		summary += fmt.Sprintf("; %d did not apply: %s", len(msg.lines), strings.Join(msg.lines, ", "))
+	}
+	return summary
+}
+
+func pluralChanges(n int) string {
+	if n == 1 {
+		return "1 change"
+	}
+	return fmt.Sprintf("%d changes", n)
+}
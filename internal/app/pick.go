@@ -0,0 +1,208 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/config"
+	"github.com/Danny-Dasilva/gdiff/internal/git"
+	"github.com/Danny-Dasilva/gdiff/internal/types"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+)
+
+// PickFormats lists the output formats of pick mode
+var PickFormats = []string{"paths", "lines", "patch"}
+
+// pick is a file, hunk or set of lines chosen in pick mode
+type pick struct {
+	path   string
+	staged bool
+	hunk   *diff.Hunk // nil for the whole file
+	lines  []int      // indices into hunk.Lines, nil for the whole hunk
+}
+
+// NewPick creates a model for pick mode, where Enter chooses the marked
+// files and hunks, or the visual line selection, and quits so the caller
+// can print them with PickOutput
+func NewPick(colorblind bool, cfg config.Config) Model {
+	m := NewWithConfig(colorblind, cfg)
+	m.picking = true
+	m.statusBar.SetMode("PICK")
+	m.statusBar.SetMessage("m: mark file or hunk · enter: pick · q: cancel")
+	return m
+}
+
+// markHunkKey marks or unmarks the hunk under the cursor in the diff view,
+// for pick mode, patch export and applying an opened patch
+func (m Model) markHunkKey(msg tea.KeyPressMsg) (Model, bool) {
+	if m.focused != types.PaneDiffView || !key.Matches(msg, m.keyMap.MarkHunk) {
+		return m, false
+	}
+	marked, ok := m.diffView.TogglePick()
+	switch {
+	case !ok:
+		m.statusBar.SetMessage("No hunk under the cursor")
+	case marked:
+		m.statusBar.SetMessage(fmt.Sprintf("Marked hunk (%d marked)", len(m.diffView.PickedHunks())))
+	default:
+		m.statusBar.SetMessage(fmt.Sprintf("Unmarked hunk (%d marked)", len(m.diffView.PickedHunks())))
+	}
+	return m, true
+}
+
+// pickKey handles Enter in pick mode, which picks and quits
+func (m Model) pickKey(msg tea.KeyPressMsg) (Model, tea.Cmd, bool) {
+	if !m.picking || (m.focused != types.PaneFileTree && m.focused != types.PaneDiffView) {
+		return m, nil, false
+	}
+	if key.Matches(msg, m.keyMap.Enter) {
+		m.picks = m.collectPicks()
+		if len(m.picks) == 0 {
+			m.statusBar.SetMessage("Nothing to pick")
+			return m, nil, true
+		}
+		return m, tea.Quit, true
+	}
+	return m, nil, false
+}
+
+// collectPicks gathers marked files and hunks plus any visual selection,
+// falling back to the hunk or file under the cursor when nothing is marked
+func (m Model) collectPicks() []pick {
+	var picks []pick
+	for _, f := range m.fileTree.MarkedFiles() {
+		picks = append(picks, pick{path: f.Path, staged: f.Staged})
+	}
+	for _, h := range m.diffView.PickedHunks() {
+		picks = append(picks, pick{path: h.Path, staged: m.showStaged, hunk: &h.Hunk})
+	}
+	if m.focused == types.PaneDiffView && m.diffView.IsInVisualMode() {
+		for _, info := range m.diffView.GetLineStagingInfo() {
+			picks = append(picks, pick{path: info.Path, staged: m.showStaged, hunk: &info.Hunk, lines: info.LineIndices})
+		}
+	}
+	if len(picks) > 0 {
+		return picks
+	}
+
+	if m.focused == types.PaneDiffView {
+		if info := m.diffView.GetHunkStagingInfo(); info != nil {
+			return []pick{{path: info.Path, staged: m.showStaged, hunk: &info.Hunk}}
+		}
+	}
+	if f := m.fileTree.SelectedFile(); f != nil {
+		return []pick{{path: f.Path, staged: f.Staged}}
+	}
+	// On a section header, the file shown in the diff view
+	if m.currentFile != "" {
+		return []pick{{path: m.currentFile, staged: m.showStaged}}
+	}
+	return nil
+}
+
+// Picked reports whether pick mode ended with a choice
+func (m Model) Picked() bool {
+	return len(m.picks) > 0
+}
+
+// PickOutput formats the picks in one of PickFormats: one path per file,
+// path:line for the first change of each pick, or patch text
+func (m Model) PickOutput(ctx context.Context, format string) (string, error) {
+	var b strings.Builder
+	var seen []string
+	for _, p := range m.picks {
+		switch format {
+		case "paths":
+			if !slices.Contains(seen, p.path) {
+				seen = append(seen, p.path)
+				b.WriteString(p.path + "\n")
+			}
+
+		case "lines":
+			line := 1
+			if p.hunk != nil {
+				line = changeLine(*p.hunk, p.lines)
+			} else if diffs, err := git.GetFileDiff(ctx, p.path, p.staged, m.diffOpts); err == nil && len(diffs) > 0 && len(diffs[0].Hunks) > 0 {
+				line = changeLine(diffs[0].Hunks[0], nil)
+			}
+			fmt.Fprintf(&b, "%s:%d\n", p.path, line)
+
+		case "patch":
+			patch, err := m.pickPatch(ctx, p)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(patch)
+
+		default:
+			return "", fmt.Errorf("unknown pick format %q (want %s)", format, strings.Join(PickFormats, ", "))
+		}
+	}
+	return b.String(), nil
+}
+
+// pickPatch returns the patch of a pick: the file's git diff, or a patch
+// holding just the hunk or lines
+func (m Model) pickPatch(ctx context.Context, p pick) (string, error) {
+	switch {
+	case p.hunk != nil && p.lines != nil:
+		return git.LinesPatch(p.path, *p.hunk, p.lines, false), nil
+	case p.hunk != nil:
+		return git.HunkPatch(p.path, *p.hunk), nil
+	}
+
+	// A static diff has no repository behind it to diff again
+	if m.static != nil {
+		var b strings.Builder
+		for _, fd := range m.static {
+			if fd.NewPath == p.path {
+				b.WriteString(git.FilePatch(fd, fd.Hunks))
+			}
+		}
+		return b.String(), nil
+	}
+
+	args := []string{"diff", "--no-color"}
+	if p.staged {
+		args = append(args, "--cached")
+	}
+	out, err := git.RunGitCommand(ctx, append(args, "--", p.path)...)
+	if err != nil || out != "" {
+		return out, err
+	}
+	// Untracked files have no diff against the index
+	return git.NoIndexDiff(ctx, "/dev/null", p.path, git.DefaultDiffOptions())
+}
+
+// changeLine returns the new-file line of the first selected change in a
+// hunk, all changes when indices is nil. A removed line counts as the line
+// that follows it.
+func changeLine(h diff.Hunk, indices []int) int {
+	line := 0
+	next := h.NewStart + h.NewCount
+	for i := len(h.Lines) - 1; i >= 0; i-- {
+		l := h.Lines[i]
+		selected := indices == nil || slices.Contains(indices, i)
+		switch l.Type {
+		case diff.LineContext:
+			next = l.NewNum
+		case diff.LineAdded:
+			next = l.NewNum
+			if selected {
+				line = l.NewNum
+			}
+		case diff.LineRemoved:
+			if selected {
+				line = next
+			}
+		}
+	}
+	if line == 0 {
+		line = h.NewStart
+	}
+	return max(line, 1)
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/git"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+)
+
+// stagePreviewMsg carries the index copy of a file as it would be after a
+// pending stage, and the command that performs the stage
+type stagePreviewMsg struct {
+	path  string
+	lines []diff.Line
+	err   error
+	stage tea.Cmd
+}
+
+// previewStage applies the patch that staging the character or line
+// selection would apply to the index copy of the file in memory, and shows
+// the result for confirmation. Nothing is staged until it is confirmed.
+func (m *Model) previewStage() tea.Cmd {
+	if m.threeWay {
+		m.statusBar.SetMessage("Stage preview is not available in the three-way view")
+		return nil
+	}
+	if m.showStaged {
+		m.statusBar.SetMessage("Switch to the unstaged view (t) to preview staging")
+		return nil
+	}
+
+	var path string
+	var patches []string
+	var stage tea.Cmd
+	if m.diffView.IsInCharMode() {
+		info := m.diffView.GetCharStagingInfo()
+		if info == nil {
+			return nil
+		}
+		path = info.Path
+		patches = append(patches, git.BuildCharacterPatch(info.Path, info.Hunk, info.HunkLineIndex, info.CharStart, info.CharEnd))
+		stage = m.stageCharacters(info.Path, info.Hunk, info.HunkLineIndex, info.CharStart, info.CharEnd)
+	} else {
+		infos := m.diffView.GetLineStagingInfo()
+		if len(infos) == 0 {
+			return nil
+		}
+		path = infos[0].Path
+		for _, info := range infos {
+			if info.Path != path {
+				m.statusBar.SetMessage("Select lines from one file to preview staging")
+				return nil
+			}
+			patches = append(patches, git.LinesPatch(info.Path, info.Hunk, info.LineIndices, false))
+		}
+		stage = m.stageLines(infos, false)
+	}
+
+	return func() tea.Msg {
+		lines, err := git.PreviewStage(context.Background(), path, patches...)
+		return stagePreviewMsg{path: path, lines: lines, err: err, stage: stage}
+	}
+}
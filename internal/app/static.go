@@ -0,0 +1,98 @@
+package app
+
+import (
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/config"
+	"github.com/Danny-Dasilva/gdiff/internal/types"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+)
+
+// NewStatic creates a read-only model browsing diffs that do not come from
+// the working tree, such as a diff piped in on stdin. Every file is shown
+// in one scrollable diff and label names the source in the title bar.
+func NewStatic(colorblind bool, cfg config.Config, diffs []diff.FileDiff, label string) Model {
+	m := NewWithConfig(colorblind, cfg)
+	m.static = diffs
+	m.staticLabel = label
+	m.allFiles = true
+	m.diffCache[allDiffsCacheKey(false, m.diffOpts)] = diffs
+	m.statusBar.SetMode("READ-ONLY")
+	m.statusBar.SetBranch(label)
+	return m
+}
+
+// staticFiles lists each file of a static diff once, in diff order
+func staticFiles(diffs []diff.FileDiff) []diff.FileEntry {
+	var files []diff.FileEntry
+	seen := make(map[string]bool)
+	for _, fd := range diffs {
+		if seen[fd.NewPath] {
+			continue
+		}
+		seen[fd.NewPath] = true
+
+		status := diff.StatusModified
+		switch {
+		case fd.OldPath != fd.NewPath:
+			status = diff.StatusRenamed
+		case len(fd.Hunks) == 1 && fd.Hunks[0].OldStart == 0 && fd.Hunks[0].OldCount == 0:
+			status = diff.StatusAdded
+		case len(fd.Hunks) == 1 && fd.Hunks[0].NewStart == 0 && fd.Hunks[0].NewCount == 0:
+			status = diff.StatusDeleted
+		}
+		files = append(files, diff.FileEntry{
+			Path:       fd.NewPath,
+			OldPath:    fd.OldPath,
+			Status:     status,
+			WorkStatus: status,
+		})
+	}
+	return files
+}
+
+// staticStats counts added and removed lines per file, like git diff --numstat
+func staticStats(diffs []diff.FileDiff) map[string][2]int {
+	stats := make(map[string][2]int)
+	for _, fd := range diffs {
+		s := stats[fd.NewPath]
+		for _, h := range fd.Hunks {
+			for _, l := range h.Lines {
+				switch l.Type {
+				case diff.LineAdded:
+					s[0]++
+				case diff.LineRemoved:
+					s[1]++
+				}
+			}
+		}
+		stats[fd.NewPath] = s
+	}
+	return stats
+}
+
+// readOnlyKey reports whether a key needs the repository, which a static
+// diff does not have
+func (m Model) readOnlyKey(msg tea.KeyPressMsg) bool {
+	if m.static == nil {
+		return false
+	}
+	switch {
+	case key.Matches(msg, m.keyMap.Commit), key.Matches(msg, m.keyMap.CommitAmend),
+		key.Matches(msg, m.keyMap.Push), key.Matches(msg, m.keyMap.ForcePush),
+		key.Matches(msg, m.keyMap.StageFile), key.Matches(msg, m.keyMap.UnstageFile),
+		key.Matches(msg, m.keyMap.RevertItem), key.Matches(msg, m.keyMap.Stash),
+		key.Matches(msg, m.keyMap.ToggleStagedView), key.Matches(msg, m.keyMap.ToggleAllFiles),
+		key.Matches(msg, m.keyMap.DiffOptions), key.Matches(msg, m.keyMap.ToggleThreeWay),
+		msg.String() == "i":
+		return true
+	case m.focused == types.PaneCommitInput:
+		return msg.String() == "enter"
+	case m.focused == types.PaneDiffView:
+		return key.Matches(msg, m.keyMap.StageItem) || key.Matches(msg, m.keyMap.UnstageItem) ||
+			key.Matches(msg, m.keyMap.StageHunk) || key.Matches(msg, m.keyMap.UnstageHunk) ||
+			key.Matches(msg, m.keyMap.SpaceToggle) || key.Matches(msg, m.keyMap.PreviewStage) ||
+			key.Matches(msg, m.keyMap.EditHunk)
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"context"
+	"slices"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/git"
+	"github.com/Danny-Dasilva/gdiff/internal/types"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+)
+
+// threeWayLoadedMsg carries a file's merged HEAD / index / worktree diff
+type threeWayLoadedMsg struct {
+	path string
+	diff diff.ThreeWay
+	err  error
+}
+
+// toggleThreeWay switches between the three-way view of the current file
+// and the staged or unstaged view
+func (m *Model) toggleThreeWay() tea.Cmd {
+	if m.threeWay {
+		m.leaveThreeWay()
+		m.statusBar.SetMessage("Showing single file")
+		if m.currentFile != "" {
+			return m.loadDiff(m.currentFile, m.showStaged)
+		}
+		return nil
+	}
+
+	m.threeWay = true
+	m.allFiles = false
+	m.statusBar.SetMode("3-WAY")
+	m.statusBar.SetMessage("S staged · U unstaged · I staged, then removed · s/u move lines · S/U move hunks")
+	if m.currentFile == "" {
+		return nil
+	}
+	return tea.Batch(m.statusBar.StartSpinner("Loading diff..."), m.loadThreeWay(m.currentFile))
+}
+
+// leaveThreeWay returns to the staged or unstaged view
+func (m *Model) leaveThreeWay() {
+	m.threeWay = false
+	m.threeWayDiff = nil
+	if m.showStaged {
+		m.statusBar.SetMode("STAGED")
+	} else {
+		m.statusBar.SetMode("NORMAL")
+	}
+}
+
+// loadThreeWay loads the three-way diff of a file. It is not cached: both
+// sides change with every stage and unstage made from the view.
+func (m *Model) loadThreeWay(path string) tea.Cmd {
+	if m.cancelDiffLoad != nil {
+		m.cancelDiffLoad()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelDiffLoad = cancel
+	opts := m.diffOpts
+
+	return func() tea.Msg {
+		tw, err := git.GetThreeWayDiff(ctx, path, opts)
+		if ctx.Err() != nil {
+			return nil
+		}
+		return threeWayLoadedMsg{path: path, diff: tw, err: err}
+	}
+}
+
+// threeWayStage moves the lines or hunk under the cursor between the
+// worktree and the index without leaving the three-way view. toggle, for
+// space, stages what the hunk has unstaged, or unstages it when it has
+// nothing unstaged.
+func (m *Model) threeWayStage(unstage, hunk, toggle bool) tea.Cmd {
+	tw := m.threeWayDiff
+	if tw == nil {
+		return nil
+	}
+	if m.diffView.IsInCharMode() {
+		m.statusBar.SetMessage("Character staging is not supported in the three-way view")
+		return nil
+	}
+
+	// Every merged hunk maps into the single whole-file hunk of each side
+	var stageIdx, unstageIdx []int
+	add := func(h diff.Hunk, lines []int) {
+		i := slices.IndexFunc(tw.File.Hunks, func(th diff.Hunk) bool { return th.Header == h.Header })
+		if i < 0 {
+			return
+		}
+		stageIdx = append(stageIdx, tw.StageIndices(i, lines)...)
+		unstageIdx = append(unstageIdx, tw.UnstageIndices(i, lines)...)
+	}
+	if hunk {
+		if info := m.diffView.GetHunkStagingInfo(); info != nil {
+			add(info.Hunk, allLines(info.Hunk))
+		}
+	} else {
+		for _, info := range m.diffView.GetLineStagingInfo() {
+			add(info.Hunk, info.LineIndices)
+		}
+	}
+	if toggle {
+		unstage = len(stageIdx) == 0
+	}
+	slices.Sort(stageIdx)
+	slices.Sort(unstageIdx)
+
+	path := m.currentFile
+	m.diffView.ExitVisualMode()
+	switch {
+	case unstage && len(unstageIdx) == 0:
+		m.statusBar.SetMessage("Nothing staged here")
+		return nil
+	case unstage:
+		staged := *tw.Staged
+		return func() tea.Msg {
+			err := git.UnstageLines(context.Background(), path, staged, unstageIdx)
+			return types.UnstageCompleteMsg{Path: path, Err: err}
+		}
+	case len(stageIdx) == 0:
+		m.statusBar.SetMessage("Nothing unstaged here")
+		return nil
+	default:
+		unstaged := *tw.Unstaged
+		return func() tea.Msg {
+			err := git.StageLines(context.Background(), path, unstaged, stageIdx)
+			return types.StageCompleteMsg{Path: path, Err: err}
+		}
+	}
+}
+
+// allLines returns the indices of every line of a hunk
+func allLines(h diff.Hunk) []int {
+	indices := make([]int, len(h.Lines))
+	for i := range indices {
+		indices[i] = i
+	}
+	return indices
+}
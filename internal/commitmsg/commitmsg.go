@@ -0,0 +1,197 @@
+// Package commitmsg prepares commit messages: templates with placeholders,
+// trailers and the co-authors offered for them
+package commitmsg
+
+import (
+	"cmp"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Danny-Dasilva/gdiff/internal/git"
+)
+
+// Trailer tokens
+const (
+	SignedOffBy  = "Signed-off-by"
+	CoAuthoredBy = "Co-authored-by"
+)
+
+// logDepth is how many recent commits co-authors are gathered from
+const logDepth = "1000"
+
+var (
+	// trailerRe matches a "Token: value" trailer line
+	trailerRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*\s*:\s*\S`)
+	// gitTrailerRe matches the lines git itself writes into trailer blocks
+	gitTrailerRe = regexp.MustCompile(`^(Signed-off-by: |\(cherry picked from commit )`)
+)
+
+// Info is what the commit dialogs need to prefill and extend a message
+type Info struct {
+	Template string   // expanded template, empty for none
+	Identity string   // "Name <email>" of the committer, empty when unset
+	Authors  []string // other authors from history, most frequent first
+}
+
+// Load gathers Info for the repository in the working directory. template
+// is gdiff's own template; when empty, the file named by commit.template
+// is used. {branch} and {ticket} are filled in, the ticket being the first
+// match of ticketPattern in the branch name. The Info is usable even when
+// an error is returned for an unreadable template.
+func Load(ctx context.Context, template, ticketPattern string) (Info, error) {
+	var info Info
+	name, _ := git.RunGitCommand(ctx, "config", "user.name")
+	email, _ := git.RunGitCommand(ctx, "config", "user.email")
+	if name, email = strings.TrimSpace(name), strings.TrimSpace(email); name != "" && email != "" {
+		info.Identity = name + " <" + email + ">"
+	}
+	info.Authors = logAuthors(ctx, info.Identity)
+
+	if template == "" {
+		var err error
+		if template, err = commitTemplate(ctx); err != nil {
+			return info, err
+		}
+	}
+	// Unlike rev-parse, this names the branch before its first commit too
+	out, _ := git.RunGitCommand(ctx, "branch", "--show-current")
+	branch := strings.TrimSpace(out)
+	ticket, err := Ticket(branch, ticketPattern)
+	if err != nil {
+		return info, err
+	}
+	info.Template = Expand(StripComments(template), branch, ticket)
+	return info, nil
+}
+
+// commitTemplate reads the file named by commit.template, "" when unset.
+// Relative paths are taken from the repository root.
+func commitTemplate(ctx context.Context) (string, error) {
+	out, err := git.RunGitCommand(ctx, "config", "--path", "commit.template")
+	path := strings.TrimSpace(out)
+	if err != nil || path == "" {
+		return "", nil
+	}
+	if !filepath.IsAbs(path) {
+		if root, err := git.GetRepoRoot(ctx); err == nil {
+			path = filepath.Join(root, path)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// logAuthors returns the authors of recent commits, most frequent first,
+// leaving out self
+func logAuthors(ctx context.Context, self string) []string {
+	out, err := git.RunGitCommand(ctx, "log", "-n", logDepth, "--format=%aN <%aE>")
+	if err != nil {
+		return nil
+	}
+	counts := make(map[string]int)
+	var authors []string
+	for _, author := range strings.Split(strings.TrimSpace(out), "\n") {
+		if author == "" || author == self {
+			continue
+		}
+		if counts[author] == 0 {
+			authors = append(authors, author)
+		}
+		counts[author]++
+	}
+	slices.SortStableFunc(authors, func(a, b string) int {
+		return cmp.Compare(counts[b], counts[a])
+	})
+	return authors
+}
+
+// Ticket returns the first match of pattern in a branch name, or "" when
+// the pattern is empty or does not match
+func Ticket(branch, pattern string) (string, error) {
+	if pattern == "" {
+		return "", nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.FindString(branch), nil
+}
+
+// Expand fills the {branch} and {ticket} placeholders of a template
+func Expand(template, branch, ticket string) string {
+	return strings.NewReplacer("{branch}", branch, "{ticket}", ticket).Replace(template)
+}
+
+// StripComments drops lines starting with # and surrounding blank lines,
+// as git does with a message edited from commit.template. Trailing spaces
+// are kept so a template can end where typing should continue. Messages given
+// with -m keep their comments, so templates are stripped up front.
+func StripComments(message string) string {
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// Subject returns the first line of a message
+func Subject(message string) string {
+	subject, _, _ := strings.Cut(message, "\n")
+	return subject
+}
+
+// AddTrailer appends a "token: value" trailer to a message the way
+// git interpret-trailers does by default: it joins the trailer block that
+// ends the message, or starts one after a blank line, and is skipped when
+// the trailer next to it is the same.
+func AddTrailer(message, token, value string) string {
+	trailer := token + ": " + value
+	message = strings.TrimRight(message, " \t\n")
+	if message == "" {
+		return "\n\n" + trailer
+	}
+
+	lines := strings.Split(message, "\n")
+	if trailerStart(lines) == len(lines) {
+		return message + "\n\n" + trailer
+	}
+
+	last := lines[len(lines)-1]
+	if lastToken, lastValue, ok := strings.Cut(last, ":"); ok &&
+		strings.EqualFold(strings.TrimSpace(lastToken), token) && strings.TrimSpace(lastValue) == value {
+		return message
+	}
+	return message + "\n" + trailer
+}
+
+// isTrailerBlock reports whether a paragraph is a trailer block: every
+// line is a trailer or a continuation line, or at least a quarter are and
+// one of them was written by git
+func isTrailerBlock(lines []string) bool {
+	var trailers, others int
+	gitWritten := false
+	for _, line := range lines {
+		switch {
+		case gitTrailerRe.MatchString(line):
+			gitWritten = true
+			trailers++
+		case trailerRe.MatchString(line):
+			trailers++
+		case strings.HasPrefix(line, " "), strings.HasPrefix(line, "\t"):
+			// continues the previous trailer
+		default:
+			others++
+		}
+	}
+	return trailers > 0 && (others == 0 || gitWritten && trailers*3 >= others)
+}
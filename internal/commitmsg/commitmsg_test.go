@@ -0,0 +1,86 @@
+package commitmsg
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddTrailerMatchesInterpretTrailers(t *testing.T) {
+	messages := []string{
+		"Fix parser",
+		"Fix parser\n\nHandle empty input.",
+		"Fix parser\n\nHandle empty input.\n\nReviewed-by: A <a@example.com>",
+		"Fix parser\n\nSigned-off-by: Test <test@example.com>",
+		"Fix parser\n\nRefs: #12\n  and #13",
+		"Fix parser\n\nSome notes\nSigned-off-by: B <b@example.com>",
+		"Fixes: the subject is not a trailer",
+	}
+	for _, msg := range messages {
+		cmd := exec.Command("git", "interpret-trailers", "--trailer", "Signed-off-by: Test <test@example.com>")
+		cmd.Stdin = strings.NewReader(msg + "\n")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("git interpret-trailers: %v", err)
+		}
+		want := strings.TrimRight(string(out), "\n")
+		if got := AddTrailer(msg, SignedOffBy, "Test <test@example.com>"); got != want {
+			t.Errorf("AddTrailer(%q) =\n%q\nwant\n%q", msg, got, want)
+		}
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	ticket, err := Ticket("feature/ABC-123-login", `[A-Z]+-[0-9]+`)
+	if err != nil || ticket != "ABC-123" {
+		t.Fatalf("Ticket() = %q, %v", ticket, err)
+	}
+	got := Expand(StripComments("# Subject\n{ticket}: \n\n# Why?\nOn {branch}\n"), "feature/ABC-123-login", ticket)
+	if want := "ABC-123: \n\nOn feature/ABC-123-login"; got != want {
+		t.Errorf("template = %q, want %q", got, want)
+	}
+	if _, err := Ticket("main", "("); err == nil {
+		t.Error("an invalid pattern should be reported")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Chdir(t.TempDir())
+	git := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "JIRA-7-fix")
+	git("config", "user.name", "Me")
+	git("config", "user.email", "me@example.com")
+	for _, author := range []string{"Ann <ann@example.com>", "Bob <bob@example.com>", "Bob <bob@example.com>", "Me <me@example.com>"} {
+		git("commit", "-q", "--allow-empty", "-m", "c", "--author", author)
+	}
+	if err := os.WriteFile(".gitmessage", []byte("# Describe the change\n[{ticket}] \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git("config", "commit.template", ".gitmessage")
+
+	info, err := Load(context.Background(), "", `[A-Z]+-[0-9]+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Info{
+		Template: "[JIRA-7] ",
+		Identity: "Me <me@example.com>",
+		Authors:  []string{"Bob <bob@example.com>", "Ann <ann@example.com>"},
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("Load() = %+v, want %+v", info, want)
+	}
+
+	// gdiff's own template wins over commit.template
+	if info, _ = Load(context.Background(), "{branch}: ", ""); info.Template != "JIRA-7-fix: " {
+		t.Errorf("Template = %q", info.Template)
+	}
+}
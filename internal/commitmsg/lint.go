@@ -0,0 +1,215 @@
+package commitmsg
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Level is how seriously a lint rule is taken. Errors block the commit,
+// warnings are only shown, and an empty level turns the rule off.
+type Level string
+
+const (
+	Off     Level = ""
+	Warning Level = "warning"
+	Error   Level = "error"
+)
+
+// UnmarshalJSON rejects unknown levels, so a typo such as "err" fails to
+// load instead of silently becoming a warning
+func (l *Level) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	switch Level(s) {
+	case Off, Warning, Error:
+		*l = Level(s)
+		return nil
+	}
+	return fmt.Errorf("unknown lint level %q (want %q, %q or %q)", s, Error, Warning, Off)
+}
+
+// LengthRule limits the length of lines
+type LengthRule struct {
+	Level Level `json:"level"`
+	Max   int   `json:"max"`
+}
+
+// ListRule checks a message against a list of words
+type ListRule struct {
+	Level  Level    `json:"level"`
+	Values []string `json:"values"`
+}
+
+// LintRules are the checks run on commit messages
+type LintRules struct {
+	// SubjectLength limits the first line
+	SubjectLength LengthRule `json:"subject_length"`
+	// BlankSecondLine requires the subject and body to be separated
+	BlankSecondLine Level `json:"blank_second_line"`
+	// BodyWrap limits the lines after the subject; trailers are exempt
+	BodyWrap LengthRule `json:"body_wrap"`
+	// ConventionalTypes requires a Conventional Commits subject,
+	// "type(scope)!: description", whose type is one of Values
+	ConventionalTypes ListRule `json:"conventional_types"`
+	// ConventionalScopes limits the optional scope to Values
+	ConventionalScopes ListRule `json:"conventional_scopes"`
+	// ForbiddenWords may not appear anywhere, matched as whole words
+	// ignoring case
+	ForbiddenWords ListRule `json:"forbidden_words"`
+	// RequiredTrailers must each be present in the trailer block
+	RequiredTrailers ListRule `json:"required_trailers"`
+}
+
+// DefaultLintRules warns about the git conventions every repository
+// follows; the stricter rules are opt-in
+func DefaultLintRules() LintRules {
+	return LintRules{
+		SubjectLength:   LengthRule{Level: Warning, Max: 72},
+		BlankSecondLine: Warning,
+		BodyWrap:        LengthRule{Level: Warning, Max: 72},
+	}
+}
+
+// Violation is a broken rule. Line is 1-based, 0 for the whole message.
+type Violation struct {
+	Level   Level
+	Line    int
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Line == 0 {
+		return v.Message
+	}
+	return fmt.Sprintf("line %d: %s", v.Line, v.Message)
+}
+
+// conventionalRe splits a Conventional Commits subject into type, scope
+// and description
+var conventionalRe = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]*)\))?!?: (\S.*)$`)
+
+// Lint checks a message against the rules, errors first. An empty message
+// has nothing to check.
+func Lint(message string, rules LintRules) []Violation {
+	message = strings.TrimRight(message, " \t\n")
+	if strings.TrimSpace(message) == "" {
+		return nil
+	}
+	lines := strings.Split(message, "\n")
+	subject := lines[0]
+
+	var vs []Violation
+	add := func(level Level, line int, format string, args ...any) {
+		if level != Off {
+			vs = append(vs, Violation{Level: level, Line: line, Message: fmt.Sprintf(format, args...)})
+		}
+	}
+
+	if r := rules.SubjectLength; r.Max > 0 {
+		if n := utf8.RuneCountInString(subject); n > r.Max {
+			add(r.Level, 1, "subject is %d characters, at most %d allowed", n, r.Max)
+		}
+	}
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		add(rules.BlankSecondLine, 2, "leave a blank line between subject and body")
+	}
+	if r := rules.BodyWrap; r.Max > 0 {
+		trailers := trailerStart(lines)
+		for i := 1; i < trailers; i++ {
+			if n := utf8.RuneCountInString(lines[i]); n > r.Max {
+				add(r.Level, i+1, "body line is %d characters, wrap at %d", n, r.Max)
+			}
+		}
+	}
+
+	if r := rules.ConventionalTypes; len(r.Values) > 0 {
+		if m := conventionalRe.FindStringSubmatch(subject); m == nil {
+			add(r.Level, 1, "subject should read \"type(scope): description\"")
+		} else if !slices.Contains(r.Values, m[1]) {
+			add(r.Level, 1, "type %q is not one of %s", m[1], strings.Join(r.Values, ", "))
+		}
+	}
+	if r := rules.ConventionalScopes; len(r.Values) > 0 {
+		if m := conventionalRe.FindStringSubmatch(subject); m != nil && m[2] != "" && !slices.Contains(r.Values, m[2]) {
+			add(r.Level, 1, "scope %q is not one of %s", m[2], strings.Join(r.Values, ", "))
+		}
+	}
+
+	if r := rules.ForbiddenWords; len(r.Values) > 0 {
+		for _, word := range r.Values {
+			re := wordRe(word)
+			for i, line := range lines {
+				if re.MatchString(line) {
+					add(r.Level, i+1, "%q is not allowed", word)
+					break
+				}
+			}
+		}
+	}
+
+	if r := rules.RequiredTrailers; len(r.Values) > 0 {
+		block := lines[trailerStart(lines):]
+		for _, token := range r.Values {
+			if !slices.ContainsFunc(block, func(line string) bool {
+				t, _, ok := strings.Cut(line, ":")
+				return ok && strings.EqualFold(strings.TrimSpace(t), token)
+			}) {
+				add(r.Level, 0, "missing %s trailer", token)
+			}
+		}
+	}
+
+	slices.SortStableFunc(vs, func(a, b Violation) int {
+		if a.Level == b.Level {
+			return 0
+		}
+		if a.Level == Error {
+			return -1
+		}
+		return 1
+	})
+	return vs
+}
+
+// HasErrors reports whether any violation is an error
+func HasErrors(vs []Violation) bool {
+	return slices.ContainsFunc(vs, func(v Violation) bool { return v.Level == Error })
+}
+
+// trailerStart returns the index of the first line of the message's
+// trailer block, or len(lines) when it has none. The subject paragraph
+// never holds trailers.
+func trailerStart(lines []string) int {
+	start := len(lines)
+	for start > 0 && strings.TrimSpace(lines[start-1]) != "" {
+		start--
+	}
+	if start == 0 || !isTrailerBlock(lines[start:]) {
+		return len(lines)
+	}
+	return start
+}
+
+// wordRe matches word as a whole word ignoring case. A boundary is only
+// required where the word starts or ends with a word character, so entries
+// such as "fixup!" still match.
+func wordRe(word string) *regexp.Regexp {
+	isWord := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	expr := regexp.QuoteMeta(word)
+	if first, _ := utf8.DecodeRuneInString(word); isWord(first) {
+		expr = `\b` + expr
+	}
+	if last, _ := utf8.DecodeLastRuneInString(word); isWord(last) {
+		expr += `\b`
+	}
+	return regexp.MustCompile(`(?i)` + expr)
+}
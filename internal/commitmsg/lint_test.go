@@ -0,0 +1,87 @@
+package commitmsg
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	rules := DefaultLintRules()
+	rules.SubjectLength.Level = Error
+	rules.ConventionalTypes = ListRule{Level: Error, Values: []string{"feat", "fix"}}
+	rules.ConventionalScopes = ListRule{Level: Warning, Values: []string{"ui", "git"}}
+	rules.ForbiddenWords = ListRule{Level: Error, Values: []string{"WIP"}}
+	rules.RequiredTrailers = ListRule{Level: Error, Values: []string{"Signed-off-by"}}
+
+	lint := func(message string) []string {
+		var got []string
+		for _, v := range Lint(message, rules) {
+			got = append(got, string(v.Level)+" "+v.String())
+		}
+		return got
+	}
+
+	if got := lint("fix(git): handle renames\n\nSigned-off-by: Me <me@example.com>"); got != nil {
+		t.Errorf("a clean message got %q", got)
+	}
+	if got := lint(""); got != nil {
+		t.Errorf("an empty message got %q", got)
+	}
+
+	got := lint("chore(docs): " + strings.Repeat("x", 70) + "\nwip notes " + strings.Repeat("y", 70))
+	want := []string{
+		"error line 1: subject is 83 characters, at most 72 allowed",
+		`error line 1: type "chore" is not one of feat, fix`,
+		`error line 2: "WIP" is not allowed`,
+		"error missing Signed-off-by trailer",
+		"warning line 2: leave a blank line between subject and body",
+		"warning line 2: body line is 80 characters, wrap at 72",
+		`warning line 1: scope "docs" is not one of ui, git`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lint =\n%q\nwant\n%q", got, want)
+	}
+
+	if HasErrors(Lint("feat: x\n\nSigned-off-by: Me <me@example.com>\n\n", rules)) {
+		t.Error("trailing blank lines should not hide the trailer block")
+	}
+	if got := lint("Add feature\n\nSigned-off-by: Me <me@example.com>"); len(got) != 1 || !strings.Contains(got[0], "type(scope): description") {
+		t.Errorf("non-conventional subject got %q", got)
+	}
+}
+
+func TestLintForbiddenWords(t *testing.T) {
+	rules := LintRules{ForbiddenWords: ListRule{Level: Error, Values: []string{"fixup!", "WIP", "#nocommit"}}}
+	tests := []struct {
+		message string
+		want    bool
+	}{
+		{"fixup! Add parser", true},
+		{"Add fixup handling", false},
+		{"wip: parser", true},
+		{"Add wiping", false},
+		{"Add parser #nocommit", true},
+		{"Add parser#nocommit", true},
+	}
+	for _, tt := range tests {
+		if got := HasErrors(Lint(tt.message, rules)); got != tt.want {
+			t.Errorf("Lint(%q) forbidden = %v, want %v", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestLevelUnmarshal(t *testing.T) {
+	var rules LintRules
+	if err := json.Unmarshal([]byte(`{"blank_second_line": "error", "subject_length": {"level": "warning", "max": 50}, "body_wrap": {"level": ""}}`), &rules); err != nil {
+		t.Fatal(err)
+	}
+	if rules.BlankSecondLine != Error || rules.SubjectLength.Level != Warning || rules.BodyWrap.Level != Off {
+		t.Errorf("levels = %+v", rules)
+	}
+	err := json.Unmarshal([]byte(`{"forbidden_words": {"level": "err", "values": ["WIP"]}}`), &rules)
+	if err == nil || !strings.Contains(err.Error(), `"err"`) {
+		t.Errorf("err = %v, want an unknown level error", err)
+	}
+}
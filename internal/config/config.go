@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+
+	"github.com/Danny-Dasilva/gdiff/internal/commitmsg"
 )
 
 // Config holds the application configuration
@@ -17,6 +19,20 @@ type Config struct {
 	// Performance settings
 	LargeDiffThreshold int `json:"large_diff_threshold"` // Lines before showing warning
 	MaxContextLines    int `json:"max_context_lines"`    // Context lines in diff
+
+	// WordDiffExtensions are the file extensions diffed word by word
+	// rather than line by line, e.g. ".md"
+	WordDiffExtensions []string `json:"word_diff_extensions"`
+
+	// CommitTemplate prefills new commit messages instead of git's
+	// commit.template. {branch} is replaced by the branch name and {ticket}
+	// by the first match of TicketPattern in it.
+	CommitTemplate string `json:"commit_template,omitempty"`
+	TicketPattern  string `json:"ticket_pattern"`
+
+	// CommitLint are the checks run on commit messages as they are typed;
+	// errors block the commit
+	CommitLint commitmsg.LintRules `json:"commit_lint"`
 }
 
 // Theme defines color settings
@@ -44,6 +60,9 @@ func DefaultConfig() Config {
 		},
 		LargeDiffThreshold: 5000,
 		MaxContextLines:    3,
+		WordDiffExtensions: []string{".md", ".markdown", ".rst", ".txt", ".adoc"},
+		TicketPattern:      `[A-Z][A-Z0-9]+-[0-9]+`,
+		CommitLint:         commitmsg.DefaultLintRules(),
 	}
 }
 
@@ -0,0 +1,82 @@
+package git
+
+import (
+	"context"
+	"strings"
+)
+
+// HeadCommit describes the commit an amend rewrites
+type HeadCommit struct {
+	Hash    string
+	Message string // full message, subject and body
+	Author  string // "Name <email>"
+	Date    string // author date
+	Files   []string
+
+	// Upstream is the branch's upstream, empty when it has none; Pushed is
+	// set when HEAD is already reachable from it
+	Upstream string
+	Pushed   bool
+}
+
+// AmendOptions are the choices offered when amending HEAD
+type AmendOptions struct {
+	NoEdit      bool // keep HEAD's message (--no-edit)
+	ResetAuthor bool // take the author from the current identity and the date from now
+	DateNow     bool // set the author date to now but keep the author
+}
+
+// GetHeadCommit returns HEAD's message, author, changed files and whether
+// it has been pushed to the upstream of the current branch
+func GetHeadCommit(ctx context.Context) (HeadCommit, error) {
+	out, err := RunGitCommand(ctx, "log", "-1", "--date=format:%Y-%m-%d %H:%M", "--format=%H%x00%an <%ae>%x00%ad%x00%B", "HEAD")
+	if err != nil {
+		return HeadCommit{}, err
+	}
+	parts := strings.SplitN(out, "\x00", 4)
+	if len(parts) != 4 {
+		return HeadCommit{}, &GitError{Command: "log -1 HEAD", Stderr: "unexpected output"}
+	}
+	head := HeadCommit{
+		Hash:    parts[0],
+		Author:  parts[1],
+		Date:    parts[2],
+		Message: strings.TrimSpace(parts[3]),
+	}
+
+	// --root lists the files of a first commit too
+	out, err = RunGitCommand(ctx, "diff-tree", "-z", "--no-commit-id", "--name-only", "-r", "--root", "HEAD")
+	if err != nil {
+		return HeadCommit{}, err
+	}
+	for _, path := range strings.Split(out, "\x00") {
+		if path != "" {
+			head.Files = append(head.Files, path)
+		}
+	}
+
+	if out, err := RunGitCommand(ctx, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}"); err == nil {
+		head.Upstream = strings.TrimSpace(out)
+		_, err := RunGitCommand(ctx, "merge-base", "--is-ancestor", "HEAD", "@{upstream}")
+		head.Pushed = err == nil
+	}
+	return head, nil
+}
+
+// AmendArgs returns the git commit arguments that amend HEAD with a message
+// and options. The message is ignored with NoEdit.
+func AmendArgs(message string, opts AmendOptions) []string {
+	args := []string{"commit", "--amend"}
+	if opts.NoEdit {
+		args = append(args, "--no-edit")
+	} else {
+		args = append(args, "-m", message)
+	}
+	switch {
+	case opts.ResetAuthor:
+		args = append(args, "--reset-author")
+	case opts.DateNow:
+		args = append(args, "--date=now")
+	}
+	return args
+}
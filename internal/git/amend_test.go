@@ -0,0 +1,64 @@
+package git
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Danny-Dasilva/gdiff/internal/testutil"
+)
+
+func TestGetHeadCommit(t *testing.T) {
+	testutil.InitRepo(t, map[string]string{"a file.txt": "a\n", "b.txt": "b\n"})
+	ctx := context.Background()
+
+	head, err := GetHeadCommit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Message != "Initial commit\n\nWith a body." {
+		t.Errorf("Message = %q", head.Message)
+	}
+	if head.Author != "Test <test@example.com>" {
+		t.Errorf("Author = %q", head.Author)
+	}
+	if !reflect.DeepEqual(head.Files, []string{"a file.txt", "b.txt"}) {
+		t.Errorf("Files = %q", head.Files)
+	}
+	if head.Upstream != "" || head.Pushed {
+		t.Errorf("no upstream expected, got %q pushed=%v", head.Upstream, head.Pushed)
+	}
+
+	remote := t.TempDir()
+	testutil.Git(t, "init", "-q", "--bare", remote)
+	testutil.Git(t, "remote", "add", "origin", remote)
+	testutil.Git(t, "push", "-q", "-u", "origin", "HEAD")
+	if head, _ = GetHeadCommit(ctx); !head.Pushed || head.Upstream == "" {
+		t.Errorf("HEAD should be pushed to its upstream, got %+v", head)
+	}
+
+	if err := os.WriteFile("b.txt", []byte("B\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testutil.Git(t, "commit", "-qam", "Local")
+	if head, _ = GetHeadCommit(ctx); head.Pushed {
+		t.Error("a local commit is not pushed")
+	}
+}
+
+func TestAmendArgs(t *testing.T) {
+	tests := []struct {
+		opts AmendOptions
+		want []string
+	}{
+		{AmendOptions{}, []string{"commit", "--amend", "-m", "msg"}},
+		{AmendOptions{NoEdit: true, DateNow: true}, []string{"commit", "--amend", "--no-edit", "--date=now"}},
+		{AmendOptions{ResetAuthor: true, DateNow: true}, []string{"commit", "--amend", "-m", "msg", "--reset-author"}},
+	}
+	for _, tt := range tests {
+		if got := AmendArgs("msg", tt.opts); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("AmendArgs(%+v) = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
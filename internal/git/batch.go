@@ -0,0 +1,197 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+)
+
+// BatchResult reports the outcome of a multi-file operation.
+// Paths lists every path in the batch; Errs holds per-file failures.
+type BatchResult struct {
+	Paths []string
+	Errs  map[string]error
+}
+
+// Failed returns the number of paths that could not be processed
+func (r BatchResult) Failed() int {
+	return len(r.Errs)
+}
+
+// Err summarizes all per-file failures in a single error, or returns nil
+func (r BatchResult) Err() error {
+	if len(r.Errs) == 0 {
+		return nil
+	}
+
+	paths := make([]string, 0, len(r.Errs))
+	for p := range r.Errs {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	parts := make([]string, 0, len(paths))
+	for _, p := range paths {
+		parts = append(parts, fmt.Sprintf("%s: %v", p, r.Errs[p]))
+	}
+	return errors.New(strings.Join(parts, "; "))
+}
+
+// StageFiles stages all paths with a single git add invocation
+func StageFiles(ctx context.Context, paths []string) BatchResult {
+	return runBatch(ctx, paths, func(p []string) []string {
+		return append([]string{"add", "--"}, p...)
+	})
+}
+
+// UnstageFiles unstages all paths with a single git reset invocation
+func UnstageFiles(ctx context.Context, paths []string) BatchResult {
+	return runBatch(ctx, paths, func(p []string) []string {
+		return append([]string{"reset", "HEAD", "--"}, p...)
+	})
+}
+
+// DiscardFiles drops the changes of the given files. Staged entries are
+// restored from HEAD in both the index and the working tree, unstaged ones
+// from the index; untracked files are removed.
+func DiscardFiles(ctx context.Context, files []diff.FileEntry) BatchResult {
+	var staged, unstaged, untracked []string
+	for _, f := range files {
+		switch {
+		case f.Staged:
+			staged = append(staged, f.Path)
+			if f.OldPath != "" && f.OldPath != f.Path {
+				// Restoring both sides undoes the rename
+				staged = append(staged, f.OldPath)
+			}
+		case f.WorkStatus == diff.StatusUntracked:
+			untracked = append(untracked, f.Path)
+		default:
+			unstaged = append(unstaged, f.Path)
+		}
+	}
+
+	result := BatchResult{Errs: make(map[string]error)}
+	if len(staged) > 0 {
+		r := runBatch(ctx, staged, func(p []string) []string {
+			return append([]string{"restore", "--staged", "--worktree", "--"}, p...)
+		})
+		result.merge(r)
+	}
+	if len(unstaged) > 0 {
+		r := runBatch(ctx, unstaged, func(p []string) []string {
+			return append([]string{"checkout", "--"}, p...)
+		})
+		result.merge(r)
+	}
+	if len(untracked) > 0 {
+		r := runBatch(ctx, untracked, func(p []string) []string {
+			return append([]string{"clean", "-f", "--"}, p...)
+		})
+		result.merge(r)
+	}
+	return result
+}
+
+// StashFiles stashes the changes of the given files with a single
+// git stash push invocation
+func StashFiles(ctx context.Context, files []diff.FileEntry, message string) BatchResult {
+	includeUntracked := false
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, f.Path)
+		if f.WorkStatus == diff.StatusUntracked {
+			includeUntracked = true
+		}
+	}
+
+	return runBatch(ctx, paths, func(p []string) []string {
+		args := []string{"stash", "push"}
+		if includeUntracked {
+			args = append(args, "--include-untracked")
+		}
+		if message != "" {
+			args = append(args, "-m", message)
+		}
+		return append(append(args, "--"), p...)
+	})
+}
+
+func (r *BatchResult) merge(other BatchResult) {
+	r.Paths = append(r.Paths, other.Paths...)
+	for p, err := range other.Errs {
+		r.Errs[p] = err
+	}
+}
+
+// runBatch runs a single git command for all paths. Git aborts the whole
+// command when one pathspec is bad, so on failure the offending paths are
+// identified from stderr and the command is retried once without them.
+func runBatch(ctx context.Context, paths []string, buildArgs func([]string) []string) BatchResult {
+	result := BatchResult{
+		Paths: paths,
+		Errs:  make(map[string]error),
+	}
+	if len(paths) == 0 {
+		return result
+	}
+
+	_, err := RunGitCommand(ctx, buildArgs(paths)...)
+	if err == nil {
+		return result
+	}
+
+	attributed := attributeBatchErrors(err, paths)
+	if len(attributed) == 0 {
+		// Nothing points at a specific file, so the whole batch failed
+		for _, p := range paths {
+			result.Errs[p] = err
+		}
+		return result
+	}
+
+	var remaining []string
+	for _, p := range paths {
+		if e, ok := attributed[p]; ok {
+			result.Errs[p] = e
+		} else {
+			remaining = append(remaining, p)
+		}
+	}
+
+	if len(remaining) > 0 {
+		if _, err := RunGitCommand(ctx, buildArgs(remaining)...); err != nil {
+			for _, p := range remaining {
+				result.Errs[p] = err
+			}
+		}
+	}
+
+	return result
+}
+
+// attributeBatchErrors maps stderr lines that quote a path back to that path
+func attributeBatchErrors(err error, paths []string) map[string]error {
+	var gitErr *GitError
+	if !errors.As(err, &gitErr) {
+		return nil
+	}
+
+	errs := make(map[string]error)
+	for _, line := range strings.Split(gitErr.Stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		for _, p := range paths {
+			if strings.Contains(line, "'"+p+"'") {
+				errs[p] = errors.New(line)
+			}
+		}
+	}
+	return errs
+}
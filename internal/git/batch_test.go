@@ -0,0 +1,148 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Danny-Dasilva/gdiff/internal/testutil"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+)
+
+func TestAttributeBatchErrors(t *testing.T) {
+	paths := []string{"main.go", "missing.go", "docs/readme.md"}
+
+	t.Run("maps quoted paths to their stderr line", func(t *testing.T) {
+		err := &GitError{
+			Command: "add -- main.go missing.go docs/readme.md",
+			Stderr:  "fatal: pathspec 'missing.go' did not match any files\n",
+			Err:     errors.New("exit status 128"),
+		}
+
+		errs := attributeBatchErrors(err, paths)
+		if len(errs) != 1 {
+			t.Fatalf("expected 1 attributed error, got %d: %v", len(errs), errs)
+		}
+		if e, ok := errs["missing.go"]; !ok || !strings.Contains(e.Error(), "did not match") {
+			t.Errorf("missing.go should carry the pathspec error, got %v", errs)
+		}
+	})
+
+	t.Run("unattributable errors return nothing", func(t *testing.T) {
+		err := &GitError{
+			Command: "add -- main.go",
+			Stderr:  "fatal: Unable to create '.git/index.lock': File exists.\n",
+			Err:     errors.New("exit status 128"),
+		}
+
+		if errs := attributeBatchErrors(err, paths); len(errs) != 0 {
+			t.Errorf("expected no attributed errors, got %v", errs)
+		}
+	})
+
+	t.Run("non-git errors return nothing", func(t *testing.T) {
+		if errs := attributeBatchErrors(errors.New("boom"), paths); errs != nil {
+			t.Errorf("expected nil, got %v", errs)
+		}
+	})
+}
+
+func TestBatchResultErr(t *testing.T) {
+	r := BatchResult{
+		Paths: []string{"a.go", "b.go", "c.go"},
+		Errs: map[string]error{
+			"c.go": errors.New("locked"),
+			"a.go": errors.New("not found"),
+		},
+	}
+
+	if r.Failed() != 2 {
+		t.Errorf("Failed() = %d, want 2", r.Failed())
+	}
+
+	want := "a.go: not found; c.go: locked"
+	if got := r.Err().Error(); got != want {
+		t.Errorf("Err() = %q, want %q", got, want)
+	}
+
+	if (BatchResult{Paths: []string{"a.go"}}).Err() != nil {
+		t.Error("Err() should be nil when nothing failed")
+	}
+}
+
+func TestBatchOperations(t *testing.T) {
+	testutil.InitRepo(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n", "c.txt": "c\n"})
+	ctx := context.Background()
+	for path, content := range map[string]string{"a.txt": "A\n", "b.txt": "B\n", "c.txt": "C\n", "new.txt": "new\n"} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	status := func() string {
+		t.Helper()
+		out, err := RunGitCommand(ctx, "status", "--porcelain")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	if err := StageFiles(ctx, []string{"a.txt", "b.txt"}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := UnstageFiles(ctx, []string{"b.txt"}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := status(), "M  a.txt\n M b.txt\n M c.txt\n?? new.txt\n"; got != want {
+		t.Fatalf("after stage and unstage:\n%s\nwant:\n%s", got, want)
+	}
+
+	// c.txt is staged and then changed again; discarding its staged entry
+	// drops both
+	if err := StageFiles(ctx, []string{"c.txt"}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("c.txt", []byte("CC\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result := DiscardFiles(ctx, []diff.FileEntry{
+		{Path: "a.txt", Staged: true, IndexStatus: diff.StatusModified},
+		{Path: "b.txt", WorkStatus: diff.StatusModified},
+		{Path: "c.txt", Staged: true, IndexStatus: diff.StatusModified},
+		{Path: "new.txt", WorkStatus: diff.StatusUntracked},
+	})
+	if err := result.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(); got != "" {
+		t.Errorf("after discard:\n%s\nwant a clean tree", got)
+	}
+	for path, want := range map[string]string{"a.txt": "a\n", "b.txt": "b\n", "c.txt": "c\n"} {
+		if data, err := os.ReadFile(path); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v, want %q", path, data, err, want)
+		}
+	}
+}
+
+func TestDiscardUnstagedKeepsIndex(t *testing.T) {
+	testutil.InitRepo(t, map[string]string{"a.txt": "a\n"})
+	ctx := context.Background()
+	if err := os.WriteFile("a.txt", []byte("staged\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := StageFiles(ctx, []string{"a.txt"}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("a.txt", []byte("unstaged\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DiscardFiles(ctx, []diff.FileEntry{{Path: "a.txt", WorkStatus: diff.StatusModified}}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile("a.txt"); err != nil || string(data) != "staged\n" {
+		t.Errorf("a.txt = %q, %v, want the staged content", data, err)
+	}
+}
@@ -1,14 +1,70 @@
 package git
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"github.com/Danny-Dasilva/gdiff/pkg/diff"
 )
 
+// Algorithms lists the diff algorithms git supports, in toggle order
+var Algorithms = []string{"histogram", "patience", "minimal", "myers"}
+
+// DiffOptions controls how git computes diffs
+type DiffOptions struct {
+	IgnoreAllSpace    bool   // -w
+	IgnoreSpaceChange bool   // -b
+	IgnoreBlankLines  bool   // --ignore-blank-lines
+	Algorithm         string // One of Algorithms
+	Context           int    // Lines of context (-U)
+}
+
+// DefaultDiffOptions returns the options gdiff starts with
+func DefaultDiffOptions() DiffOptions {
+	return DiffOptions{Algorithm: "histogram", Context: 3}
+}
+
+// IgnoresWhitespace reports whether any whitespace option is set. Hunks
+// from such diffs do not match the index byte for byte.
+func (o DiffOptions) IgnoresWhitespace() bool {
+	return o.IgnoreAllSpace || o.IgnoreSpaceChange || o.IgnoreBlankLines
+}
+
+// Args returns the git diff flags for the options
+func (o DiffOptions) Args() []string {
+	algorithm := o.Algorithm
+	if algorithm == "" {
+		algorithm = "histogram"
+	}
+	args := []string{"--diff-algorithm=" + algorithm, fmt.Sprintf("-U%d", max(o.Context, 0))}
+	if o.IgnoreAllSpace {
+		args = append(args, "-w")
+	}
+	if o.IgnoreSpaceChange {
+		args = append(args, "-b")
+	}
+	if o.IgnoreBlankLines {
+		args = append(args, "--ignore-blank-lines")
+	}
+	return args
+}
+
+// String summarizes the options, e.g. "patience -U5 -w"
+func (o DiffOptions) String() string {
+	args := o.Args()
+	args[0] = strings.TrimPrefix(args[0], "--diff-algorithm=")
+	return strings.Join(args, " ")
+}
+
 // GetFileDiff returns the diff for a specific file
-func GetFileDiff(ctx context.Context, path string, staged bool) ([]diff.FileDiff, error) {
-	args := []string{"diff", "--histogram", "--no-color"}
+func GetFileDiff(ctx context.Context, path string, staged bool, opts DiffOptions) ([]diff.FileDiff, error) {
+	args := append([]string{"diff", "--no-color"}, opts.Args()...)
 	if staged {
 		args = append(args, "--cached")
 	}
@@ -22,9 +78,32 @@ func GetFileDiff(ctx context.Context, path string, staged bool) ([]diff.FileDiff
 	return diff.Parse(out), nil
 }
 
+// wholeFile is a -U context large enough to hold any file in one hunk
+const wholeFile = 1 << 30
+
+// GetThreeWayDiff returns a file's changes from HEAD to the working tree
+// with each attributed to the index or the worktree. Both diffs are taken
+// with the whole file as context so the merge can line them up; the result
+// has opts.Context lines of context.
+func GetThreeWayDiff(ctx context.Context, path string, opts DiffOptions) (diff.ThreeWay, error) {
+	full := opts
+	full.Context = wholeFile
+	var sides [2]*diff.Hunk
+	for i, staged := range []bool{true, false} {
+		diffs, err := GetFileDiff(ctx, path, staged, full)
+		if err != nil {
+			return diff.ThreeWay{}, err
+		}
+		if len(diffs) > 0 && len(diffs[0].Hunks) > 0 {
+			sides[i] = &diffs[0].Hunks[0]
+		}
+	}
+	return diff.MergeThreeWay(path, sides[0], sides[1], opts.Context), nil
+}
+
 // GetAllDiffs returns diffs for all changed files
-func GetAllDiffs(ctx context.Context, staged bool) ([]diff.FileDiff, error) {
-	args := []string{"diff", "--histogram", "--no-color"}
+func GetAllDiffs(ctx context.Context, staged bool, opts DiffOptions) ([]diff.FileDiff, error) {
+	args := append([]string{"diff", "--no-color"}, opts.Args()...)
 	if staged {
 		args = append(args, "--cached")
 	}
@@ -37,9 +116,59 @@ func GetAllDiffs(ctx context.Context, staged bool) ([]diff.FileDiff, error) {
 	return diff.Parse(out), nil
 }
 
-// GetDiffStats returns quick stats for all changed files
+// GetRevisionDiff returns the changes of a commit, or between two commits
+// for a range such as A..B or A...B, optionally limited to paths
+func GetRevisionDiff(ctx context.Context, rev string, opts DiffOptions, paths ...string) ([]diff.FileDiff, error) {
+	var args []string
+	if strings.Contains(rev, "..") {
+		args = append([]string{"diff", "--no-color"}, opts.Args()...)
+	} else {
+		args = append([]string{"show", "--no-color", "--format="}, opts.Args()...)
+	}
+	args = append(args, rev, "--")
+	args = append(args, paths...)
+
+	out, err := RunGitCommand(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return diff.Parse(out), nil
+}
+
+// GetFileLines returns the new side of a file's diff split into lines: the
+// index version for staged diffs, otherwise the working tree copy
+func GetFileLines(ctx context.Context, path string, staged bool) ([]string, error) {
+	var content string
+	if staged {
+		out, err := RunGitCommand(ctx, "show", ":"+path)
+		if err != nil {
+			return nil, err
+		}
+		content = out
+	} else {
+		root, err := GetRepoRoot(ctx)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filepath.Join(root, path))
+		if err != nil {
+			return nil, err
+		}
+		content = string(data)
+	}
+
+	content = strings.TrimSuffix(content, "\n")
+	if content == "" {
+		return []string{}, nil
+	}
+	return strings.Split(content, "\n"), nil
+}
+
+// GetDiffStats returns quick stats for all changed files. Renamed files
+// are keyed by their new path.
 func GetDiffStats(ctx context.Context, staged bool) (map[string][2]int, error) {
-	args := []string{"diff", "--numstat"}
+	args := []string{"diff", "--numstat", "-z"}
 	if staged {
 		args = append(args, "--cached")
 	}
@@ -54,21 +183,23 @@ func GetDiffStats(ctx context.Context, staged bool) (map[string][2]int, error) {
 
 func parseNumstat(output string) map[string][2]int {
 	stats := make(map[string][2]int)
-	// Format: added\tremoved\tpath
+	// Format: added\tremoved\tpath\0
+	// Renames and copies leave the path empty and follow with old\0new\0
 	// Binary files show as -\t-\tpath
-	lines := splitLines(output)
-
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
+	records := strings.Split(output, "\x00")
 
+	for i := 0; i < len(records); i++ {
 		var added, removed int
 		var path string
 
-		// Try to parse numeric values
-		n, _ := scanNumstat(line, &added, &removed, &path)
-		if n >= 3 && path != "" {
+		if n, _ := scanNumstat(records[i], &added, &removed, &path); n < 3 {
+			continue
+		}
+		if path == "" && i+2 < len(records) {
+			path = records[i+2]
+			i += 2
+		}
+		if path != "" {
 			stats[path] = [2]int{added, removed}
 		}
 	}
@@ -77,8 +208,9 @@ func parseNumstat(output string) map[string][2]int {
 }
 
 func scanNumstat(line string, added, removed *int, path *string) (int, error) {
-	// Manual parsing since fmt.Sscanf doesn't handle tabs well
-	fields := splitTabs(line)
+	// Manual parsing since fmt.Sscanf doesn't handle tabs well; the path
+	// may itself hold tabs
+	fields := strings.SplitN(line, "\t", 3)
 	if len(fields) < 3 {
 		return 0, nil
 	}
@@ -109,32 +241,64 @@ func scanNumstat(line string, added, removed *int, path *string) (int, error) {
 	return 3, nil
 }
 
-func splitLines(s string) []string {
-	var lines []string
-	start := 0
-	for i := 0; i < len(s); i++ {
-		if s[i] == '\n' {
-			lines = append(lines, s[start:i])
-			start = i + 1
+// NoIndexDiff returns the raw git diff --no-index output for two files or
+// directories, which need not be in a repository
+func NoIndexDiff(ctx context.Context, a, b string, opts DiffOptions) (string, error) {
+	args := append([]string{"diff", "--no-index", "--no-color"}, opts.Args()...)
+	args = append(args, "--", a, b)
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// Exit status 1 only means the inputs differ
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+			return "", &GitError{Command: strings.Join(args, " "), Stderr: stderr.String(), Err: err}
 		}
 	}
-	if start < len(s) {
-		lines = append(lines, s[start:])
+	return stdout.String(), nil
+}
+
+// CompareNoIndex parses NoIndexDiff output. Directory diffs get paths
+// relative to the directories compared, so a file changed in both is not
+// taken for a rename.
+func CompareNoIndex(ctx context.Context, a, b string, opts DiffOptions) ([]diff.FileDiff, error) {
+	out, err := NoIndexDiff(ctx, a, b, opts)
+	if err != nil {
+		return nil, err
 	}
-	return lines
+
+	diffs := diff.Parse(out)
+	if !isDir(a) || !isDir(b) {
+		return diffs, nil
+	}
+	prefixes := []string{noIndexPrefix(a), noIndexPrefix(b)}
+	for i := range diffs {
+		diffs[i].OldPath = trimPrefixes(diffs[i].OldPath, prefixes)
+		diffs[i].NewPath = trimPrefixes(diffs[i].NewPath, prefixes)
+	}
+	return diffs, nil
+}
+
+// noIndexPrefix returns the form git diff --no-index gives a directory at
+// the start of the paths under it
+func noIndexPrefix(dir string) string {
+	return strings.TrimLeft(filepath.ToSlash(filepath.Clean(dir)), "/") + "/"
 }
 
-func splitTabs(s string) []string {
-	var parts []string
-	start := 0
-	for i := 0; i < len(s); i++ {
-		if s[i] == '\t' {
-			parts = append(parts, s[start:i])
-			start = i + 1
+func trimPrefixes(path string, prefixes []string) string {
+	for _, p := range prefixes {
+		if strings.HasPrefix(path, p) {
+			return strings.TrimPrefix(path, p)
 		}
 	}
-	if start < len(s) {
-		parts = append(parts, s[start:])
-	}
-	return parts
+	return path
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
 }
@@ -0,0 +1,118 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Danny-Dasilva/gdiff/internal/testutil"
+)
+
+func TestDiffOptionsArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts DiffOptions
+		want string
+	}{
+		{"defaults", DefaultDiffOptions(), "--diff-algorithm=histogram -U3"},
+		{"zero value", DiffOptions{}, "--diff-algorithm=histogram -U0"},
+		{"whitespace", DiffOptions{Algorithm: "patience", Context: 5, IgnoreAllSpace: true, IgnoreBlankLines: true},
+			"--diff-algorithm=patience -U5 -w --ignore-blank-lines"},
+		{"space change", DiffOptions{Algorithm: "minimal", Context: 1, IgnoreSpaceChange: true},
+			"--diff-algorithm=minimal -U1 -b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(tt.opts.Args(), " "); got != tt.want {
+				t.Errorf("Args() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffOptionsWhitespace(t *testing.T) {
+	if DefaultDiffOptions().IgnoresWhitespace() {
+		t.Error("defaults should not ignore whitespace")
+	}
+	for _, opts := range []DiffOptions{{IgnoreAllSpace: true}, {IgnoreSpaceChange: true}, {IgnoreBlankLines: true}} {
+		if !opts.IgnoresWhitespace() {
+			t.Errorf("%+v should ignore whitespace", opts)
+		}
+	}
+	if got := (DiffOptions{Algorithm: "myers", Context: 0, IgnoreAllSpace: true}).String(); got != "myers -U0 -w" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestCompareNoIndexDirectories(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	for path, content := range map[string]string{
+		"a/same": "x\n", "b/same": "x\n",
+		"a/sub/changed": "one\ntwo\n", "b/sub/changed": "one\n2\n",
+		"a/gone": "bye\n", "b/added": "hi\n",
+	} {
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diffs, err := CompareNoIndex(context.Background(), a, b, DefaultDiffOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, fd := range diffs {
+		got[fd.NewPath] = fd.OldPath
+	}
+	want := map[string]string{"added": "added", "gone": "gone", "sub/changed": "sub/changed"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+}
+
+func TestParseNumstatRenames(t *testing.T) {
+	out := "3\t1\tplain.go\x00" +
+		"0\t0\t\x00old.go\x00new.go\x00" +
+		"2\t2\t\x00dir/a.go\x00other/a.go\x00" +
+		"-\t-\timage.png\x00"
+	want := map[string][2]int{
+		"plain.go":   {3, 1},
+		"new.go":     {0, 0},
+		"other/a.go": {2, 2},
+		"image.png":  {0, 0},
+	}
+	if got := parseNumstat(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNumstat() = %v, want %v", got, want)
+	}
+}
+
+func TestGetDiffStatsKeysRenamesByNewPath(t *testing.T) {
+	testutil.InitRepo(t, map[string]string{"old.txt": "a\nb\nc\nd\ne\n"})
+	ctx := context.Background()
+	if _, err := RunGitCommand(ctx, "mv", "old.txt", "new.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("new.txt", []byte("a\nb\nc\nd\nE\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunGitCommand(ctx, "add", "new.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := GetDiffStats(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string][2]int{"new.txt": {1, 1}}; !reflect.DeepEqual(stats, want) {
+		t.Errorf("GetDiffStats() = %v, want %v", stats, want)
+	}
+}
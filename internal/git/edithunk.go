@@ -0,0 +1,140 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+)
+
+// ErrEmptyEdit is returned for an edited hunk with no added or removed
+// lines left; there is nothing to stage
+var ErrEmptyEdit = errors.New("the edited hunk has no changes left")
+
+// editGuide is appended to a hunk opened for editing, like git add -e
+const editGuide = `# ---
+# To remove '-' lines, make them ' ' lines (context).
+# To remove '+' lines, delete them.
+# Lines starting with # will be removed.
+#
+# The hunk header is recounted when you save, so line counts need no
+# fixing. If the patch applies cleanly, the edited hunk is staged.
+# Removing every change leaves the index alone.
+`
+
+var editedHunkRe = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@(.*)$`)
+
+// CreateTempHunkFile writes a hunk as a patch for editing, followed by a
+// short guide in comment lines. Returns the path to the temp file.
+func CreateTempHunkFile(filePath string, hunk diff.Hunk) (string, error) {
+	tmpfile, err := os.CreateTemp("", "gdiff-hunk-*.diff")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tmpfile.WriteString(buildHunkPatch(filePath, hunk) + editGuide); err != nil {
+		tmpfile.Close()
+		os.Remove(tmpfile.Name())
+		return "", err
+	}
+
+	if err := tmpfile.Close(); err != nil {
+		os.Remove(tmpfile.Name())
+		return "", err
+	}
+
+	return tmpfile.Name(), nil
+}
+
+// RecountHunk turns an edited hunk patch back into one git apply accepts.
+// Comment lines are dropped and the hunk header is recounted from the
+// lines that are left; its start lines are kept. Every other line must be
+// a context, added or removed line. Returns ErrEmptyEdit when no change is
+// left.
+func RecountHunk(edited string) (string, error) {
+	var header []string
+	var body []string
+	var match []string
+	for i, line := range strings.Split(edited, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if m := editedHunkRe.FindStringSubmatch(line); m != nil {
+			if match != nil {
+				return "", errors.New("only one hunk can be edited at a time")
+			}
+			match = m
+			continue
+		}
+		if match == nil {
+			if line != "" {
+				header = append(header, line)
+			}
+			continue
+		}
+		switch {
+		case line == "", strings.HasPrefix(line, " "), strings.HasPrefix(line, "+"),
+			strings.HasPrefix(line, "-"), strings.HasPrefix(line, `\`):
+			body = append(body, line)
+		default:
+			return "", fmt.Errorf("line %d: %q does not start with ' ', '+' or '-'", i+1, line)
+		}
+	}
+	if match == nil {
+		return "", errors.New("the hunk header (@@ ... @@) is missing")
+	}
+	// Editors may leave blank lines at the end; they are not context
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+	}
+
+	var oldCount, newCount int
+	changed := false
+	for i, line := range body {
+		switch {
+		case line == "":
+			body[i] = " "
+			oldCount++
+			newCount++
+		case line[0] == ' ':
+			oldCount++
+			newCount++
+		case line[0] == '-':
+			oldCount++
+			changed = true
+		case line[0] == '+':
+			newCount++
+			changed = true
+		}
+	}
+	if !changed {
+		return "", ErrEmptyEdit
+	}
+
+	oldStart, _ := strconv.Atoi(match[1])
+	newStart, _ := strconv.Atoi(match[2])
+	var b strings.Builder
+	for _, line := range header {
+		b.WriteString(line + "\n")
+	}
+	b.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@%s\n", oldStart, oldCount, newStart, newCount, match[3]))
+	for _, line := range body {
+		b.WriteString(line + "\n")
+	}
+	return b.String(), nil
+}
+
+// StageEditedHunk recounts an edited hunk patch and applies it to the
+// index. A patch that does not apply leaves the index untouched.
+func StageEditedHunk(ctx context.Context, edited string) error {
+	patch, err := RecountHunk(edited)
+	if err != nil {
+		return err
+	}
+	return applyPatch(ctx, patch, true)
+}
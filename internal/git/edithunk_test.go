@@ -0,0 +1,101 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Danny-Dasilva/gdiff/internal/testutil"
+)
+
+func TestRecountHunk(t *testing.T) {
+	const head = "diff --git a/f.txt b/f.txt\n--- a/f.txt\n+++ b/f.txt\n"
+	tests := []struct {
+		name    string
+		edited  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "deleted addition and guide",
+			edited: head + "@@ -1,3 +1,5 @@ func\n a\n b\n+x\n c\n" + editGuide,
+			want:   head + "@@ -1,3 +1,4 @@ func\n a\n b\n+x\n c\n",
+		},
+		{
+			name:   "removal turned into context",
+			edited: head + "@@ -1,2 +1,2 @@\n a\n b\n+c\n",
+			want:   head + "@@ -1,2 +1,3 @@\n a\n b\n+c\n",
+		},
+		{
+			name:    "stray line",
+			edited:  head + "@@ -1,1 +1,1 @@\n-a\n+b\noops\n",
+			wantErr: `line 7: "oops"`,
+		},
+		{
+			name:    "no changes left",
+			edited:  head + "@@ -1,2 +1,2 @@\n a\n b\n\n\n",
+			wantErr: ErrEmptyEdit.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RecountHunk(tt.edited)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("RecountHunk() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStageEditedHunk(t *testing.T) {
+	testutil.InitRepo(t, map[string]string{"f.txt": "a\nb\nc\n"})
+	if err := os.WriteFile("f.txt", []byte("a\nB\nx\nc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	fds, err := GetFileDiff(ctx, "f.txt", false, DefaultDiffOptions())
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("GetFileDiff: %v, %d files", err, len(fds))
+	}
+
+	path, err := CreateTempHunkFile("f.txt", fds[0].Hunks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stage B but not x
+	edited := strings.Replace(string(data), "+x\n", "", 1)
+	if err := StageEditedHunk(ctx, edited); err != nil {
+		t.Fatal(err)
+	}
+	index, err := GetFileLines(ctx, "f.txt", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(index, ","); got != "a,B,c" {
+		t.Errorf("index = %s, want a,B,c", got)
+	}
+
+	// Context that is not in the index does not apply
+	bad := strings.Replace(string(data), " a\n", " z\n", 1)
+	var gitErr *GitError
+	if err := StageEditedHunk(ctx, bad); !errors.As(err, &gitErr) {
+		t.Errorf("err = %v, want a git apply error", err)
+	}
+}
@@ -0,0 +1,139 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+)
+
+// FilePatch builds a patch applying some hunks of a file diff, such as one
+// read from a patch file or taken from another commit. New and deleted
+// files get /dev/null headers so git apply creates or removes them.
+func FilePatch(fd diff.FileDiff, hunks []diff.Hunk) string {
+	var b strings.Builder
+	b.WriteString(fileHeader(fd))
+	for _, h := range hunks {
+		writeHunk(&b, h)
+	}
+	return b.String()
+}
+
+// ForeignLinesPatch builds a patch applying some lines of a hunk from a
+// file diff that is not the working tree's own, such as another commit's.
+// Unselected removals stay as context, as when staging lines.
+func ForeignLinesPatch(fd diff.FileDiff, hunk diff.Hunk, lineIndices []int) string {
+	return withFileHeader(fd, buildPatch(fd.NewPath, hunk, lineIndices, false))
+}
+
+// ForeignCharacterPatch builds a patch applying columns charStart to
+// charEnd of one added line of a hunk from another diff
+func ForeignCharacterPatch(fd diff.FileDiff, hunk diff.Hunk, lineIndex, charStart, charEnd int) string {
+	return withFileHeader(fd, BuildCharacterPatch(fd.NewPath, hunk, lineIndex, charStart, charEnd))
+}
+
+// fileHeader returns the diff --git, --- and +++ lines for a file diff.
+// git apply needs a mode line to create or delete a file; the diff does
+// not record modes, so regular files are assumed.
+func fileHeader(fd diff.FileDiff) string {
+	oldPath, newPath, mode := "a/"+fd.NewPath, "b/"+fd.NewPath, ""
+	switch {
+	case isNewFile(fd):
+		oldPath, mode = "/dev/null", "new file mode 100644\n"
+	case isDeletedFile(fd):
+		newPath, mode = "/dev/null", "deleted file mode 100644\n"
+	}
+	return fmt.Sprintf("diff --git a/%s b/%s\n%s--- %s\n+++ %s\n", fd.NewPath, fd.NewPath, mode, oldPath, newPath)
+}
+
+// withFileHeader gives a single-file patch taken from a file fd creates
+// the /dev/null header that lets git apply create it. Part of a deletion
+// applies as an edit of the file, so its a/ b/ header stays.
+func withFileHeader(fd diff.FileDiff, patch string) string {
+	i := strings.Index(patch, "\n@@")
+	if !isNewFile(fd) || i < 0 {
+		return patch
+	}
+	return fileHeader(fd) + patch[i+1:]
+}
+
+// writeHunk writes a hunk's header and lines in patch form
+func writeHunk(b *strings.Builder, hunk diff.Hunk) {
+	b.WriteString(hunk.Header + "\n")
+	for _, line := range hunk.Lines {
+		switch line.Type {
+		case diff.LineContext:
+			b.WriteString(" " + line.Content + "\n")
+		case diff.LineRemoved:
+			b.WriteString("-" + line.Content + "\n")
+		case diff.LineAdded:
+			b.WriteString("+" + line.Content + "\n")
+		}
+	}
+}
+
+func isNewFile(fd diff.FileDiff) bool {
+	return len(fd.Hunks) == 1 && fd.Hunks[0].OldStart == 0 && fd.Hunks[0].OldCount == 0
+}
+
+func isDeletedFile(fd diff.FileDiff) bool {
+	return len(fd.Hunks) == 1 && fd.Hunks[0].NewStart == 0 && fd.Hunks[0].NewCount == 0
+}
+
+// ApplyHunks applies hunks of a file diff one at a time to the working tree
+// or, with toIndex, the index, so a hunk whose context does not match fails
+// alone. The returned errors line up with hunks; nil means applied.
+func ApplyHunks(ctx context.Context, fd diff.FileDiff, hunks []diff.Hunk, toIndex bool) []error {
+	errs := make([]error, len(hunks))
+	// From the bottom up, so earlier hunks keep their line numbers
+	for i := len(hunks) - 1; i >= 0; i-- {
+		errs[i] = ApplyPatch(ctx, FilePatch(fd, hunks[i:i+1]), toIndex)
+	}
+	return errs
+}
+
+// ApplyPatch applies patch text to the working tree or, with toIndex, the
+// index. A patch whose context does not match fails with a *GitError
+// holding git's reason.
+func ApplyPatch(ctx context.Context, patch string, toIndex bool) error {
+	return applyPatch(ctx, patch, toIndex)
+}
+
+// ResolveCommit reports whether rev names a commit
+func ResolveCommit(ctx context.Context, rev string) bool {
+	_, err := RunGitCommand(ctx, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	return err == nil
+}
+
+// ResolveRange reports whether rev is a range A..B or A...B whose ends
+// name commits; an empty end means HEAD, as in git diff
+func ResolveRange(ctx context.Context, rev string) bool {
+	from, to, ok := strings.Cut(rev, "...")
+	if !ok {
+		from, to, ok = strings.Cut(rev, "..")
+	}
+	if !ok {
+		return false
+	}
+	for _, end := range []string{from, to} {
+		if end != "" && !ResolveCommit(ctx, end) {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatPatchHeader returns the mail header git format-patch writes for a
+// commit: From, Date, Subject and the message body, ending with the "---"
+// line the diff follows
+func FormatPatchHeader(ctx context.Context, rev string) (string, error) {
+	out, err := RunGitCommand(ctx, "format-patch", "--stdout", "--no-stat", "--no-signature", "-1", rev)
+	if err != nil {
+		return "", err
+	}
+	if i := strings.Index(out, "\ndiff --git "); i >= 0 {
+		out = out[:i]
+	}
+	return strings.TrimRight(out, "\n") + "\n---\n", nil
+}
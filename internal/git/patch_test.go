@@ -0,0 +1,151 @@
+package git
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Danny-Dasilva/gdiff/internal/testutil"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+)
+
+func TestFilePatchNewFile(t *testing.T) {
+	fd := diff.Parse(`diff --git a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++one
++two
+`)[0]
+	want := "diff --git a/new.txt b/new.txt\nnew file mode 100644\n--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1,2 @@\n+one\n+two\n"
+	if got := FilePatch(fd, fd.Hunks); got != want {
+		t.Errorf("FilePatch() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestForeignLinesPatchCreatesFile(t *testing.T) {
+	testutil.InitRepo(t, map[string]string{"a.txt": "a\n"})
+	fd := diff.Parse(`diff --git a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++one
++two
+`)[0]
+
+	patch := ForeignLinesPatch(fd, fd.Hunks[0], []int{2})
+	want := "diff --git a/new.txt b/new.txt\nnew file mode 100644\n--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1,1 @@\n+two\n"
+	if patch != want {
+		t.Fatalf("ForeignLinesPatch() =\n%s\nwant:\n%s", patch, want)
+	}
+	if err := ApplyPatch(context.Background(), patch, false); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile("new.txt"); err != nil || string(data) != "two\n" {
+		t.Errorf("new.txt = %q, %v", data, err)
+	}
+}
+
+// TestForeignCharacterPatchApplies applies part of a line changed in
+// another commit and checks the rest of the line is left alone
+func TestForeignCharacterPatchApplies(t *testing.T) {
+	testutil.InitRepo(t, map[string]string{"a.txt": "a\nx := compute(a)\nb\n"})
+	fd := diff.Parse(`diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,3 +1,3 @@
+ a
+-x := compute(a)
++y := compute(a, b)
+ b
+`)[0]
+
+	ctx := context.Background()
+	patch := ForeignCharacterPatch(fd, fd.Hunks[0], 3, 14, 17)
+	if err := ApplyPatch(ctx, patch, false); err != nil {
+		t.Fatalf("%v\n%s", err, patch)
+	}
+	if data, err := os.ReadFile("a.txt"); err != nil || string(data) != "a\nx := compute(a, b)\nb\n" {
+		t.Errorf("a.txt = %q, %v", data, err)
+	}
+
+	if err := ApplyPatch(ctx, ForeignCharacterPatch(fd, fd.Hunks[0], 3, 0, 1), true); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := GetFileLines(ctx, "a.txt", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(lines, "\n"); got != "a\ny := compute(a)\nb" {
+		t.Errorf("index = %q", got)
+	}
+}
+
+func TestApplyHunksReportsConflicts(t *testing.T) {
+	testutil.InitRepo(t, map[string]string{"a.txt": "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"})
+
+	// The first hunk expects a line the file does not have
+	fd := diff.Parse(`diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+-one
++ONE
+ 2
+@@ -9,2 +9,2 @@
+ 9
+-10
++TEN
+`)[0]
+
+	errs := ApplyHunks(context.Background(), fd, fd.Hunks, false)
+	if len(errs) != 2 || errs[0] == nil || errs[1] != nil {
+		t.Fatalf("errors = %v, want only the first hunk to fail", errs)
+	}
+	data, err := os.ReadFile("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(data), "9\nTEN\n") {
+		t.Errorf("second hunk not applied:\n%s", data)
+	}
+
+	// Applying to the index leaves the working tree alone
+	fd.Hunks[0].Lines[1].Content = "1"
+	if errs := ApplyHunks(context.Background(), fd, fd.Hunks[:1], true); errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+	staged, err := RunGitCommand(context.Background(), "diff", "--cached", "--name-only")
+	if err != nil || staged != "a.txt\n" {
+		t.Errorf("staged = %q, %v; want a.txt", staged, err)
+	}
+}
+
+func TestFormatPatchHeader(t *testing.T) {
+	testutil.InitRepo(t, map[string]string{"a.txt": "a\n"})
+
+	ctx := context.Background()
+	if !ResolveCommit(ctx, "HEAD") || ResolveCommit(ctx, "no-such-rev") {
+		t.Fatal("ResolveCommit should accept HEAD only")
+	}
+	for rev, want := range map[string]bool{"HEAD..": true, "HEAD...HEAD": true, "HEAD..nope": false, "HEAD": false} {
+		if got := ResolveRange(ctx, rev); got != want {
+			t.Errorf("ResolveRange(%q) = %v, want %v", rev, got, want)
+		}
+	}
+	header, err := FormatPatchHeader(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"From: Test <test@example.com>", "Subject: [PATCH] Initial commit", "With a body."} {
+		if !strings.Contains(header, want) {
+			t.Errorf("header lacks %q:\n%s", want, header)
+		}
+	}
+	if !strings.HasSuffix(header, "With a body.\n---\n") {
+		t.Errorf("header should end with the message and one ---:\n%s", header)
+	}
+}
@@ -41,10 +41,11 @@ func StageLines(ctx context.Context, filePath string, hunk diff.Hunk, lineIndice
 	return applyPatch(ctx, patch, true)
 }
 
-// UnstageLines unstages specific lines from a file
+// UnstageLines unstages specific lines from a file. The hunk must come from
+// the staged diff (git diff --cached).
 func UnstageLines(ctx context.Context, filePath string, hunk diff.Hunk, lineIndices []int) error {
 	patch := buildPatch(filePath, hunk, lineIndices, true)
-	return applyPatch(ctx, patch, false)
+	return applyPatch(ctx, patch, true, "--reverse")
 }
 
 // StageHunk stages an entire hunk
@@ -53,10 +54,48 @@ func StageHunk(ctx context.Context, filePath string, hunk diff.Hunk) error {
 	return applyPatch(ctx, patch, true)
 }
 
-// UnstageHunk unstages an entire hunk
+// UnstageHunk unstages an entire hunk from the staged diff
 func UnstageHunk(ctx context.Context, filePath string, hunk diff.Hunk) error {
 	patch := buildHunkPatch(filePath, hunk)
-	return applyPatch(ctx, patch, false)
+	return applyPatch(ctx, patch, true, "--reverse")
+}
+
+// LinesPatch returns the patch StageLines applies, or with reverse set the
+// patch UnstageLines applies in reverse
+func LinesPatch(filePath string, hunk diff.Hunk, lineIndices []int, reverse bool) string {
+	return buildPatch(filePath, hunk, lineIndices, reverse)
+}
+
+// HunkPatch returns the patch StageHunk and UnstageHunk apply
+func HunkPatch(filePath string, hunk diff.Hunk) string {
+	return buildHunkPatch(filePath, hunk)
+}
+
+// CheckPatch reports whether a patch would apply to the index, in reverse
+// when reverse is set, without applying it
+func CheckPatch(ctx context.Context, patch string, reverse bool) error {
+	if reverse {
+		return applyPatch(ctx, patch, true, "--check", "--reverse")
+	}
+	return applyPatch(ctx, patch, true, "--check")
+}
+
+// PreviewStage applies staging patches to the index copy of a file in
+// memory, leaving the index alone, and returns every line of the result
+// marked kept, removed or added. The patches are the ones StageLines or
+// StageCharacters would apply.
+func PreviewStage(ctx context.Context, filePath string, patches ...string) ([]diff.Line, error) {
+	index, err := GetFileLines(ctx, filePath, true)
+	if err != nil {
+		return nil, err
+	}
+	var hunks []diff.Hunk
+	for _, p := range patches {
+		for _, fd := range diff.Parse(p) {
+			hunks = append(hunks, fd.Hunks...)
+		}
+	}
+	return diff.ApplyLines(index, hunks)
 }
 
 // RevertHunk reverts changes in a hunk
@@ -65,7 +104,10 @@ func RevertHunk(ctx context.Context, filePath string, hunk diff.Hunk) error {
 	return applyPatch(ctx, patch, false)
 }
 
-// buildPatch creates a patch for specific lines
+// buildPatch creates a patch for specific lines. When reverse is set the
+// patch is meant to be applied with --reverse against the index: unselected
+// added lines are already staged and stay as context, while unselected
+// removed lines are absent from the index and are dropped.
 func buildPatch(filePath string, hunk diff.Hunk, lineIndices []int, reverse bool) string {
 	var b strings.Builder
 
@@ -100,7 +142,7 @@ func buildPatch(filePath string, hunk diff.Hunk, lineIndices []int, reverse bool
 			if include {
 				selectedLines = append(selectedLines, line)
 				oldCount++
-			} else {
+			} else if !reverse {
 				// Convert to context line if not selected
 				selectedLines = append(selectedLines, diff.Line{
 					Type:    diff.LineContext,
@@ -115,8 +157,17 @@ func buildPatch(filePath string, hunk diff.Hunk, lineIndices []int, reverse bool
 			if include {
 				selectedLines = append(selectedLines, line)
 				newCount++
+			} else if reverse {
+				selectedLines = append(selectedLines, diff.Line{
+					Type:    diff.LineContext,
+					Content: line.Content,
+					OldNum:  line.NewNum,
+					NewNum:  line.NewNum,
+				})
+				oldCount++
+				newCount++
 			}
-			// Skip non-selected added lines
+			// Skip non-selected added lines when staging
 		}
 	}
 
@@ -135,32 +186,9 @@ func buildPatch(filePath string, hunk diff.Hunk, lineIndices []int, reverse bool
 		}
 	}
 
-	if reverse {
-		// Reverse the patch for unstaging
-		return reversePatchContent(b.String())
-	}
-
 	return b.String()
 }
 
-// reversePatchContent reverses a patch (swap + and -)
-func reversePatchContent(patch string) string {
-	lines := strings.Split(patch, "\n")
-	var result []string
-
-	for _, line := range lines {
-		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
-			result = append(result, "-"+line[1:])
-		} else if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
-			result = append(result, "+"+line[1:])
-		} else {
-			result = append(result, line)
-		}
-	}
-
-	return strings.Join(result, "\n")
-}
-
 // buildHunkPatch creates a patch for an entire hunk
 func buildHunkPatch(filePath string, hunk diff.Hunk) string {
 	var b strings.Builder
@@ -168,21 +196,7 @@ func buildHunkPatch(filePath string, hunk diff.Hunk) string {
 	b.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n", filePath, filePath))
 	b.WriteString(fmt.Sprintf("--- a/%s\n", filePath))
 	b.WriteString(fmt.Sprintf("+++ b/%s\n", filePath))
-	b.WriteString(hunk.Header + "\n")
-
-	for _, line := range hunk.Lines {
-		if line.Type == diff.LineHunkHeader {
-			continue
-		}
-		switch line.Type {
-		case diff.LineContext:
-			b.WriteString(" " + line.Content + "\n")
-		case diff.LineRemoved:
-			b.WriteString("-" + line.Content + "\n")
-		case diff.LineAdded:
-			b.WriteString("+" + line.Content + "\n")
-		}
-	}
+	writeHunk(&b, hunk)
 
 	return b.String()
 }
@@ -217,12 +231,14 @@ func buildReversePatch(filePath string, hunk diff.Hunk) string {
 	return b.String()
 }
 
-// applyPatch applies a patch to the index or working tree
-func applyPatch(ctx context.Context, patch string, toIndex bool) error {
+// applyPatch applies a patch to the index or working tree. Extra flags such
+// as --reverse are passed through to git apply.
+func applyPatch(ctx context.Context, patch string, toIndex bool, extra ...string) error {
 	args := []string{"apply"}
 	if toIndex {
 		args = append(args, "--cached")
 	}
+	args = append(args, extra...)
 	args = append(args, "--unidiff-zero", "-")
 
 	cmd := exec.CommandContext(ctx, "git", args...)
@@ -267,14 +283,18 @@ func SplitLineAtCharBoundary(content string, charStart, charEnd int) (left, mid,
 }
 
 // BuildCharacterPatch creates a patch that stages only specific characters within a line.
-// This works by splitting the changed line at character boundaries to create a partial change.
+// This works by applying part of the change to the line it replaces.
 //
 // For example, if the original line is "hello world" and the new line is "hello there world",
-// and we want to stage only "there ", this function creates a patch that:
+// and we want to stage only "there", this function creates a patch that:
 // 1. Removes "hello world"
-// 2. Adds "hello there" (partial staging of the addition)
+// 2. Adds "hello there world" with only "there" of the new text inserted
 //
-// The remaining " world" stays unstaged for a future commit.
+// Changes outside the selection, here the space after "there", stay
+// unstaged for a future commit. An added line with no removed line to
+// pair with is staged as just the selected characters. Selecting part of
+// a removed line stages the whole replacement. The hunk header points at
+// the line itself, so other changes in the hunk are left alone.
 func BuildCharacterPatch(filePath string, hunk diff.Hunk, lineIndex, charStart, charEnd int) string {
 	// Validate line index
 	if lineIndex < 0 || lineIndex >= len(hunk.Lines) {
@@ -293,102 +313,78 @@ func BuildCharacterPatch(filePath string, hunk diff.Hunk, lineIndex, charStart,
 		return ""
 	}
 
-	var b strings.Builder
-
-	// Write git diff header
-	b.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n", filePath, filePath))
-	b.WriteString(fmt.Sprintf("--- a/%s\n", filePath))
-	b.WriteString(fmt.Sprintf("+++ b/%s\n", filePath))
-
-	// Find the paired line (removed line that corresponds to added, or vice versa)
+	// The removed line an added line replaces, or the reverse
 	var pairedLine *diff.Line
-	var pairedIndex int = -1
-
-	if targetLine.Type == diff.LineAdded {
-		// Look backwards for the removed line
-		for i := lineIndex - 1; i >= 0; i-- {
-			if hunk.Lines[i].Type == diff.LineRemoved {
-				pairedLine = &hunk.Lines[i]
-				pairedIndex = i
-				break
-			}
-			if hunk.Lines[i].Type == diff.LineContext {
-				break // Stop at context lines
-			}
-		}
-	} else { // LineRemoved
-		// Look forwards for the added line
-		for i := lineIndex + 1; i < len(hunk.Lines); i++ {
-			if hunk.Lines[i].Type == diff.LineAdded {
-				pairedLine = &hunk.Lines[i]
-				pairedIndex = i
-				break
-			}
-			if hunk.Lines[i].Type == diff.LineContext {
-				break
-			}
-		}
+	if i := diff.PairedLine(hunk, lineIndex); i >= 0 {
+		pairedLine = &hunk.Lines[i]
 	}
 
-	// Calculate what content to include based on character selection
-	var oldContent, newContent string
-
+	// The old and new sides of the one-line change, absent when nil
+	var oldContent, newContent *string
+	var oldNum int
 	if targetLine.Type == diff.LineAdded {
-		// Staging part of an added line
-		// The old content is the paired removed line (if exists) or empty
+		staged := string([]rune(targetLine.Content)[min(charStart, runeLen(targetLine.Content)):min(charEnd, runeLen(targetLine.Content))])
 		if pairedLine != nil {
-			oldContent = pairedLine.Content
-		}
-		// The new content is the original content up to the selection end
-		_, _, _ = SplitLineAtCharBoundary(targetLine.Content, charStart, charEnd)
-		runes := []rune(targetLine.Content)
-		if charEnd > len(runes) {
-			charEnd = len(runes)
-		}
-		newContent = string(runes[:charEnd])
-
-		// If there's a paired removed line, we need to keep the unchanged suffix
-		if pairedLine != nil && charEnd < len(runes) {
-			// Keep the suffix from the original that wasn't selected
-			newContent = string(runes[:charEnd])
+			oldContent = &pairedLine.Content
+			oldNum = pairedLine.OldNum
+			staged = diff.StageRange(pairedLine.Content, targetLine.Content, charStart, charEnd)
+			if staged == pairedLine.Content {
+				return ""
+			}
+		} else {
+			oldNum = oldLineBefore(hunk, lineIndex)
 		}
+		newContent = &staged
 	} else {
-		// Staging part of a removed line
-		oldContent = targetLine.Content
+		oldContent = &targetLine.Content
+		oldNum = targetLine.OldNum
 		if pairedLine != nil {
-			newContent = pairedLine.Content
+			newContent = &pairedLine.Content
 		}
-		// For removed lines, we stage the whole removed line but partial added
-		_ = pairedIndex // Mark as used
 	}
 
-	// Calculate hunk header values
-	oldStart := hunk.OldStart
-	newStart := hunk.NewStart
-	oldCount := 0
-	newCount := 0
+	var b strings.Builder
 
-	if oldContent != "" {
-		oldCount = 1
-	}
-	if newContent != "" {
-		newCount = 1
-	}
+	// Write git diff header
+	b.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n", filePath, filePath))
+	b.WriteString(fmt.Sprintf("--- a/%s\n", filePath))
+	b.WriteString(fmt.Sprintf("+++ b/%s\n", filePath))
 
-	// Write hunk header
-	b.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount))
+	// An insertion goes after old line oldNum; anything else replaces it
+	switch {
+	case oldContent == nil:
+		b.WriteString(fmt.Sprintf("@@ -%d,0 +%d,1 @@\n", oldNum, oldNum+1))
+	case newContent == nil:
+		b.WriteString(fmt.Sprintf("@@ -%d,1 +%d,0 @@\n", oldNum, oldNum-1))
+	default:
+		b.WriteString(fmt.Sprintf("@@ -%d,1 +%d,1 @@\n", oldNum, oldNum))
+	}
 
 	// Write the patch content
-	if oldContent != "" {
-		b.WriteString("-" + oldContent + "\n")
+	if oldContent != nil {
+		b.WriteString("-" + *oldContent + "\n")
 	}
-	if newContent != "" {
-		b.WriteString("+" + newContent + "\n")
+	if newContent != nil {
+		b.WriteString("+" + *newContent + "\n")
 	}
 
 	return b.String()
 }
 
+// oldLineBefore returns the old-file line after which the line at index i
+// of a hunk is inserted
+func oldLineBefore(hunk diff.Hunk, i int) int {
+	for j := i - 1; j >= 0; j-- {
+		if t := hunk.Lines[j].Type; t == diff.LineContext || t == diff.LineRemoved {
+			return hunk.Lines[j].OldNum
+		}
+	}
+	if hunk.OldCount == 0 {
+		return hunk.OldStart
+	}
+	return hunk.OldStart - 1
+}
+
 // StageCharacters stages specific characters within a line
 func StageCharacters(ctx context.Context, filePath string, hunk diff.Hunk, lineIndex, charStart, charEnd int) error {
 	patch := BuildCharacterPatch(filePath, hunk, lineIndex, charStart, charEnd)
@@ -1,9 +1,13 @@
 package git
 
 import (
+	"context"
+	"os"
+	"slices"
 	"strings"
 	"testing"
 
+	"github.com/Danny-Dasilva/gdiff/internal/testutil"
 	"github.com/Danny-Dasilva/gdiff/pkg/diff"
 )
 
@@ -53,7 +57,7 @@ func TestBuildCharacterPatch(t *testing.T) {
 				},
 			},
 			lineIndex:   1,
-			charStart:   4, // Start of "bar "
+			charStart:   3, // Start of " bar "
 			charEnd:     8,
 			wantAdded:   "foo bar",
 			wantRemoved: "foo",
@@ -241,3 +245,157 @@ func TestSplitLineAtCharBoundary(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildPatchSelection(t *testing.T) {
+	hunk := diff.Hunk{
+		OldStart: 1,
+		OldCount: 3,
+		NewStart: 1,
+		NewCount: 3,
+		Header:   "@@ -1,3 +1,3 @@",
+		Lines: []diff.Line{
+			{Type: diff.LineContext, Content: "ctx", OldNum: 1, NewNum: 1},
+			{Type: diff.LineRemoved, Content: "old a", OldNum: 2},
+			{Type: diff.LineRemoved, Content: "old b", OldNum: 3},
+			{Type: diff.LineAdded, Content: "new a", NewNum: 2},
+			{Type: diff.LineAdded, Content: "new b", NewNum: 3},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		reverse bool
+		want    string
+	}{
+		{
+			name: "stage keeps unselected removals as context",
+			want: "@@ -1,3 +1,3 @@\n ctx\n-old a\n old b\n+new a\n",
+		},
+		{
+			name:    "unstage keeps unselected additions as context",
+			reverse: true,
+			want:    "@@ -1,3 +1,3 @@\n ctx\n-old a\n+new a\n new b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch := buildPatch("test.go", hunk, []int{1, 3}, tt.reverse)
+			body := patch[strings.Index(patch, "@@"):]
+			if body != tt.want {
+				t.Errorf("buildPatch() body =\n%s\nwant:\n%s", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnstageFromStagedDiff(t *testing.T) {
+	testutil.InitRepo(t, map[string]string{"f.txt": "a\nb\nc\n"})
+	if err := os.WriteFile("f.txt", []byte("a\nB\nc\nd\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := RunGitCommand(ctx, "add", "f.txt"); err != nil {
+		t.Fatal(err)
+	}
+	staged := func() string {
+		t.Helper()
+		out, err := RunGitCommand(ctx, "show", ":f.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.ReplaceAll(strings.TrimSpace(out), "\n", ",")
+	}
+	stagedHunks := func() []diff.Hunk {
+		t.Helper()
+		fds, err := GetFileDiff(ctx, "f.txt", true, DefaultDiffOptions())
+		if err != nil || len(fds) != 1 {
+			t.Fatalf("GetFileDiff: %v, %d files", err, len(fds))
+		}
+		return fds[0].Hunks
+	}
+
+	// Unstage only the added d: B stays staged
+	hunks := stagedHunks()
+	var d int
+	for i, line := range hunks[0].Lines {
+		if line.Content == "d" {
+			d = i
+		}
+	}
+	if err := UnstageLines(ctx, "f.txt", hunks[0], []int{d}); err != nil {
+		t.Fatal(err)
+	}
+	if got := staged(); got != "a,B,c" {
+		t.Errorf("after UnstageLines index = %s, want a,B,c", got)
+	}
+
+	// Unstaging the rest restores HEAD
+	if err := UnstageHunk(ctx, "f.txt", stagedHunks()[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got := staged(); got != "a,b,c" {
+		t.Errorf("after UnstageHunk index = %s, want a,b,c", got)
+	}
+}
+
+func TestPreviewStageMatchesStage(t *testing.T) {
+	testutil.InitRepo(t, map[string]string{"f.txt": "a\nhello world\nb\nx := compute(a)\n"})
+	if err := os.WriteFile("f.txt", []byte("a\nhello there world\nb\ny := compute(a, b)\nnew\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		content    string // the added line to stage part of
+		start, end int
+		want       []string // the index afterwards
+	}{
+		{"whole insertion", "hello there world", 6, 12, []string{"a", "hello there world", "b", "x := compute(a)"}},
+		{"part of an insertion", "hello there world", 6, 9, []string{"a", "hello theworld", "b", "x := compute(a)"}},
+		{"one of two changes", "y := compute(a, b)", 14, 17, []string{"a", "hello world", "b", "x := compute(a, b)"}},
+		{"added line", "new", 0, 2, []string{"a", "hello world", "b", "x := compute(a)", "ne"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := RunGitCommand(ctx, "reset", "-q"); err != nil {
+				t.Fatal(err)
+			}
+			fds, err := GetFileDiff(ctx, "f.txt", false, DefaultDiffOptions())
+			if err != nil || len(fds) != 1 {
+				t.Fatalf("GetFileDiff: %v, %d files", err, len(fds))
+			}
+			hunk := fds[0].Hunks[0]
+			added := slices.IndexFunc(hunk.Lines, func(l diff.Line) bool {
+				return l.Type == diff.LineAdded && l.Content == tt.content
+			})
+
+			patch := BuildCharacterPatch("f.txt", hunk, added, tt.start, tt.end)
+			preview, err := PreviewStage(ctx, "f.txt", patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var after []string
+			for _, l := range preview {
+				if l.Type != diff.LineRemoved {
+					after = append(after, l.Content)
+				}
+			}
+
+			if err := StageCharacters(ctx, "f.txt", hunk, added, tt.start, tt.end); err != nil {
+				t.Fatal(err)
+			}
+			index, err := GetFileLines(ctx, "f.txt", true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(index, tt.want) {
+				t.Errorf("index = %q, want %q", index, tt.want)
+			}
+			if !slices.Equal(after, index) {
+				t.Errorf("preview %q, staged index %q", after, index)
+			}
+		})
+	}
+}
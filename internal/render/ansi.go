@@ -0,0 +1,200 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// tabWidth is the number of spaces a tab expands to
+const tabWidth = 4
+
+// gutterWidth is the width of "%4d " plus the marker and a space
+const gutterWidth = 7
+
+// palette holds the styles of the ANSI output, matching the diff view
+type palette struct {
+	header, hunk, context, lineNum, separator lipgloss.Style
+	added, removed                            lipgloss.Style
+	addedHi, removedHi                        lipgloss.Style
+}
+
+func newPalette(colorblind bool) palette {
+	addedFg, addedBg, addedHiBg := "#a6e3a1", "#1a2f1a", "#2d5c3a"
+	removedFg, removedBg, removedHiBg := "#f38ba8", "#2f1a1a", "#5c2d3a"
+	if colorblind {
+		addedFg, addedBg, addedHiBg = "#f5a623", "#2f2a1a", "#5c4a2d"
+		removedFg, removedBg, removedHiBg = "#7ab4ff", "#1a1f2f", "#2d3a5c"
+	}
+	added := lipgloss.NewStyle().Foreground(lipgloss.Color(addedFg)).Background(lipgloss.Color(addedBg))
+	removed := lipgloss.NewStyle().Foreground(lipgloss.Color(removedFg)).Background(lipgloss.Color(removedBg))
+	return palette{
+		header:    lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("39")).Background(lipgloss.Color("236")),
+		hunk:      lipgloss.NewStyle().Foreground(lipgloss.Color("#89b4fa")).Bold(true),
+		context:   lipgloss.NewStyle().Foreground(lipgloss.Color("#a6adc8")),
+		lineNum:   lipgloss.NewStyle().Foreground(lipgloss.Color("#585b70")),
+		separator: lipgloss.NewStyle().Foreground(lipgloss.Color("#585b70")),
+		added:     added,
+		removed:   removed,
+		addedHi:   added.Background(lipgloss.Color(addedHiBg)).Bold(true),
+		removedHi: removed.Background(lipgloss.Color(removedHiBg)).Bold(true),
+	}
+}
+
+// renderANSI writes files side by side, old on the left and new on the
+// right, wrapping lines that do not fit their side
+func renderANSI(w io.Writer, files []File, opts Options) error {
+	width := opts.Width
+	if width <= 0 {
+		width = DefaultWidth
+	}
+	leftWidth := max((width-3)/2, gutterWidth+1)
+	rightWidth := max(width-3-leftWidth, gutterWidth+1)
+	p := newPalette(opts.Colorblind)
+	sep := p.separator.Render(" │ ")
+
+	var b strings.Builder
+	for fi, f := range files {
+		if fi > 0 {
+			b.WriteString("\n")
+		}
+		title := f.NewPath
+		if f.OldPath != f.NewPath {
+			title = f.OldPath + " → " + f.NewPath
+		}
+		b.WriteString(p.header.Render(pad(" "+title, leftWidth+rightWidth+3)) + "\n")
+		if f.Binary {
+			b.WriteString(p.context.Render("Binary file") + "\n")
+			continue
+		}
+
+		for _, h := range f.Hunks {
+			b.WriteString(p.hunk.Render(ansi.Truncate(h.Header, leftWidth+rightWidth+3, "…")) + "\n")
+			for _, row := range h.rows {
+				var left, right []string
+				if row[0] >= 0 {
+					left = renderSide(p, h.Lines[row[0]], false, leftWidth)
+				}
+				if row[1] >= 0 {
+					right = renderSide(p, h.Lines[row[1]], true, rightWidth)
+				}
+				for i := range max(len(left), len(right)) {
+					l, r := strings.Repeat(" ", leftWidth), strings.Repeat(" ", rightWidth)
+					if i < len(left) {
+						l = left[i]
+					}
+					if i < len(right) {
+						r = right[i]
+					}
+					b.WriteString(l + sep + r + "\n")
+				}
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// renderSide renders one line for the old or new side as rows exactly
+// width cells wide, continuation rows getting a blank gutter
+func renderSide(p palette, l Line, newSide bool, width int) []string {
+	style, hi, marker := p.context, p.context, " "
+	switch l.Type {
+	case "added":
+		style, hi, marker = p.added, p.addedHi, "+"
+	case "removed":
+		style, hi, marker = p.removed, p.removedHi, "-"
+	}
+	num := l.OldNum
+	if newSide {
+		num = l.NewNum
+	}
+
+	content, changes := expandTabs(l.Content, l.Changes)
+	chunks := wrapRunes(content, width-gutterWidth)
+	rows := make([]string, len(chunks))
+	offset := 0
+	for i, chunk := range chunks {
+		gutter := strings.Repeat(" ", gutterWidth)
+		if i == 0 {
+			gutter = p.lineNum.Render(fmt.Sprintf("%4d ", num)) + style.Render(marker+" ")
+		} else {
+			gutter = style.Render(gutter)
+		}
+
+		var text strings.Builder
+		used := 0
+		for _, seg := range segments(chunk, shiftRanges(changes, offset)) {
+			used += ansi.StringWidth(seg.text)
+			if seg.changed && l.Type != "context" {
+				text.WriteString(hi.Render(seg.text))
+			} else {
+				text.WriteString(style.Render(seg.text))
+			}
+		}
+		text.WriteString(style.Render(strings.Repeat(" ", max(width-gutterWidth-used, 0))))
+		rows[i] = gutter + text.String()
+		offset += len(chunk)
+	}
+	return rows
+}
+
+// expandTabs replaces tabs with spaces to the next tab stop, moving the
+// change ranges along with the content
+func expandTabs(content string, changes []Range) ([]rune, []Range) {
+	var out []rune
+	pos := make([]int, 0, len(content)+1)
+	for _, r := range content {
+		pos = append(pos, len(out))
+		if r == '\t' {
+			for n := tabWidth - len(out)%tabWidth; n > 0; n-- {
+				out = append(out, ' ')
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	pos = append(pos, len(out))
+
+	moved := make([]Range, 0, len(changes))
+	for _, c := range changes {
+		start, end := min(max(c.Start, 0), len(pos)-1), min(max(c.End, 0), len(pos)-1)
+		moved = append(moved, Range{Start: pos[start], End: pos[end]})
+	}
+	return out, moved
+}
+
+// wrapRunes splits content into chunks at most width cells wide; empty
+// content still yields one chunk
+func wrapRunes(content []rune, width int) [][]rune {
+	width = max(width, 1)
+	var chunks [][]rune
+	start, used := 0, 0
+	for i, r := range content {
+		rw := ansi.StringWidth(string(r))
+		if used+rw > width && i > start {
+			chunks = append(chunks, content[start:i])
+			start, used = i, 0
+		}
+		used += rw
+	}
+	return append(chunks, content[start:])
+}
+
+// shiftRanges moves ranges left by offset, as seen from a wrapped chunk
+func shiftRanges(changes []Range, offset int) []Range {
+	shifted := make([]Range, len(changes))
+	for i, c := range changes {
+		shifted[i] = Range{Start: c.Start - offset, End: c.End - offset}
+	}
+	return shifted
+}
+
+// pad fills s with spaces to width cells, truncating it when longer
+func pad(s string, width int) string {
+	s = ansi.Truncate(s, width, "…")
+	return s + strings.Repeat(" ", max(width-ansi.StringWidth(s), 0))
+}
@@ -0,0 +1,100 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+const htmlHead = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { margin: 0; padding: 1em; background: #1e1e2e; color: #a6adc8; font: 13px/1.4 ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; }
+h1 { font-size: 1.2em; color: #cdd6f4; }
+table { width: 100%%; border-collapse: collapse; table-layout: fixed; margin-bottom: 2em; }
+th.file { text-align: left; padding: 0.4em 0.6em; color: #89b4fa; background: #313244; }
+td { vertical-align: top; padding: 0 0.4em; white-space: pre-wrap; word-break: break-all; tab-size: 4; }
+td.num { width: 4em; text-align: right; color: #585b70; user-select: none; }
+td.hunk { color: #89b4fa; font-weight: bold; padding: 0.3em 0.6em; background: #181825; }
+td.sep { width: 1px; padding: 0; background: #45475a; }
+.added { color: %s; background: %s; }
+.added .hl { background: %s; font-weight: bold; }
+.removed { color: %s; background: %s; }
+.removed .hl { background: %s; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>%s</h1>
+`
+
+// renderHTML writes a self-contained page with a side-by-side table per file
+func renderHTML(w io.Writer, files []File, opts Options) error {
+	title := opts.Title
+	if title == "" {
+		title = "gdiff"
+	}
+	addedFg, addedBg, addedHiBg := "#a6e3a1", "#1a2f1a", "#2d5c3a"
+	removedFg, removedBg, removedHiBg := "#f38ba8", "#2f1a1a", "#5c2d3a"
+	if opts.Colorblind {
+		addedFg, addedBg, addedHiBg = "#f5a623", "#2f2a1a", "#5c4a2d"
+		removedFg, removedBg, removedHiBg = "#7ab4ff", "#1a1f2f", "#2d3a5c"
+	}
+
+	var b strings.Builder
+	t := html.EscapeString(title)
+	fmt.Fprintf(&b, htmlHead, t, addedFg, addedBg, addedHiBg, removedFg, removedBg, removedHiBg, t)
+	for _, f := range files {
+		name := f.NewPath
+		if f.OldPath != f.NewPath {
+			name = f.OldPath + " → " + f.NewPath
+		}
+		b.WriteString("<table>\n")
+		fmt.Fprintf(&b, "<tr><th class=\"file\" colspan=\"5\">%s</th></tr>\n", html.EscapeString(name))
+		if f.Binary {
+			b.WriteString("<tr><td colspan=\"5\">Binary file</td></tr>\n</table>\n")
+			continue
+		}
+		for _, h := range f.Hunks {
+			fmt.Fprintf(&b, "<tr><td class=\"hunk\" colspan=\"5\">%s</td></tr>\n", html.EscapeString(h.Header))
+			for _, row := range h.rows {
+				b.WriteString("<tr>")
+				writeHTMLSide(&b, h.Lines, row[0], false)
+				b.WriteString("<td class=\"sep\"></td>")
+				writeHTMLSide(&b, h.Lines, row[1], true)
+				b.WriteString("</tr>\n")
+			}
+		}
+		b.WriteString("</table>\n")
+	}
+	b.WriteString("</body>\n</html>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeHTMLSide writes the number and content cells of one side of a row,
+// leaving both empty when the side has no line
+func writeHTMLSide(b *strings.Builder, lines []Line, i int, newSide bool) {
+	if i < 0 {
+		b.WriteString("<td class=\"num\"></td><td></td>")
+		return
+	}
+	l := lines[i]
+	num := l.OldNum
+	if newSide {
+		num = l.NewNum
+	}
+	fmt.Fprintf(b, "<td class=\"num\">%d</td><td class=\"%s\">", num, l.Type)
+	for _, seg := range segments([]rune(l.Content), l.Changes) {
+		if seg.changed && l.Type != "context" {
+			b.WriteString("<span class=\"hl\">" + html.EscapeString(seg.text) + "</span>")
+		} else {
+			b.WriteString(html.EscapeString(seg.text))
+		}
+	}
+	b.WriteString("</td>")
+}
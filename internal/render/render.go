@@ -0,0 +1,157 @@
+// Package render writes diffs without the TUI: side-by-side ANSI text for
+// terminals and CI logs, a self-contained HTML page, and JSON. Lines are
+// paired and character-highlighted with the same pkg/diff logic as the diff
+// view.
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+	"github.com/Danny-Dasilva/gdiff/pkg/syntax"
+)
+
+// Formats lists the supported output formats
+var Formats = []string{"ansi", "html", "json"}
+
+// Options controls rendering
+type Options struct {
+	Width      int    // Total width of ANSI output
+	Colorblind bool   // Blue/orange instead of green/red
+	Title      string // Title of the HTML page
+}
+
+// DefaultWidth is the ANSI width used when none is given
+const DefaultWidth = 160
+
+// File is the rendered form of one file's diff
+type File struct {
+	OldPath string `json:"old_path"`
+	NewPath string `json:"new_path"`
+	Binary  bool   `json:"binary,omitempty"`
+	Hunks   []Hunk `json:"hunks"`
+}
+
+// Hunk is a hunk without its header line, which is kept in Header
+type Hunk struct {
+	Header   string `json:"header"`
+	OldStart int    `json:"old_start"`
+	OldCount int    `json:"old_count"`
+	NewStart int    `json:"new_start"`
+	NewCount int    `json:"new_count"`
+	Lines    []Line `json:"lines"`
+
+	// rows is the side-by-side layout of Lines as (old, new) indices
+	rows [][2]int
+}
+
+// Line is a diff line with its character-level changes
+type Line struct {
+	Type    string  `json:"type"` // "context", "added" or "removed"
+	OldNum  int     `json:"old_num,omitempty"`
+	NewNum  int     `json:"new_num,omitempty"`
+	Content string  `json:"content"`
+	Changes []Range `json:"changes,omitempty"`
+}
+
+// Range is a changed span of a line's content in rune offsets
+type Range struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+var lineTypes = map[diff.LineType]string{
+	diff.LineContext: "context",
+	diff.LineAdded:   "added",
+	diff.LineRemoved: "removed",
+}
+
+// Build pairs and highlights the lines of every file
+func Build(diffs []diff.FileDiff) []File {
+	files := make([]File, 0, len(diffs))
+	for _, fd := range diffs {
+		tokenizer := diff.DefaultTokenizer
+		if lang := syntax.Detect(fd.NewPath); lang != nil {
+			tokenizer = diff.TokenizerFor(lang.Name)
+		}
+
+		f := File{OldPath: fd.OldPath, NewPath: fd.NewPath, Binary: fd.IsBinary, Hunks: []Hunk{}}
+		for _, h := range fd.Hunks {
+			body := h.Lines
+			if len(body) > 0 && body[0].Type == diff.LineHunkHeader {
+				body = body[1:]
+			}
+			hunk := Hunk{
+				Header:   h.Header,
+				OldStart: h.OldStart,
+				OldCount: h.OldCount,
+				NewStart: h.NewStart,
+				NewCount: h.NewCount,
+				Lines:    make([]Line, len(body)),
+				rows:     diff.SideBySide(body, diff.PairAdjacentLines(body)),
+			}
+			for i, hl := range diff.ComputeHighlightedDiffWith(diff.Hunk{Lines: body}, tokenizer) {
+				line := Line{
+					Type:    lineTypes[hl.Line.Type],
+					OldNum:  hl.Line.OldNum,
+					NewNum:  hl.Line.NewNum,
+					Content: hl.Line.Content,
+				}
+				for _, c := range hl.Changes {
+					line.Changes = append(line.Changes, Range{Start: c.Start, End: c.End})
+				}
+				hunk.Lines[i] = line
+			}
+			f.Hunks = append(f.Hunks, hunk)
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// Render writes diffs to w in one of Formats
+func Render(w io.Writer, format string, diffs []diff.FileDiff, opts Options) error {
+	files := Build(diffs)
+	switch format {
+	case "ansi":
+		return renderANSI(w, files, opts)
+	case "html":
+		return renderHTML(w, files, opts)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(struct {
+			Files []File `json:"files"`
+		}{files})
+	}
+	return fmt.Errorf("unknown format %q (want ansi, html or json)", format)
+}
+
+// segment is a run of a line's content that is either changed or not
+type segment struct {
+	text    string
+	changed bool
+}
+
+// segments splits content at the boundaries of its changed ranges
+func segments(content []rune, changes []Range) []segment {
+	changed := make([]bool, len(content))
+	for _, c := range changes {
+		for i := max(c.Start, 0); i < min(c.End, len(content)); i++ {
+			changed[i] = true
+		}
+	}
+	var segs []segment
+	for start := 0; start < len(content); {
+		end := start + 1
+		for end < len(content) && changed[end] == changed[start] {
+			end++
+		}
+		segs = append(segs, segment{text: string(content[start:end]), changed: changed[start]})
+		start = end
+	}
+	return segs
+}
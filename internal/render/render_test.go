@@ -0,0 +1,116 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const sample = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ package main
+-func old() <b>
++func new() <b>
++	extra()
+ }
+`
+
+func TestBuildPairsAndHighlights(t *testing.T) {
+	files := Build(diff.Parse(sample))
+	if len(files) != 1 || len(files[0].Hunks) != 1 {
+		t.Fatalf("got %d files, want one file with one hunk", len(files))
+	}
+	h := files[0].Hunks[0]
+	if len(h.Lines) != 5 || h.Lines[0].Type != "context" {
+		t.Fatalf("lines = %+v, want 5 lines without the header", h.Lines)
+	}
+
+	removed, added := h.Lines[1], h.Lines[2]
+	if len(removed.Changes) != 1 || removed.Content[removed.Changes[0].Start:removed.Changes[0].End] != "old" {
+		t.Errorf("removed changes = %v, want the word old", removed.Changes)
+	}
+	if len(added.Changes) != 1 || added.Content[added.Changes[0].Start:added.Changes[0].End] != "new" {
+		t.Errorf("added changes = %v, want the word new", added.Changes)
+	}
+
+	want := [][2]int{{0, 0}, {1, 2}, {-1, 3}, {4, 4}}
+	if len(h.rows) != len(want) {
+		t.Fatalf("rows = %v, want %v", h.rows, want)
+	}
+	for i := range want {
+		if h.rows[i] != want[i] {
+			t.Fatalf("rows = %v, want %v", h.rows, want)
+		}
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, "json", diff.Parse(sample), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Files []File `json:"files"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(out.Files) != 1 || out.Files[0].NewPath != "main.go" || len(out.Files[0].Hunks[0].Lines) != 5 {
+		t.Errorf("unexpected JSON: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"changes"`) {
+		t.Error("JSON is missing the char change ranges")
+	}
+}
+
+func TestRenderANSIWidth(t *testing.T) {
+	long := strings.Replace(sample, "+\textra()", "+\textra("+strings.Repeat("x", 100)+")", 1)
+	var buf bytes.Buffer
+	if err := Render(&buf, "ansi", diff.Parse(long), Options{Width: 80}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(ansi.Strip(buf.String()), "\n"), "\n")
+	wrapped := false
+	for _, l := range lines[2:] {
+		if w := ansi.StringWidth(l); w != 80 {
+			t.Errorf("row is %d cells wide, want 80: %q", w, l)
+		}
+		wrapped = wrapped || strings.Contains(l, "xxx)")
+	}
+	if !wrapped {
+		t.Error("long line was not wrapped onto a continuation row")
+	}
+	if !strings.Contains(lines[3], "-") || !strings.Contains(lines[3], "│") || !strings.Contains(lines[3], "+") {
+		t.Errorf("changed pair should share a row: %q", lines[3])
+	}
+}
+
+func TestRenderHTMLEscapes(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, "html", diff.Parse(sample), Options{Title: "a & b"}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"<title>a &amp; b</title>", "&lt;b&gt;", `<span class="hl">new</span>`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML is missing %q", want)
+		}
+	}
+	if strings.Contains(out, "<b>") {
+		t.Error("diff content was not escaped")
+	}
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	if err := Render(&bytes.Buffer{}, "pdf", nil, Options{}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
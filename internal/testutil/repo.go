@@ -0,0 +1,45 @@
+// Package testutil holds helpers shared by tests that need a real git
+// repository.
+package testutil
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// InitRepo creates a repository in a fresh temporary directory, makes it
+// the working directory for the rest of the test and commits files, keyed
+// by path, as "Initial commit". The repository's identity is
+// Test <test@example.com>, so later commits need no -c flags.
+func InitRepo(t testing.TB, files map[string]string) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	for path, content := range files {
+		if dir := filepath.Dir(path); dir != "." {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	Git(t, "init", "-q")
+	Git(t, "config", "user.name", "Test")
+	Git(t, "config", "user.email", "test@example.com")
+	Git(t, "add", ".")
+	Git(t, "commit", "-q", "-m", "Initial commit", "-m", "With a body.")
+}
+
+// Git runs git in the working directory and returns its combined output,
+// failing the test if it exits non-zero
+func Git(t testing.TB, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
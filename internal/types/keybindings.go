@@ -15,10 +15,12 @@ type KeyMap struct {
 	FullPageUp   key.Binding
 	FullPageDown key.Binding
 
-	NextHunk   key.Binding
-	PrevHunk   key.Binding
-	NextChange key.Binding
-	PrevChange key.Binding
+	NextHunk       key.Binding
+	PrevHunk       key.Binding
+	NextChange     key.Binding
+	PrevChange     key.Binding
+	JumpMove       key.Binding
+	ScrollSideways key.Binding
 
 	// Pane switching
 	SwitchPane    key.Binding
@@ -30,17 +32,41 @@ type KeyMap struct {
 	SelectHunk key.Binding
 
 	// Staging
-	StageFile   key.Binding
-	UnstageFile key.Binding
-	StageItem   key.Binding
-	UnstageItem key.Binding
-	StageHunk   key.Binding
-	UnstageHunk key.Binding
-	SpaceToggle key.Binding
-	RevertItem  key.Binding
+	StageFile    key.Binding
+	UnstageFile  key.Binding
+	StageItem    key.Binding
+	UnstageItem  key.Binding
+	StageHunk    key.Binding
+	UnstageHunk  key.Binding
+	SpaceToggle  key.Binding
+	RevertItem   key.Binding
+	ToggleMark   key.Binding
+	MarkHunk     key.Binding
+	Stash        key.Binding
+	PreviewStage key.Binding
+	EditHunk     key.Binding
 
 	// View toggle
 	ToggleStagedView key.Binding
+	ToggleDiffStat   key.Binding
+	ToggleAllFiles   key.Binding
+	ToggleLayout     key.Binding
+	ToggleWrap       key.Binding
+	ToggleWordDiff   key.Binding
+	DiffOptions      key.Binding
+
+	// Context expansion
+	ExpandAbove    key.Binding
+	ExpandBelow    key.Binding
+	ExpandGap      key.Binding
+	ToggleFullFile key.Binding
+	ToggleThreeWay key.Binding
+
+	// Patches
+	ExportPatch     key.Binding
+	OpenPatch       key.Binding
+	ApplyPatch      key.Binding
+	ApplyPatchIndex key.Binding
 
 	// Commit/Push
 	Commit      key.Binding
@@ -122,6 +148,14 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("["),
 			key.WithHelp("[c", "prev change"),
 		),
+		JumpMove: key.NewBinding(
+			key.WithKeys("%"),
+			key.WithHelp("%", "other end of move"),
+		),
+		ScrollSideways: key.NewBinding(
+			key.WithKeys("z"),
+			key.WithHelp("zh/zl", "scroll left/right"),
+		),
 
 		// Pane switching
 		SwitchPane: key.NewBinding(
@@ -172,6 +206,26 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("d"),
 			key.WithHelp("d", "discard (confirm)"),
 		),
+		ToggleMark: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "mark file"),
+		),
+		MarkHunk: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "mark hunk (diff view)"),
+		),
+		Stash: key.NewBinding(
+			key.WithKeys("Z"),
+			key.WithHelp("Z", "stash"),
+		),
+		PreviewStage: key.NewBinding(
+			key.WithKeys("K"),
+			key.WithHelp("K", "preview stage"),
+		),
+		EditHunk: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "edit hunk in $EDITOR"),
+		),
 		StageHunk: key.NewBinding(
 			key.WithKeys("S"),
 			key.WithHelp("S", "stage hunk"),
@@ -184,6 +238,70 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("t"),
 			key.WithHelp("t", "toggle staged view"),
 		),
+		ToggleDiffStat: key.NewBinding(
+			key.WithKeys("D"),
+			key.WithHelp("D", "diffstat panel"),
+		),
+		ToggleAllFiles: key.NewBinding(
+			key.WithKeys("F"),
+			key.WithHelp("F", "all files view"),
+		),
+		ToggleLayout: key.NewBinding(
+			key.WithKeys("L"),
+			key.WithHelp("L", "unified/side-by-side"),
+		),
+		ToggleWrap: key.NewBinding(
+			key.WithKeys("W"),
+			key.WithHelp("W", "soft-wrap"),
+		),
+		ToggleWordDiff: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", "word diff"),
+		),
+		DiffOptions: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "diff options"),
+		),
+
+		// Context expansion
+		ExpandAbove: key.NewBinding(
+			key.WithKeys("<"),
+			key.WithHelp("<", "more context above"),
+		),
+		ExpandBelow: key.NewBinding(
+			key.WithKeys(">"),
+			key.WithHelp(">", "more context below"),
+		),
+		ExpandGap: key.NewBinding(
+			key.WithKeys("E"),
+			key.WithHelp("E", "reveal gap above"),
+		),
+		ToggleFullFile: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "full file"),
+		),
+		ToggleThreeWay: key.NewBinding(
+			key.WithKeys("T"),
+			key.WithHelp("T", "three-way view"),
+		),
+
+		// Patches
+		ExportPatch: key.NewBinding(
+			key.WithKeys("X"),
+			key.WithHelp("X", "export patch"),
+		),
+		OpenPatch: key.NewBinding(
+			key.WithKeys("I"),
+			key.WithHelp("I", "open patch/commit/range"),
+		),
+		ApplyPatch: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "apply to worktree"),
+		),
+		ApplyPatchIndex: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", "apply to index"),
+		),
 
 		// Commit/Push
 		Commit: key.NewBinding(
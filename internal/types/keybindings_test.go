@@ -1,6 +1,7 @@
 package types
 
 import (
+	"reflect"
 	"testing"
 
 	"charm.land/bubbles/v2/key"
@@ -75,3 +76,31 @@ func TestDefaultKeyMap_SpaceToggle(t *testing.T) {
 		}
 	})
 }
+
+func TestDefaultKeyMap_NoDuplicateKeys(t *testing.T) {
+	// SelectHunk is not dispatched anywhere, so its "a" never shadows
+	// StageFile
+	unused := map[string]bool{"SelectHunk": true}
+	// Bindings handled in a single pane may share keys with bindings of
+	// other panes
+	paneOnly := map[string]string{"ToggleMark": "file tree", "MarkHunk": "diff view"}
+
+	km := reflect.ValueOf(DefaultKeyMap())
+	owners := make(map[string]string)
+	for i := 0; i < km.NumField(); i++ {
+		name := km.Type().Field(i).Name
+		b, ok := km.Field(i).Interface().(key.Binding)
+		if !ok || unused[name] {
+			continue
+		}
+		for _, k := range b.Keys() {
+			if other, dup := owners[k]; dup {
+				pane, otherPane := paneOnly[name], paneOnly[other]
+				if pane == "" || otherPane == "" || pane == otherPane {
+					t.Errorf("%q is bound to both %s and %s", k, other, name)
+				}
+			}
+			owners[k] = name
+		}
+	}
+}
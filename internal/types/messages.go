@@ -10,10 +10,34 @@ type StatusLoadedMsg struct {
 	Err   error
 }
 
-// DiffLoadedMsg is sent when a file's diff is loaded
+// StatsLoadedMsg is sent when per-file line counts are loaded.
+// Maps are keyed by path and hold [added, removed] counts.
+type StatsLoadedMsg struct {
+	Staged   map[string][2]int
+	Unstaged map[string][2]int
+	Err      error
+}
+
+// DiffLoadedMsg is sent when a file's diff is loaded.
+// All is set when Diffs holds every changed file rather than just Path.
 type DiffLoadedMsg struct {
 	Path  string
 	Diffs []diff.FileDiff
+	All   bool
+	Err   error
+}
+
+// FileLinesRequestMsg asks for the contents of a file so the diff view can
+// expand context around its hunks
+type FileLinesRequestMsg struct {
+	Path string
+}
+
+// FileLinesLoadedMsg is sent when a file's contents are loaded for context
+// expansion
+type FileLinesLoadedMsg struct {
+	Path  string
+	Lines []string
 	Err   error
 }
 
@@ -38,6 +62,24 @@ type UnstageCompleteMsg struct {
 	Err  error
 }
 
+// BatchCompleteMsg is sent when a multi-file operation completes.
+// Errs holds per-file failures keyed by path.
+type BatchCompleteMsg struct {
+	Op    BatchOp
+	Paths []string
+	Errs  map[string]error
+}
+
+// BatchOp identifies a multi-file operation
+type BatchOp int
+
+const (
+	BatchStage BatchOp = iota
+	BatchUnstage
+	BatchDiscard
+	BatchStash
+)
+
 // CommitCompleteMsg is sent when a commit completes
 type CommitCompleteMsg struct {
 	Hash string
@@ -60,6 +102,12 @@ type FileSelectedMsg struct {
 	Staged bool
 }
 
+// CursorFileChangedMsg is sent when the diff view cursor moves into a
+// different file of a multi-file diff
+type CursorFileChangedMsg struct {
+	Path string
+}
+
 // FocusChangedMsg is sent when focus changes between panes
 type FocusChangedMsg struct {
 	Pane Pane
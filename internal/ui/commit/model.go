@@ -1,6 +1,7 @@
 package commit
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
@@ -10,8 +11,10 @@ import (
 	"charm.land/bubbles/v2/textarea"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/commitmsg"
 	"github.com/Danny-Dasilva/gdiff/internal/git"
 	"github.com/Danny-Dasilva/gdiff/internal/types"
+	"github.com/charmbracelet/x/ansi"
 )
 
 // execCmd wraps *exec.Cmd to implement tea.ExecCommand
@@ -19,6 +22,32 @@ type execCmd struct{ *exec.Cmd }
 
 var confirmBinding = key.NewBinding(key.WithKeys("ctrl+d"))
 
+// Amend options, toggled while amending. Alt keys leave the textarea's
+// own ctrl bindings, such as ctrl+n for the next line, alone.
+var (
+	noEditBinding      = key.NewBinding(key.WithKeys("alt+n"))
+	resetAuthorBinding = key.NewBinding(key.WithKeys("alt+r"))
+	dateNowBinding     = key.NewBinding(key.WithKeys("alt+t"))
+)
+
+// Trailers and the co-author picker
+var (
+	signOffBinding  = key.NewBinding(key.WithKeys("ctrl+s"))
+	coAuthorBinding = key.NewBinding(key.WithKeys("ctrl+o"))
+	pickUpBinding   = key.NewBinding(key.WithKeys("up", "ctrl+p"))
+	pickDownBinding = key.NewBinding(key.WithKeys("down", "ctrl+n"))
+	pickBinding     = key.NewBinding(key.WithKeys("enter"))
+)
+
+// maxListedFiles is how many file names the amend summary lists per side
+const maxListedFiles = 4
+
+// pickerHeight is how many authors the co-author picker shows at once
+const pickerHeight = 8
+
+// maxListedViolations is how many lint violations are listed
+const maxListedViolations = 5
+
 func clamp(v, lo, hi int) int { return max(lo, min(v, hi)) }
 
 func (c *execCmd) SetStdin(r io.Reader)  { c.Cmd.Stdin = r }
@@ -37,6 +66,20 @@ type Model struct {
 	// Editor support
 	tempFilePath string
 
+	// head is the commit being amended, with the files staged on top of
+	// it and the chosen amend options
+	head      *git.HeadCommit
+	staged    []string
+	amendOpts git.AmendOptions
+
+	// info holds the template, identity and authors for the message;
+	// picking is set while the co-author picker is open
+	info       commitmsg.Info
+	picking    bool
+	pickCursor int
+
+	lintRules commitmsg.LintRules
+
 	// Styles
 	borderStyle lipgloss.Style
 	titleStyle  lipgloss.Style
@@ -65,10 +108,57 @@ func New(keyMap types.KeyMap) Model {
 func (m *Model) Show(amend bool) {
 	m.visible = true
 	m.amend = amend
+	m.head = nil
+	m.staged = nil
+	m.amendOpts = git.AmendOptions{}
+	m.info = commitmsg.Info{}
+	m.picking = false
 	m.textarea.Reset()
 	m.textarea.Focus()
 }
 
+// SetInfo supplies the template, identity and co-authors, which load after
+// the modal opens. A new commit with an untouched message is prefilled
+// from the template.
+func (m *Model) SetInfo(info commitmsg.Info) {
+	m.info = info
+	if !m.amend && m.textarea.Value() == "" && info.Template != "" {
+		m.textarea.SetValue(info.Template)
+		m.textarea.MoveToBegin()
+		m.textarea.CursorEnd()
+	}
+}
+
+// SetLintRules sets the checks run on the message as it is typed
+func (m *Model) SetLintRules(rules commitmsg.LintRules) {
+	m.lintRules = rules
+}
+
+// Violations returns the lint results for the message. A message kept
+// with --no-edit is not checked.
+func (m Model) Violations() []commitmsg.Violation {
+	if m.amend && m.amendOpts.NoEdit {
+		return nil
+	}
+	return commitmsg.Lint(m.textarea.Value(), m.lintRules)
+}
+
+// addTrailer appends a trailer to the message
+func (m *Model) addTrailer(token, value string) {
+	m.textarea.SetValue(commitmsg.AddTrailer(m.textarea.Value(), token, value))
+	m.textarea.MoveToEnd()
+}
+
+// ShowAmend displays the modal for amending HEAD, prefilled with its
+// message. staged lists the files staged on top of it.
+func (m *Model) ShowAmend(head git.HeadCommit, staged []string) {
+	m.Show(true)
+	m.head = &head
+	m.staged = staged
+	m.textarea.SetValue(head.Message)
+	m.textarea.MoveToEnd()
+}
+
 // Hide hides the commit modal
 func (m *Model) Hide() {
 	m.visible = false
@@ -106,10 +196,12 @@ func (m Model) Init() tea.Cmd {
 	return nil
 }
 
-// ConfirmMsg is sent when the user confirms the commit
+// ConfirmMsg is sent when the user confirms the commit. Options only
+// apply to an amend.
 type ConfirmMsg struct {
 	Message string
 	Amend   bool
+	Options git.AmendOptions
 }
 
 // CancelMsg is sent when the user cancels the commit
@@ -140,7 +232,21 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyPressMsg:
+		if m.picking {
+			return m.updatePicker(msg), nil
+		}
 		switch {
+		case key.Matches(msg, signOffBinding):
+			if m.info.Identity != "" {
+				m.addTrailer(commitmsg.SignedOffBy, m.info.Identity)
+			}
+			return m, nil
+
+		case key.Matches(msg, coAuthorBinding):
+			m.picking = len(m.info.Authors) > 0
+			m.pickCursor = 0
+			return m, nil
+
 		case key.Matches(msg, m.keyMap.Escape):
 			m.Hide()
 			return m, func() tea.Msg { return CancelMsg{} }
@@ -148,13 +254,27 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		case key.Matches(msg, m.keyMap.OpenEditor):
 			return m, m.openEditor()
 
+		case m.amend && key.Matches(msg, noEditBinding):
+			m.amendOpts.NoEdit = !m.amendOpts.NoEdit
+			return m, nil
+
+		case m.amend && key.Matches(msg, resetAuthorBinding):
+			m.amendOpts.ResetAuthor = !m.amendOpts.ResetAuthor
+			return m, nil
+
+		case m.amend && key.Matches(msg, dateNowBinding):
+			m.amendOpts.DateNow = !m.amendOpts.DateNow
+			return m, nil
+
 		case key.Matches(msg, confirmBinding):
-			if m.Message() != "" {
+			ready := m.Message() != "" || (m.amend && m.amendOpts.NoEdit)
+			if ready && !commitmsg.HasErrors(m.Violations()) {
 				m.Hide()
 				return m, func() tea.Msg {
 					return ConfirmMsg{
 						Message: m.Message(),
 						Amend:   m.amend,
+						Options: m.amendOpts,
 					}
 				}
 			}
@@ -166,6 +286,23 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	return m, cmd
 }
 
+// updatePicker moves through the co-author picker; Enter adds the author
+// under the cursor as a Co-authored-by trailer and Esc closes the picker
+func (m Model) updatePicker(msg tea.KeyPressMsg) Model {
+	switch {
+	case key.Matches(msg, pickUpBinding):
+		m.pickCursor = max(m.pickCursor-1, 0)
+	case key.Matches(msg, pickDownBinding):
+		m.pickCursor = min(m.pickCursor+1, len(m.info.Authors)-1)
+	case key.Matches(msg, pickBinding):
+		m.addTrailer(commitmsg.CoAuthoredBy, m.info.Authors[m.pickCursor])
+		m.picking = false
+	case key.Matches(msg, m.keyMap.Escape):
+		m.picking = false
+	}
+	return m
+}
+
 // openEditor creates a temp file and opens the external editor
 func (m *Model) openEditor() tea.Cmd {
 	tmpPath, err := git.CreateTempCommitFile(m.textarea.Value())
@@ -200,10 +337,28 @@ func (m Model) View() string {
 	if m.amend {
 		title = "Amend Commit"
 	}
+	if m.head != nil {
+		title += " " + shortHash(m.head.Hash)
+	}
 
 	content := m.titleStyle.Render(title) + "\n\n"
+	if m.head != nil {
+		content += m.amendSummary() + "\n\n"
+	}
 	content += m.textarea.View() + "\n\n"
-	content += m.helpStyle.Render("Ctrl+D to confirm • Ctrl+E to open editor • Esc to cancel")
+	if lint := m.lintView(); lint != "" {
+		content += lint + "\n\n"
+	}
+	if m.picking {
+		content += m.pickerView() + "\n\n"
+		content += m.helpStyle.Render("↑/↓ choose • Enter add co-author • Esc back")
+	} else {
+		if m.head != nil {
+			content += m.amendOptions() + "\n"
+		}
+		content += m.helpStyle.Render("Ctrl+D to confirm • Ctrl+E to open editor • Esc to cancel") + "\n"
+		content += m.helpStyle.Render("Ctrl+S sign off • Ctrl+O add co-author")
+	}
 
 	modal := m.borderStyle.Render(content)
 
@@ -225,3 +380,95 @@ func (m Model) View() string {
 
 	return b.String()
 }
+
+// amendSummary describes the commit being amended: a warning when it has
+// been pushed, its author and the files it holds next to the newly staged
+// ones
+func (m Model) amendSummary() string {
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	var lines []string
+	if m.head.Pushed {
+		warn := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#f38ba8"))
+		lines = append(lines, warn.Render(fmt.Sprintf("⚠ HEAD is already on %s; amending rewrites pushed history", m.head.Upstream)))
+	}
+
+	author, date := m.head.Author, m.head.Date
+	if m.amendOpts.ResetAuthor {
+		author, date = "you", "now"
+	} else if m.amendOpts.DateNow {
+		date = "now"
+	}
+	lines = append(lines,
+		labelStyle.Render("Author:  ")+author+labelStyle.Render(" · ")+date,
+		labelStyle.Render("In HEAD: ")+listFiles(m.head.Files),
+		labelStyle.Render("Staged:  ")+listFiles(m.staged),
+	)
+	return strings.Join(lines, "\n")
+}
+
+// amendOptions renders the amend toggles
+func (m Model) amendOptions() string {
+	check := func(on bool) string {
+		if on {
+			return "[x]"
+		}
+		return "[ ]"
+	}
+	return m.helpStyle.Render(fmt.Sprintf("%s keep message (alt+n)  %s reset author (alt+r)  %s date now (alt+t)",
+		check(m.amendOpts.NoEdit), check(m.amendOpts.ResetAuthor), check(m.amendOpts.DateNow)))
+}
+
+// listFiles joins file names, eliding all but the first few
+func listFiles(files []string) string {
+	switch {
+	case len(files) == 0:
+		return "none"
+	case len(files) > maxListedFiles:
+		return strings.Join(files[:maxListedFiles], ", ") + fmt.Sprintf(" and %d more", len(files)-maxListedFiles)
+	}
+	return strings.Join(files, ", ")
+}
+
+// shortHash abbreviates a commit hash
+func shortHash(hash string) string {
+	return hash[:min(len(hash), 7)]
+}
+
+// lintView lists the lint violations of the message, errors in red
+func (m Model) lintView() string {
+	vs := m.Violations()
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#f38ba8"))
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#f9e2af"))
+
+	var lines []string
+	for i, v := range vs {
+		if i == maxListedViolations {
+			lines = append(lines, m.helpStyle.Render(fmt.Sprintf("… %d more", len(vs)-i)))
+			break
+		}
+		text := ansi.Truncate(v.String(), max(m.textarea.Width()-2, 10), "…")
+		if v.Level == commitmsg.Error {
+			lines = append(lines, errorStyle.Render("✗ "+text))
+		} else {
+			lines = append(lines, warnStyle.Render("! "+text))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// pickerView lists the co-authors around the cursor
+func (m Model) pickerView() string {
+	start := max(0, min(m.pickCursor-pickerHeight/2, len(m.info.Authors)-pickerHeight))
+	end := min(start+pickerHeight, len(m.info.Authors))
+	selected := lipgloss.NewStyle().Background(lipgloss.Color("62"))
+
+	lines := []string{m.titleStyle.Render("Co-author")}
+	for i := start; i < end; i++ {
+		line := "  " + m.info.Authors[i] + " "
+		if i == m.pickCursor {
+			line = selected.Render("> " + m.info.Authors[i] + " ")
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
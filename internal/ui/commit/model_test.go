@@ -0,0 +1,124 @@
+package commit
+
+import (
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/commitmsg"
+	"github.com/Danny-Dasilva/gdiff/internal/git"
+	"github.com/Danny-Dasilva/gdiff/internal/types"
+)
+
+func TestShowAmendPrefillsAndConfirmsOptions(t *testing.T) {
+	m := New(types.DefaultKeyMap())
+	m.SetSize(100, 40)
+	m.ShowAmend(git.HeadCommit{
+		Hash:     "0123456789abcdef",
+		Message:  "Fix parser\n\nHandle empty input.",
+		Author:   "Test <test@example.com>",
+		Files:    []string{"parser.go"},
+		Upstream: "origin/main",
+		Pushed:   true,
+	}, []string{"parser_test.go"})
+
+	if m.Message() != "Fix parser\n\nHandle empty input." {
+		t.Errorf("Message() = %q, want HEAD's message", m.Message())
+	}
+	view := m.View()
+	for _, want := range []string{"Amend Commit 0123456", "already on origin/main", "parser.go", "parser_test.go"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view lacks %q", want)
+		}
+	}
+
+	// Clearing the message still confirms once the message is kept
+	m.textarea.Reset()
+	m, cmd := m.Update(tea.KeyPressMsg{Code: 'd', Mod: tea.ModCtrl})
+	if cmd != nil {
+		t.Fatal("an empty message should not confirm")
+	}
+	m, _ = m.Update(tea.KeyPressMsg{Code: 'n', Mod: tea.ModAlt})
+	m, _ = m.Update(tea.KeyPressMsg{Code: 'r', Mod: tea.ModAlt})
+	m, cmd = m.Update(tea.KeyPressMsg{Code: 'd', Mod: tea.ModCtrl})
+	if cmd == nil {
+		t.Fatal("Ctrl+D should confirm with --no-edit")
+	}
+	msg, ok := cmd().(ConfirmMsg)
+	if want := (git.AmendOptions{NoEdit: true, ResetAuthor: true}); !ok || !msg.Amend || msg.Options != want {
+		t.Errorf("got %+v, want an amend with %+v", msg, want)
+	}
+
+	// The textarea keeps its ctrl bindings while amending
+	m.ShowAmend(git.HeadCommit{Hash: "0123456789", Message: "ab\ncd"}, nil)
+	m, _ = m.Update(tea.KeyPressMsg{Code: 't', Mod: tea.ModCtrl})
+	m, _ = m.Update(tea.KeyPressMsg{Code: 'n', Mod: tea.ModCtrl})
+	if m.amendOpts != (git.AmendOptions{}) {
+		t.Errorf("ctrl+n and ctrl+t should not toggle options, got %+v", m.amendOpts)
+	}
+	if m.Message() != "ab\ndc" {
+		t.Errorf("ctrl+t should transpose, message = %q", m.Message())
+	}
+
+	m.Show(false)
+	if strings.Contains(m.View(), "In HEAD") {
+		t.Error("a plain commit shows no amend summary")
+	}
+}
+
+func TestTemplateAndTrailers(t *testing.T) {
+	m := New(types.DefaultKeyMap())
+	m.SetSize(100, 40)
+	m.Show(false)
+	m.SetInfo(commitmsg.Info{
+		Template: "ABC-1: ",
+		Identity: "Me <me@example.com>",
+		Authors:  []string{"Ann <ann@example.com>", "Bob <bob@example.com>"},
+	})
+	if m.textarea.Value() != "ABC-1: " {
+		t.Fatalf("message = %q, want the template", m.textarea.Value())
+	}
+
+	m, _ = m.Update(tea.KeyPressMsg{Code: 'x', Text: "x"})
+	m, _ = m.Update(tea.KeyPressMsg{Code: 's', Mod: tea.ModCtrl})
+	m, _ = m.Update(tea.KeyPressMsg{Code: 'o', Mod: tea.ModCtrl})
+	if !strings.Contains(m.View(), "Bob <bob@example.com>") {
+		t.Fatal("Ctrl+O should list the co-authors")
+	}
+	m, _ = m.Update(tea.KeyPressMsg{Code: tea.KeyDown})
+	m, _ = m.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+
+	want := "ABC-1: x\n\nSigned-off-by: Me <me@example.com>\nCo-authored-by: Bob <bob@example.com>"
+	if m.Message() != want {
+		t.Errorf("message =\n%q\nwant\n%q", m.Message(), want)
+	}
+	if !m.Visible() {
+		t.Error("closing the picker keeps the modal open")
+	}
+}
+
+func TestLintErrorsBlockConfirm(t *testing.T) {
+	rules := commitmsg.DefaultLintRules()
+	rules.ConventionalTypes = commitmsg.ListRule{Level: commitmsg.Error, Values: []string{"feat", "fix"}}
+	m := New(types.DefaultKeyMap())
+	m.SetSize(100, 40)
+	m.SetLintRules(rules)
+	m.Show(false)
+
+	m.textarea.SetValue("update things")
+	if !strings.Contains(m.View(), "type(scope): description") {
+		t.Error("the error should be shown inline")
+	}
+	if _, cmd := m.Update(tea.KeyPressMsg{Code: 'd', Mod: tea.ModCtrl}); cmd != nil {
+		t.Fatal("an error should block confirmation")
+	}
+
+	// A long body line is only a warning
+	m.textarea.SetValue("fix: update things\n\n" + strings.Repeat("x", 80))
+	if !strings.Contains(m.View(), "wrap at 72") {
+		t.Error("the warning should be shown inline")
+	}
+	if _, cmd := m.Update(tea.KeyPressMsg{Code: 'd', Mod: tea.ModCtrl}); cmd == nil {
+		t.Error("warnings should not block confirmation")
+	}
+}
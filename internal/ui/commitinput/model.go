@@ -4,6 +4,8 @@ import (
 	"charm.land/bubbles/v2/textinput"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/commitmsg"
+	"github.com/charmbracelet/x/ansi"
 )
 
 // Model represents the inline commit message input
@@ -12,6 +14,8 @@ type Model struct {
 	width   int
 	focused bool
 
+	lintRules commitmsg.LintRules
+
 	containerStyle lipgloss.Style
 	labelStyle     lipgloss.Style
 }
@@ -83,6 +87,26 @@ func (m *Model) SetValue(v string) {
 	m.input.SetValue(v)
 }
 
+// SetLintRules sets the checks run on the message as it is typed
+func (m *Model) SetLintRules(rules commitmsg.LintRules) {
+	m.lintRules = rules
+}
+
+// Violations returns the lint results for the message
+func (m Model) Violations() []commitmsg.Violation {
+	return commitmsg.Lint(m.input.Value(), m.lintRules)
+}
+
+// LintError returns the first lint error, which blocks committing, or nil
+func (m Model) LintError() *commitmsg.Violation {
+	for _, v := range m.Violations() {
+		if v.Level == commitmsg.Error {
+			return &v
+		}
+	}
+	return nil
+}
+
 // Reset clears the input
 func (m *Model) Reset() {
 	m.input.Reset()
@@ -126,6 +150,17 @@ func (m Model) View() string {
 			BorderForeground(lipgloss.Color("#a6e3a1"))
 	}
 
+	// The first violation shares the label's line so the height is fixed
+	if vs := m.Violations(); len(vs) > 0 {
+		color := lipgloss.Color("#f9e2af")
+		mark := " ! "
+		if vs[0].Level == commitmsg.Error {
+			color, mark = lipgloss.Color("#f38ba8"), " ✗ "
+		}
+		room := max(m.width-lipgloss.Width(label)-8, 0)
+		label += lipgloss.NewStyle().Foreground(color).Render(ansi.Truncate(mark+vs[0].Message, room, "…"))
+	}
+
 	content := label + "\n" + m.input.View()
 
 	return containerStyle.Width(m.width - 2).Render(content)
@@ -0,0 +1,211 @@
+// Package diffopts provides the overlay for changing how diffs are computed
+package diffopts
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/git"
+	"github.com/Danny-Dasilva/gdiff/internal/types"
+)
+
+var (
+	colorMauve   = lipgloss.Color("#cba6f7")
+	colorBase    = lipgloss.Color("#1e1e2e")
+	colorBlue    = lipgloss.Color("#89b4fa")
+	colorText    = lipgloss.Color("#cdd6f4")
+	colorOverlay = lipgloss.Color("#6c7086")
+)
+
+// MaxContext is the largest context size offered
+const MaxContext = 99
+
+// ChangedMsg is sent whenever an option changes
+type ChangedMsg struct {
+	Options git.DiffOptions
+}
+
+// Rows of the overlay
+const (
+	rowIgnoreAllSpace = iota
+	rowIgnoreSpaceChange
+	rowIgnoreBlankLines
+	rowAlgorithm
+	rowContext
+	rowCount
+)
+
+var (
+	toggleBinding = key.NewBinding(key.WithKeys("space", "enter"))
+	moreBinding   = key.NewBinding(key.WithKeys("+", "="))
+	lessBinding   = key.NewBinding(key.WithKeys("-"))
+	resetBinding  = key.NewBinding(key.WithKeys("r"))
+)
+
+// Model is a toggleable overlay listing the diff options
+type Model struct {
+	opts    git.DiffOptions
+	cursor  int
+	visible bool
+	width   int
+	height  int
+	keyMap  types.KeyMap
+}
+
+// New creates a hidden options overlay
+func New(keyMap types.KeyMap, opts git.DiffOptions) Model {
+	return Model{keyMap: keyMap, opts: opts}
+}
+
+// Options returns the current options
+func (m Model) Options() git.DiffOptions {
+	return m.opts
+}
+
+// Toggle shows or hides the overlay
+func (m *Model) Toggle() {
+	m.visible = !m.visible
+}
+
+// Hide hides the overlay
+func (m *Model) Hide() {
+	m.visible = false
+}
+
+// Visible returns whether the overlay is visible
+func (m Model) Visible() bool {
+	return m.visible
+}
+
+// SetSize updates the overlay dimensions
+func (m *Model) SetSize(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+// Update handles navigation and edits. Every edit emits a ChangedMsg.
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	if !m.visible {
+		return m, nil
+	}
+
+	keyMsg, ok := msg.(tea.KeyPressMsg)
+	if !ok {
+		return m, nil
+	}
+
+	prev := m.opts
+	switch {
+	case key.Matches(keyMsg, m.keyMap.Down):
+		m.cursor = min(m.cursor+1, rowCount-1)
+	case key.Matches(keyMsg, m.keyMap.Up):
+		m.cursor = max(m.cursor-1, 0)
+	case key.Matches(keyMsg, toggleBinding), key.Matches(keyMsg, m.keyMap.Right), key.Matches(keyMsg, moreBinding):
+		m.adjust(1)
+	case key.Matches(keyMsg, m.keyMap.Left), key.Matches(keyMsg, lessBinding):
+		m.adjust(-1)
+	case key.Matches(keyMsg, resetBinding):
+		m.opts = git.DefaultDiffOptions()
+	}
+
+	if m.opts == prev {
+		return m, nil
+	}
+	opts := m.opts
+	return m, func() tea.Msg {
+		return ChangedMsg{Options: opts}
+	}
+}
+
+// adjust changes the option under the cursor: booleans flip, the algorithm
+// cycles and the context size steps by one
+func (m *Model) adjust(delta int) {
+	switch m.cursor {
+	case rowIgnoreAllSpace:
+		m.opts.IgnoreAllSpace = !m.opts.IgnoreAllSpace
+	case rowIgnoreSpaceChange:
+		m.opts.IgnoreSpaceChange = !m.opts.IgnoreSpaceChange
+	case rowIgnoreBlankLines:
+		m.opts.IgnoreBlankLines = !m.opts.IgnoreBlankLines
+	case rowAlgorithm:
+		i := max(slices.Index(git.Algorithms, m.opts.Algorithm), 0)
+		n := len(git.Algorithms)
+		m.opts.Algorithm = git.Algorithms[(i+delta+n)%n]
+	case rowContext:
+		m.opts.Context = max(0, min(m.opts.Context+delta, MaxContext))
+	}
+}
+
+// View renders the overlay as a centered modal. Returns empty string when hidden.
+func (m Model) View() string {
+	if !m.visible {
+		return ""
+	}
+
+	labelStyle := lipgloss.NewStyle().Foreground(colorText)
+	valueStyle := lipgloss.NewStyle().Bold(true).Foreground(colorBlue)
+	dimStyle := lipgloss.NewStyle().Foreground(colorOverlay)
+	cursorStyle := lipgloss.NewStyle().Background(lipgloss.Color("62"))
+
+	check := func(on bool) string {
+		if on {
+			return "[x]"
+		}
+		return "[ ]"
+	}
+	rows := [rowCount][2]string{
+		rowIgnoreAllSpace:    {"Ignore all whitespace (-w)", check(m.opts.IgnoreAllSpace)},
+		rowIgnoreSpaceChange: {"Ignore whitespace changes (-b)", check(m.opts.IgnoreSpaceChange)},
+		rowIgnoreBlankLines:  {"Ignore blank lines", check(m.opts.IgnoreBlankLines)},
+		rowAlgorithm:         {"Algorithm", "< " + m.opts.Algorithm + " >"},
+		rowContext:           {"Context lines (-U)", fmt.Sprintf("< %d >", m.opts.Context)},
+	}
+
+	var lines []string
+	for i, r := range rows {
+		row := " " + labelStyle.Render(fmt.Sprintf("%-32s", r[0])) + valueStyle.Render(r[1]) + " "
+		if i == m.cursor {
+			row = cursorStyle.Render(row)
+		}
+		lines = append(lines, row)
+	}
+	if m.opts.IgnoresWhitespace() {
+		lines = append(lines, "", dimStyle.Render("Hunk and line staging is disabled while whitespace is ignored"))
+	}
+
+	hint := dimStyle.Render("j/k move • space/h/l change • r reset • o/Esc close")
+
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.NewStyle().Bold(true).Foreground(colorText).Render("Diff Options"),
+		"",
+		strings.Join(lines, "\n"),
+		"",
+		hint,
+	)
+
+	boxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorMauve).
+		Background(colorBase).
+		Padding(1, 2)
+
+	modal := boxStyle.Render(content)
+
+	padLeft := max((m.width-lipgloss.Width(modal))/2, 0)
+	padTop := max((m.height-lipgloss.Height(modal))/2, 0)
+
+	var b strings.Builder
+	b.WriteString(strings.Repeat("\n", padTop))
+	indent := strings.Repeat(" ", padLeft)
+	for _, line := range strings.Split(modal, "\n") {
+		b.WriteString(indent)
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package diffopts
+
+import (
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/git"
+	"github.com/Danny-Dasilva/gdiff/internal/types"
+)
+
+func press(m Model, k string) (Model, tea.Cmd) {
+	r := []rune(k)
+	return m.Update(tea.KeyPressMsg{Code: r[0], Text: k})
+}
+
+func TestAdjustOptions(t *testing.T) {
+	m := New(types.DefaultKeyMap(), git.DefaultDiffOptions())
+	m.Toggle()
+
+	m, cmd := press(m, "l")
+	if !m.Options().IgnoreAllSpace {
+		t.Error("expected -w to be toggled on")
+	}
+	if cmd == nil {
+		t.Fatal("expected a ChangedMsg")
+	}
+	if msg, ok := cmd().(ChangedMsg); !ok || !msg.Options.IgnoreAllSpace {
+		t.Errorf("got %#v, want ChangedMsg with -w", cmd())
+	}
+
+	// Algorithm cycles in both directions
+	for range rowAlgorithm {
+		m, _ = press(m, "j")
+	}
+	m, _ = press(m, "l")
+	if got := m.Options().Algorithm; got != "patience" {
+		t.Errorf("next algorithm = %s, want patience", got)
+	}
+	m, _ = press(m, "h")
+	m, _ = press(m, "h")
+	if got := m.Options().Algorithm; got != "myers" {
+		t.Errorf("algorithm should wrap around, got %s", got)
+	}
+
+	// Context never goes below zero
+	m, _ = press(m, "j")
+	for range 5 {
+		m, _ = press(m, "-")
+	}
+	if got := m.Options().Context; got != 0 {
+		t.Errorf("context = %d, want 0", got)
+	}
+	m, cmd = press(m, "-")
+	if cmd != nil {
+		t.Error("no ChangedMsg expected when nothing changed")
+	}
+
+	m, _ = press(m, "r")
+	if m.Options() != git.DefaultDiffOptions() {
+		t.Errorf("reset gave %+v", m.Options())
+	}
+}
+
+func TestHiddenIgnoresKeys(t *testing.T) {
+	m := New(types.DefaultKeyMap(), git.DefaultDiffOptions())
+	m, cmd := press(m, "l")
+	if cmd != nil || m.Options() != git.DefaultDiffOptions() {
+		t.Error("hidden overlay should not change options")
+	}
+}
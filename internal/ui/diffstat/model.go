@@ -0,0 +1,322 @@
+package diffstat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/types"
+)
+
+var (
+	colorMauve   = lipgloss.Color("#cba6f7")
+	colorBase    = lipgloss.Color("#1e1e2e")
+	colorBlue    = lipgloss.Color("#89b4fa")
+	colorGreen   = lipgloss.Color("#a6e3a1")
+	colorRed     = lipgloss.Color("#f38ba8")
+	colorText    = lipgloss.Color("#cdd6f4")
+	colorOverlay = lipgloss.Color("#6c7086")
+)
+
+// BarWidth is the number of cells used by a diffstat bar
+const BarWidth = 10
+
+// chromeHeight is the height of the border, padding, title and hint
+// around the panel rows
+const chromeHeight = 8
+
+// Entry holds the line counts for one changed file
+type Entry struct {
+	Path    string
+	Added   int
+	Removed int
+	Staged  bool
+}
+
+// Churn returns the total number of changed lines
+func (e Entry) Churn() int {
+	return e.Added + e.Removed
+}
+
+// SortOrder controls how panel entries are ordered
+type SortOrder int
+
+const (
+	SortByPath SortOrder = iota
+	SortByChurn
+)
+
+func (o SortOrder) String() string {
+	if o == SortByChurn {
+		return "churn"
+	}
+	return "path"
+}
+
+// Scale splits width cells into added and removed portions, proportional
+// to the largest churn in the set, like git diff --stat does
+func Scale(added, removed, maxChurn, width int) (plus, minus int) {
+	churn := added + removed
+	if churn == 0 || maxChurn == 0 || width <= 0 {
+		return 0, 0
+	}
+
+	total := churn
+	if maxChurn > width {
+		total = churn * width / maxChurn
+		if total == 0 {
+			total = 1
+		}
+	}
+
+	plus = total * added / churn
+	minus = total - plus
+	// Never hide a side that has changes
+	if added > 0 && plus == 0 && total > 1 {
+		plus, minus = 1, total-1
+	}
+	if removed > 0 && minus == 0 && total > 1 {
+		plus, minus = total-1, 1
+	}
+	return plus, minus
+}
+
+// Entries converts numstat maps into panel entries
+func Entries(staged, unstaged map[string][2]int) []Entry {
+	var entries []Entry
+	for path, s := range staged {
+		entries = append(entries, Entry{Path: path, Added: s[0], Removed: s[1], Staged: true})
+	}
+	for path, s := range unstaged {
+		entries = append(entries, Entry{Path: path, Added: s[0], Removed: s[1]})
+	}
+	return entries
+}
+
+// Sort orders entries in place: staged before unstaged, then by order
+func Sort(entries []Entry, order SortOrder) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Staged != b.Staged {
+			return a.Staged
+		}
+		if order == SortByChurn && a.Churn() != b.Churn() {
+			return a.Churn() > b.Churn()
+		}
+		return a.Path < b.Path
+	})
+}
+
+// Model is a toggleable overview panel listing per-file line counts
+type Model struct {
+	entries []Entry
+	order   SortOrder
+	cursor  int
+	visible bool
+	width   int
+	height  int
+	keyMap  types.KeyMap
+}
+
+// New creates a hidden diffstat panel
+func New(keyMap types.KeyMap) Model {
+	return Model{keyMap: keyMap}
+}
+
+// SetStats replaces the panel contents
+func (m *Model) SetStats(staged, unstaged map[string][2]int) {
+	m.entries = Entries(staged, unstaged)
+	Sort(m.entries, m.order)
+	if m.cursor >= len(m.entries) {
+		m.cursor = max(0, len(m.entries)-1)
+	}
+}
+
+// Toggle shows or hides the panel
+func (m *Model) Toggle() {
+	m.visible = !m.visible
+}
+
+// Hide hides the panel
+func (m *Model) Hide() {
+	m.visible = false
+}
+
+// Visible returns whether the panel is visible
+func (m Model) Visible() bool {
+	return m.visible
+}
+
+// SetSize updates the panel dimensions
+func (m *Model) SetSize(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+// Order returns the current sort order
+func (m Model) Order() SortOrder {
+	return m.order
+}
+
+// ToggleOrder switches between path and churn ordering
+func (m *Model) ToggleOrder() {
+	if m.order == SortByPath {
+		m.order = SortByChurn
+	} else {
+		m.order = SortByPath
+	}
+	Sort(m.entries, m.order)
+}
+
+// Totals returns file, insertion and deletion counts for one section
+func (m Model) Totals(staged bool) (files, added, removed int) {
+	for _, e := range m.entries {
+		if e.Staged == staged {
+			files++
+			added += e.Added
+			removed += e.Removed
+		}
+	}
+	return files, added, removed
+}
+
+var orderBinding = key.NewBinding(key.WithKeys("o"))
+
+// Update handles navigation inside the panel. Enter selects the file under
+// the cursor and closes the panel.
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	if !m.visible {
+		return m, nil
+	}
+
+	keyMsg, ok := msg.(tea.KeyPressMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(keyMsg, m.keyMap.Down):
+		if m.cursor < len(m.entries)-1 {
+			m.cursor++
+		}
+	case key.Matches(keyMsg, m.keyMap.Up):
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case key.Matches(keyMsg, orderBinding):
+		m.ToggleOrder()
+	case key.Matches(keyMsg, m.keyMap.Enter):
+		if m.cursor < len(m.entries) {
+			e := m.entries[m.cursor]
+			m.Hide()
+			return m, func() tea.Msg {
+				return types.FileSelectedMsg{Path: e.Path, Staged: e.Staged}
+			}
+		}
+	}
+	return m, nil
+}
+
+// View renders the panel as a centered modal. Returns empty string when hidden.
+func (m Model) View() string {
+	if !m.visible {
+		return ""
+	}
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(colorBlue)
+	pathStyle := lipgloss.NewStyle().Foreground(colorText)
+	addStyle := lipgloss.NewStyle().Foreground(colorGreen)
+	delStyle := lipgloss.NewStyle().Foreground(colorRed)
+	dimStyle := lipgloss.NewStyle().Foreground(colorOverlay)
+	cursorStyle := lipgloss.NewStyle().Background(lipgloss.Color("62"))
+
+	modalWidth := clamp(m.width*80/100, 40, 100)
+	pathWidth := modalWidth - BarWidth - 20
+
+	maxChurn := 0
+	for _, e := range m.entries {
+		maxChurn = max(maxChurn, e.Churn())
+	}
+
+	var lines []string
+	cursorLine := 0
+	section := func(staged bool, title string) {
+		files, added, removed := m.Totals(staged)
+		if files == 0 {
+			return
+		}
+		lines = append(lines, headerStyle.Render(title)+dimStyle.Render(
+			fmt.Sprintf("  %d file(s), %d insertion(s)(+), %d deletion(s)(-)", files, added, removed)))
+		for i, e := range m.entries {
+			if e.Staged != staged {
+				continue
+			}
+			path := e.Path
+			if len(path) > pathWidth && pathWidth > 3 {
+				path = "..." + path[len(path)-pathWidth+3:]
+			}
+			plus, minus := Scale(e.Added, e.Removed, maxChurn, BarWidth)
+			padded := path + strings.Repeat(" ", max(0, pathWidth-len(path)))
+			row := " " + pathStyle.Render(padded) + fmt.Sprintf(" %5d ", e.Churn()) +
+				addStyle.Render(strings.Repeat("+", plus)) +
+				delStyle.Render(strings.Repeat("-", minus))
+			if i == m.cursor {
+				row = cursorStyle.Render(row)
+				cursorLine = len(lines)
+			}
+			lines = append(lines, row)
+		}
+		lines = append(lines, "")
+	}
+	section(true, "Staged")
+	section(false, "Unstaged")
+
+	if len(m.entries) == 0 {
+		lines = append(lines, dimStyle.Render("No changes"), "")
+	}
+
+	// Keep the rows around the cursor when they do not all fit
+	if rows := max(m.height-chromeHeight, 3); len(lines) > rows {
+		start := max(0, min(cursorLine-rows/2, len(lines)-rows))
+		lines = lines[start : start+rows]
+	}
+
+	hint := dimStyle.Render(fmt.Sprintf("j/k move • enter open • o sort (%s) • D/Esc close", m.order))
+
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.NewStyle().Bold(true).Foreground(colorText).Render("Diffstat"),
+		"",
+		strings.Join(lines, "\n"),
+		hint,
+	)
+
+	boxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorMauve).
+		Background(colorBase).
+		Padding(1, 2).
+		Width(modalWidth)
+
+	modal := boxStyle.Render(content)
+
+	padLeft := max((m.width-lipgloss.Width(modal))/2, 0)
+	padTop := max((m.height-lipgloss.Height(modal))/2, 0)
+
+	var b strings.Builder
+	b.WriteString(strings.Repeat("\n", padTop))
+	indent := strings.Repeat(" ", padLeft)
+	for _, line := range strings.Split(modal, "\n") {
+		b.WriteString(indent)
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+func clamp(v, lo, hi int) int {
+	return max(lo, min(v, hi))
+}
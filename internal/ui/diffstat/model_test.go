@@ -0,0 +1,112 @@
+package diffstat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/types"
+)
+
+func TestScale(t *testing.T) {
+	tests := []struct {
+		name              string
+		added, removed    int
+		maxChurn, width   int
+		wantPlus, wantMin int
+	}{
+		{"fits without scaling", 3, 2, 8, 10, 3, 2},
+		{"scaled to width", 50, 50, 100, 10, 5, 5},
+		{"largest file fills bar", 80, 20, 100, 10, 8, 2},
+		{"tiny change still visible", 1, 0, 1000, 10, 1, 0},
+		{"minority side kept", 99, 1, 100, 10, 9, 1},
+		{"no changes", 0, 0, 100, 10, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plus, minus := Scale(tt.added, tt.removed, tt.maxChurn, tt.width)
+			if plus != tt.wantPlus || minus != tt.wantMin {
+				t.Errorf("Scale(%d, %d, %d, %d) = (%d, %d), want (%d, %d)",
+					tt.added, tt.removed, tt.maxChurn, tt.width, plus, minus, tt.wantPlus, tt.wantMin)
+			}
+		})
+	}
+}
+
+func TestSortByChurn(t *testing.T) {
+	m := New(types.DefaultKeyMap())
+	m.SetStats(
+		map[string][2]int{"staged.go": {1, 1}},
+		map[string][2]int{"a.go": {1, 0}, "b.go": {40, 2}, "c.go": {5, 5}},
+	)
+
+	paths := func() []string {
+		var out []string
+		for _, e := range m.entries {
+			out = append(out, e.Path)
+		}
+		return out
+	}
+
+	if got := strings.Join(paths(), ","); got != "staged.go,a.go,b.go,c.go" {
+		t.Errorf("path order = %s", got)
+	}
+
+	m.ToggleOrder()
+	if m.Order() != SortByChurn {
+		t.Fatal("expected churn ordering after toggle")
+	}
+	if got := strings.Join(paths(), ","); got != "staged.go,b.go,c.go,a.go" {
+		t.Errorf("churn order = %s", got)
+	}
+}
+
+func TestTotals(t *testing.T) {
+	m := New(types.DefaultKeyMap())
+	m.SetStats(
+		map[string][2]int{"x.go": {3, 1}, "y.go": {2, 0}},
+		map[string][2]int{"z.go": {7, 4}},
+	)
+
+	files, added, removed := m.Totals(true)
+	if files != 2 || added != 5 || removed != 1 {
+		t.Errorf("staged totals = (%d, %d, %d), want (2, 5, 1)", files, added, removed)
+	}
+	files, added, removed = m.Totals(false)
+	if files != 1 || added != 7 || removed != 4 {
+		t.Errorf("unstaged totals = (%d, %d, %d), want (1, 7, 4)", files, added, removed)
+	}
+}
+
+func TestViewWhenHidden(t *testing.T) {
+	m := New(types.DefaultKeyMap())
+	m.SetSize(120, 40)
+	if m.View() != "" {
+		t.Error("View() should return empty string when hidden")
+	}
+}
+
+func TestViewScrollsToCursor(t *testing.T) {
+	m := New(types.DefaultKeyMap())
+	unstaged := map[string][2]int{}
+	for i := range 50 {
+		unstaged[fmt.Sprintf("file%02d.go", i)] = [2]int{1, 0}
+	}
+	m.SetStats(nil, unstaged)
+	m.SetSize(100, 20)
+	m.Toggle()
+
+	for range 40 {
+		m, _ = m.Update(tea.KeyPressMsg{Code: 'j', Text: "j"})
+	}
+	view := m.View()
+	if h := lipgloss.Height(view); h > 20 {
+		t.Errorf("view is %d lines high, want at most 20", h)
+	}
+	if !strings.Contains(view, "file40.go") || strings.Contains(view, "file00.go") {
+		t.Errorf("view should follow the cursor to file40.go:\n%s", view)
+	}
+}
@@ -0,0 +1,42 @@
+package diffview
+
+import "github.com/Danny-Dasilva/gdiff/pkg/diff"
+
+// Conflict is a hunk of an opened patch that did not apply, with git's
+// reason. Hunks are matched by path and header, like marks.
+type Conflict struct {
+	Path   string
+	Header string
+	Reason string
+}
+
+// SetConflicts shows each conflict under its hunk header, replacing any
+// shown before; nil clears them
+func (m *Model) SetConflicts(conflicts []Conflict) {
+	m.conflicts = conflicts
+	m.updateViewportContent()
+}
+
+// Conflicts returns the conflicts being shown
+func (m Model) Conflicts() []Conflict {
+	return m.conflicts
+}
+
+// conflictLabel returns the row shown under a hunk header whose hunk did
+// not apply, or "" for other lines
+func (m Model) conflictLabel(line diff.Line, file, hunk int) string {
+	if line.Type != diff.LineHunkHeader || len(m.conflicts) == 0 {
+		return ""
+	}
+	fd := m.diffs[file]
+	for _, c := range m.conflicts {
+		if c.Path == fd.NewPath && c.Header == fd.Hunks[hunk].Header {
+			label := "  ✗ does not apply"
+			if c.Reason != "" {
+				label += ": " + c.Reason
+			}
+			return m.removedStyle.Render(label)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,308 @@
+package diffview
+
+import (
+	tea "charm.land/bubbletea/v2"
+	"github.com/Danny-Dasilva/gdiff/internal/types"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+	"github.com/Danny-Dasilva/gdiff/pkg/syntax"
+)
+
+// contextStep is the number of lines revealed per expand keypress
+const contextStep = 10
+
+// expansion tracks the extra context revealed around one file's hunks.
+// Gap g is the unchanged region before hunk g; the last gap follows the
+// final hunk. Expanded lines are display-only: they are never part of the
+// hunks handed to the staging code.
+type expansion struct {
+	lines     []string // New-side file contents, nil until loaded
+	requested bool
+
+	top    []int  // Lines revealed at the top of each gap (below the previous hunk)
+	bottom []int  // Lines revealed at the bottom of each gap (above the next hunk)
+	open   []bool // Gaps revealed completely
+	full   bool   // Whole file shown
+
+	extras [][]diff.Line
+	tokens [][][]syntax.Token
+}
+
+func newExpansion(hunks int) *expansion {
+	return &expansion{
+		top:    make([]int, hunks+1),
+		bottom: make([]int, hunks+1),
+		open:   make([]bool, hunks+1),
+	}
+}
+
+// newBefore returns the last new-side line before a hunk. Hunks without new
+// lines are anchored on the line they follow.
+func newBefore(h diff.Hunk) int {
+	if h.NewCount == 0 {
+		return h.NewStart
+	}
+	return h.NewStart - 1
+}
+
+func oldBefore(h diff.Hunk) int {
+	if h.OldCount == 0 {
+		return h.OldStart
+	}
+	return h.OldStart - 1
+}
+
+// newEnd returns the last new-side line covered by a hunk
+func newEnd(h diff.Hunk) int {
+	if h.NewCount == 0 {
+		return h.NewStart
+	}
+	return h.NewStart + h.NewCount - 1
+}
+
+func oldEnd(h diff.Hunk) int {
+	if h.OldCount == 0 {
+		return h.OldStart
+	}
+	return h.OldStart + h.OldCount - 1
+}
+
+// gapBounds returns the new-side line range [first, last] of gap g and the
+// offset that converts its new line numbers to old ones
+func gapBounds(hunks []diff.Hunk, g, fileLen int) (first, last, delta int) {
+	first = 1
+	if g > 0 {
+		h := hunks[g-1]
+		first = newEnd(h) + 1
+		delta = oldEnd(h) - newEnd(h)
+	}
+	last = fileLen
+	if g < len(hunks) {
+		h := hunks[g]
+		last = newBefore(h)
+		delta = oldBefore(h) - newBefore(h)
+	}
+	return first, last, delta
+}
+
+// rebuild recomputes the revealed lines of every gap
+func (e *expansion) rebuild(path string, hunks []diff.Hunk) {
+	e.extras, e.tokens = nil, nil
+	if e.lines == nil {
+		return
+	}
+
+	lang := syntax.Detect(path)
+	e.extras = make([][]diff.Line, len(hunks)+1)
+	e.tokens = make([][][]syntax.Token, len(hunks)+1)
+	for g := range e.extras {
+		first, last, delta := gapBounds(hunks, g, len(e.lines))
+		last = min(last, len(e.lines))
+		size := last - first + 1
+		if size <= 0 {
+			continue
+		}
+
+		top, bottom := e.top[g], e.bottom[g]
+		if e.full || e.open[g] || top+bottom >= size {
+			top, bottom = size, 0
+		}
+
+		var state syntax.State
+		add := func(n int) {
+			e.extras[g] = append(e.extras[g], diff.Line{
+				Type:    diff.LineContext,
+				Content: e.lines[n-1],
+				OldNum:  n + delta,
+				NewNum:  n,
+			})
+			var tokens []syntax.Token
+			tokens, state = lang.Tokenize(e.lines[n-1], state)
+			e.tokens[g] = append(e.tokens[g], tokens)
+		}
+		for n := first; n < first+top; n++ {
+			add(n)
+		}
+		state = syntax.State{}
+		for n := last - bottom + 1; n <= last; n++ {
+			add(n)
+		}
+	}
+}
+
+// extraLines returns the expanded context shown for gap g of a file
+func (m Model) extraLines(file, g int) []diff.Line {
+	e := m.expansions[m.diffs[file].NewPath]
+	if e == nil || g >= len(e.extras) {
+		return nil
+	}
+	return e.extras[g]
+}
+
+func (m Model) extraTokens(file, g int) [][]syntax.Token {
+	e := m.expansions[m.diffs[file].NewPath]
+	if e == nil || g >= len(e.tokens) {
+		return nil
+	}
+	return e.tokens[g]
+}
+
+// expandAction identifies a context expansion key
+type expandAction int
+
+const (
+	expandAbove expandAction = iota
+	expandBelow
+	expandGap
+	expandFull
+)
+
+// expand reveals context around the cursor. Returns a command requesting the
+// file contents when they have not been loaded yet.
+func (m *Model) expand(action expandAction) tea.Cmd {
+	pos, ok := m.locate(m.cursor)
+	if !ok || m.diffs[pos.file].IsBinary {
+		return nil
+	}
+	fd := m.diffs[pos.file]
+	if len(fd.Hunks) == 0 {
+		return nil
+	}
+
+	if m.expansions == nil {
+		m.expansions = make(map[string]*expansion)
+	}
+	e := m.expansions[fd.NewPath]
+	if e == nil {
+		e = newExpansion(len(fd.Hunks))
+		m.expansions[fd.NewPath] = e
+	}
+
+	// above/below are relative to the hunk under the cursor; inside expanded
+	// context they grow that gap, and on a file header they act on hunk 0
+	aboveGap, belowGap := 0, 1
+	switch {
+	case pos.extra:
+		aboveGap, belowGap = pos.hunk, pos.hunk
+	case pos.hunk >= 0:
+		aboveGap, belowGap = pos.hunk, pos.hunk+1
+	}
+
+	anchor := m.cursorAnchor()
+	switch action {
+	case expandAbove:
+		e.bottom[aboveGap] += contextStep
+	case expandBelow:
+		e.top[belowGap] += contextStep
+	case expandGap:
+		e.open[aboveGap] = true
+	case expandFull:
+		e.full = !e.full
+	}
+	e.rebuild(fd.NewPath, fd.Hunks)
+	m.restoreCursor(anchor)
+	m.syncViewport()
+
+	if e.lines == nil && !e.requested {
+		e.requested = true
+		path := fd.NewPath
+		return func() tea.Msg {
+			return types.FileLinesRequestMsg{Path: path}
+		}
+	}
+	return nil
+}
+
+// SetFileLines supplies the contents used to expand context around a file
+func (m *Model) SetFileLines(path string, lines []string) {
+	e := m.expansions[path]
+	if e == nil {
+		return
+	}
+	e.lines = lines
+	for _, fd := range m.diffs {
+		if fd.NewPath == path {
+			anchor := m.cursorAnchor()
+			e.rebuild(path, fd.Hunks)
+			m.restoreCursor(anchor)
+			m.syncViewport()
+			return
+		}
+	}
+}
+
+// PendingFileLines returns the expanded files whose contents must be
+// (re)loaded, marking them as requested
+func (m *Model) PendingFileLines() []string {
+	var paths []string
+	for path, e := range m.expansions {
+		if e.lines == nil && !e.requested {
+			e.requested = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// resetExpansions drops loaded contents after the diff was reloaded, keeping
+// what was revealed when the hunk layout is unchanged
+func (m *Model) resetExpansions() {
+	for path, e := range m.expansions {
+		e.lines, e.requested, e.extras, e.tokens = nil, false, nil, nil
+		for _, fd := range m.diffs {
+			if fd.NewPath == path && len(fd.Hunks)+1 != len(e.top) {
+				full := e.full
+				*e = *newExpansion(len(fd.Hunks))
+				e.full = full
+			}
+		}
+	}
+}
+
+// lineAnchor identifies the cursor line independently of its flattened
+// index, which shifts as context is revealed
+type lineAnchor struct {
+	pos    linePos
+	newNum int
+}
+
+func (m Model) cursorAnchor() lineAnchor {
+	pos, _ := m.locate(m.cursor)
+	a := lineAnchor{pos: pos}
+	if line := m.lineAt(m.cursor); line != nil {
+		a.newNum = line.NewNum
+	}
+	return a
+}
+
+// restoreCursor moves the cursor back to an anchored line. Expanded context
+// is matched by line number; lines that disappeared fall back to the hunk.
+func (m *Model) restoreCursor(a lineAnchor) {
+	target := -1
+	m.walk(func(seg segment) bool {
+		if seg.file != a.pos.file || seg.hunk != a.pos.hunk {
+			return true
+		}
+		switch {
+		case seg.hunk < 0:
+			target = seg.start
+			return false
+		case seg.extra && a.pos.extra:
+			for i, line := range m.extraLines(seg.file, seg.hunk) {
+				if line.NewNum == a.newNum {
+					target = seg.start + i
+					return false
+				}
+			}
+		case !seg.extra && !a.pos.extra:
+			target = seg.start + a.pos.line
+			return false
+		case !seg.extra && target < 0:
+			target = seg.start
+			return false
+		}
+		return true
+	})
+	if target >= 0 {
+		m.cursor = target
+	}
+}
@@ -0,0 +1,173 @@
+package diffview
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"charm.land/bubbles/v2/key"
+	"github.com/Danny-Dasilva/gdiff/internal/types"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// expandFixture is a 60 line file whose line 15 changed, with three lines
+// of context on each side
+func expandFixture() ([]diff.FileDiff, []string) {
+	lines := make([]string, 60)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	hunk := diff.Hunk{
+		OldStart: 12, OldCount: 7, NewStart: 12, NewCount: 7,
+		Lines: []diff.Line{{Type: diff.LineHunkHeader, Content: "@@ -12,7 +12,7 @@"}},
+	}
+	for n := 12; n <= 14; n++ {
+		hunk.Lines = append(hunk.Lines, diff.Line{Type: diff.LineContext, Content: lines[n-1], OldNum: n, NewNum: n})
+	}
+	hunk.Lines = append(hunk.Lines,
+		diff.Line{Type: diff.LineRemoved, Content: "old 15", OldNum: 15},
+		diff.Line{Type: diff.LineAdded, Content: lines[14], NewNum: 15},
+	)
+	for n := 16; n <= 18; n++ {
+		hunk.Lines = append(hunk.Lines, diff.Line{Type: diff.LineContext, Content: lines[n-1], OldNum: n, NewNum: n})
+	}
+	return []diff.FileDiff{{OldPath: "a.go", NewPath: "a.go", Hunks: []diff.Hunk{hunk}}}, lines
+}
+
+func newExpandModel(t *testing.T) (Model, []string) {
+	t.Helper()
+	keys := newTestKeyMap()
+	keys.ExpandAbove = key.NewBinding(key.WithKeys("<"))
+	keys.ExpandBelow = key.NewBinding(key.WithKeys(">"))
+	keys.ExpandGap = key.NewBinding(key.WithKeys("E"))
+	keys.ToggleFullFile = key.NewBinding(key.WithKeys("f"))
+
+	m := New(keys, false)
+	m.SetFocused(true)
+	diffs, lines := expandFixture()
+	m.SetDiff("a.go", diffs)
+	m.SetSize(160, 40)
+	return m, lines
+}
+
+func TestExpandRequestsFileLines(t *testing.T) {
+	m, lines := newExpandModel(t)
+	m.cursor = 1 // hunk header
+
+	m, cmd := m.Update(keyPress("<"))
+	if cmd == nil {
+		t.Fatal("expected a request for the file contents")
+	}
+	if msg, ok := cmd().(types.FileLinesRequestMsg); !ok || msg.Path != "a.go" {
+		t.Fatalf("got %#v, want FileLinesRequestMsg for a.go", cmd())
+	}
+	if got := m.totalLines(); got != 10 {
+		t.Fatalf("nothing should be revealed before the lines load, got %d lines", got)
+	}
+
+	m.SetFileLines("a.go", lines)
+	if got := m.lineAt(1).NewNum; got != 2 {
+		t.Errorf("first revealed line = %d, want 2", got)
+	}
+	if line := m.lineAt(m.cursor); line == nil || line.Type != diff.LineHunkHeader {
+		t.Errorf("cursor should stay on the hunk header, got %+v", line)
+	}
+
+	m, cmd = m.Update(keyPress("<"))
+	if cmd != nil {
+		t.Error("loaded contents should not be requested again")
+	}
+	if got := m.lineAt(1).NewNum; got != 1 {
+		t.Errorf("gap above should be fully revealed, first line = %d", got)
+	}
+}
+
+func TestExpandBelowShowsFold(t *testing.T) {
+	m, lines := newExpandModel(t)
+	m.cursor = 1
+	m, _ = m.Update(keyPress(">"))
+	m.SetFileLines("a.go", lines)
+
+	// header, 9 hunk lines, then lines 19-28
+	if got := m.lineAt(10); got == nil || got.NewNum != 19 || got.OldNum != 19 {
+		t.Fatalf("line after hunk = %+v, want line 19", got)
+	}
+
+	// Expanding above from inside the tail gap reveals the end of the file
+	m.cursor = 12
+	m, _ = m.Update(keyPress("<"))
+	if got := m.lineAt(m.cursor).NewNum; got != 21 {
+		t.Errorf("cursor moved to line %d, want 21", got)
+	}
+	view := ansi.Strip(m.viewport.GetContent())
+	if !strings.Contains(view, "22 lines hidden") {
+		t.Error("expected a fold marker for the lines still hidden")
+	}
+	if got := m.lineAt(m.totalLines() - 1).NewNum; got != 60 {
+		t.Errorf("last line = %d, want 60", got)
+	}
+}
+
+func TestFullFileToggle(t *testing.T) {
+	m, lines := newExpandModel(t)
+	m.cursor = 6 // added line
+	m, _ = m.Update(keyPress("f"))
+	m.SetFileLines("a.go", lines)
+
+	if got := m.totalLines(); got != 1+9+11+42 {
+		t.Errorf("full file shows %d lines, want 63", got)
+	}
+	if line := m.lineAt(m.cursor); line == nil || line.Type != diff.LineAdded {
+		t.Errorf("cursor should stay on the added line, got %+v", line)
+	}
+
+	m, _ = m.Update(keyPress("f"))
+	if got := m.totalLines(); got != 10 {
+		t.Errorf("toggling back shows %d lines, want 10", got)
+	}
+}
+
+func TestExpandedContextIsNotStaged(t *testing.T) {
+	m, lines := newExpandModel(t)
+	m.cursor = 1
+	m, _ = m.Update(keyPress("E"))
+	m.SetFileLines("a.go", lines)
+
+	m.cursor = 3 // revealed line 3
+	if info := m.GetHunkStagingInfo(); info != nil {
+		t.Errorf("expanded context should not resolve to a hunk, got %+v", info)
+	}
+
+	m.visualMode = true
+	m.selectStart, m.selectEnd = 1, m.totalLines()-1
+	infos := m.GetLineStagingInfo()
+	if len(infos) != 1 || len(infos[0].LineIndices) != 2 {
+		t.Fatalf("got %+v, want only the two changed lines", infos)
+	}
+	if len(infos[0].Hunk.Lines) != 9 {
+		t.Errorf("hunk grew to %d lines, expanded context leaked in", len(infos[0].Hunk.Lines))
+	}
+}
+
+func TestReloadKeepsExpansion(t *testing.T) {
+	m, lines := newExpandModel(t)
+	m.cursor = 1
+	m, _ = m.Update(keyPress("E"))
+	m.SetFileLines("a.go", lines)
+
+	diffs, _ := expandFixture()
+	m.SetDiff("a.go", diffs)
+	if paths := m.PendingFileLines(); len(paths) != 1 || paths[0] != "a.go" {
+		t.Fatalf("reload should re-request a.go, got %v", paths)
+	}
+	m.SetFileLines("a.go", lines)
+	if got := m.lineAt(1).NewNum; got != 1 {
+		t.Errorf("revealed gap lost on reload, first line = %d", got)
+	}
+
+	m.SetDiff("b.go", diffs)
+	if paths := m.PendingFileLines(); len(paths) != 0 {
+		t.Errorf("switching files should drop expansions, got %v", paths)
+	}
+}
@@ -2,8 +2,8 @@ package diffview
 
 import (
 	"fmt"
+	"image/color"
 	"strings"
-	"unicode/utf8"
 
 	"charm.land/bubbles/v2/key"
 	"charm.land/bubbles/v2/viewport"
@@ -11,6 +11,7 @@ import (
 	"charm.land/lipgloss/v2"
 	"github.com/Danny-Dasilva/gdiff/internal/types"
 	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+	"github.com/Danny-Dasilva/gdiff/pkg/syntax"
 )
 
 type Model struct {
@@ -34,6 +35,60 @@ type Model struct {
 	charStart  int
 	charCursor int
 
+	// unified renders old and new lines inline instead of side by side;
+	// once layoutChosen is set by a toggle it overrides the narrow-pane
+	// fallback
+	unified      bool
+	layoutChosen bool
+
+	// wrap soft-wraps long lines; otherwise hscroll is the number of
+	// characters scrolled off the left edge
+	wrap    bool
+	hscroll int
+
+	// syntaxCache holds per-file syntax tokens, reset whenever the diff changes
+	syntaxCache map[int][][][]syntax.Token
+
+	// charCache holds per-file, per-hunk character changes, reset with
+	// syntaxCache
+	charCache map[int][]charBlock
+
+	// expansions holds the context revealed around each file's hunks,
+	// keyed by path
+	expansions map[string]*expansion
+
+	// moves are the moved blocks of the diff; moveEnds maps each moved
+	// line to its place in a move
+	moves    []diff.Move
+	moveEnds map[diff.LineRef]moveEnd
+
+	// wordMode selects which files are rendered as word diffs; wordExts
+	// holds the extensions word-diffed in auto mode. wordHidden marks the
+	// removed lines of the last render that were folded into a word diff.
+	wordMode   wordDiffMode
+	wordExts   map[string]bool
+	wordHidden map[int]bool
+
+	// picks are the hunks marked with m; conflicts are hunks of an opened
+	// patch that did not apply
+	picks     []HunkStagingInfo
+	conflicts []Conflict
+
+	// origins, set in the three-way view, give the provenance of each
+	// line of the single file shown, by hunk and line
+	origins [][]diff.Origin
+
+	// pendingKey holds the first key of a two-key motion such as ]f or [c
+	pendingKey string
+
+	// Row bookkeeping from the last render: lineRows maps each flattened
+	// line to its rendered row, rowFiles maps rows to file indices and
+	// fileRows holds the header row of each file
+	lineRows  []int
+	rowFiles  []int
+	fileRows  []int
+	totalRows int
+
 	colorblind bool
 
 	headerStyle    lipgloss.Style
@@ -44,6 +99,7 @@ type Model struct {
 	lineNumStyle   lipgloss.Style
 	selectedStyle  lipgloss.Style
 	separatorStyle lipgloss.Style
+	stickyStyle    lipgloss.Style
 
 	addedHighlightBg   string
 	removedHighlightBg string
@@ -106,19 +162,150 @@ func New(keyMap types.KeyMap, colorblind bool) Model {
 		addedMarkerColor:   addedFg,
 		removedMarkerColor: removedFg,
 		separatorStyle:     lipgloss.NewStyle().Foreground(lipgloss.Color("238")),
+		stickyStyle: lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("39")).
+			Background(lipgloss.Color("237")),
 	}
 }
 
 func (m *Model) SetDiff(path string, diffs []diff.FileDiff) {
+	m.origins = nil
+	m.setDiff(path, diffs)
+}
+
+func (m *Model) setDiff(path string, diffs []diff.FileDiff) {
+	reload := path == m.path && len(m.diffs) > 0
 	m.path = path
 	m.diffs = diffs
-	m.cursor = 0
+	m.syntaxCache = nil
+	m.charCache = nil
+	m.indexMoves()
 	m.hunkIndex = 0
 	m.lineIndex = 0
 	m.visualMode = false
+	m.charMode = false
+	m.pendingKey = ""
+	if reload {
+		// Reloading the same diff (e.g. after staging): keep the cursor
+		// near where it was instead of jumping back to the top
+		m.resetExpansions()
+		m.cursor = max(0, min(m.cursor, m.totalLines()-1))
+	} else {
+		m.cursor = 0
+		m.hscroll = 0
+		m.expansions = nil
+		m.viewport.SetYOffset(0)
+	}
+	m.updateViewportContent()
+	if reload {
+		m.skipHidden(m.cursor)
+		m.syncViewport()
+	}
+}
+
+// CursorPath returns the path of the file under the cursor
+func (m Model) CursorPath() string {
+	pos, ok := m.locate(m.cursor)
+	if !ok {
+		return m.path
+	}
+	return m.diffs[pos.file].NewPath
+}
+
+// JumpToFile moves the cursor to the header of the given file.
+// Returns false if the file is not part of the current diff.
+func (m *Model) JumpToFile(path string) bool {
+	for fi, fd := range m.diffs {
+		if fd.NewPath == path || fd.OldPath == path {
+			m.cursor = m.fileStart(fi)
+			m.syncViewport()
+			return true
+		}
+	}
+	return false
+}
+
+// minSideBySideWidth is the narrowest pane that still renders side by side;
+// below it the view falls back to the unified layout
+const minSideBySideWidth = 80
+
+// ToggleLayout switches between the side-by-side and unified layouts. The
+// choice sticks, even when the pane is narrower than minSideBySideWidth.
+func (m *Model) ToggleLayout() {
+	m.unified = !m.isUnified()
+	m.layoutChosen = true
+	m.updateViewportContent()
+	m.syncViewport()
+}
+
+// ToggleWrap switches between soft-wrapping and horizontally scrolling
+// long lines
+func (m *Model) ToggleWrap() {
+	m.wrap = !m.wrap
+	m.hscroll = 0
+	m.updateViewportContent()
+	m.syncViewport()
+}
+
+// Wrap reports whether long lines are soft-wrapped
+func (m Model) Wrap() bool {
+	return m.wrap
+}
+
+// scrollHorizontal shifts the view by delta characters; ignored while wrapping
+func (m *Model) scrollHorizontal(delta int) {
+	if m.wrap {
+		return
+	}
+	m.hscroll = max(0, m.hscroll+delta)
 	m.updateViewportContent()
 }
 
+// contentWidth returns the number of characters of a line shown per row
+func (m Model) contentWidth() int {
+	if m.isUnified() {
+		return max(m.width-unifiedGutterWidth, 10)
+	}
+	return max(m.width/2, 20) - 8
+}
+
+// keepCharVisible scrolls horizontally so the character cursor stays on screen
+func (m *Model) keepCharVisible() {
+	if m.wrap {
+		return
+	}
+	width := m.contentWidth()
+	if m.charCursor < m.hscroll {
+		m.hscroll = m.charCursor
+	} else if m.charCursor >= m.hscroll+width {
+		m.hscroll = m.charCursor - width + 1
+	}
+}
+
+// isUnified reports whether the unified layout is in effect, either by
+// choice or because the pane is too narrow for two columns
+func (m Model) isUnified() bool {
+	if m.origins != nil {
+		return true
+	}
+	if m.layoutChosen {
+		return m.unified
+	}
+	return m.unified || m.width < minSideBySideWidth
+}
+
+// LayoutName describes the layout in effect
+func (m Model) LayoutName() string {
+	if m.isUnified() {
+		if !m.unified {
+			return "unified (narrow pane)"
+		}
+		return "unified"
+	}
+	return "side-by-side"
+}
+
 func (m *Model) SetSize(width, height int) {
 	m.width = width
 	m.height = height
@@ -141,10 +328,26 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	}
 
 	var cmd tea.Cmd
+	prevFile := m.cursorFile()
+	prevCursor := m.cursor
 
 	switch msg := msg.(type) {
 	case tea.KeyPressMsg:
+		if m.pendingKey != "" {
+			m.handlePendingKey(msg.String())
+			break
+		}
+
 		switch {
+		case key.Matches(msg, m.keyMap.NextChange):
+			m.pendingKey = "]"
+
+		case key.Matches(msg, m.keyMap.PrevChange):
+			m.pendingKey = "["
+
+		case key.Matches(msg, m.keyMap.ScrollSideways):
+			m.pendingKey = "z"
+
 		case key.Matches(msg, m.keyMap.Down):
 			m.moveCursor(1)
 			m.syncViewport()
@@ -185,6 +388,23 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			m.prevHunk()
 			m.syncViewport()
 
+		case key.Matches(msg, m.keyMap.JumpMove):
+			if m.jumpMove() {
+				m.syncViewport()
+			}
+
+		case key.Matches(msg, m.keyMap.ExpandAbove):
+			cmd = m.expand(expandAbove)
+
+		case key.Matches(msg, m.keyMap.ExpandBelow):
+			cmd = m.expand(expandBelow)
+
+		case key.Matches(msg, m.keyMap.ExpandGap):
+			cmd = m.expand(expandGap)
+
+		case key.Matches(msg, m.keyMap.ToggleFullFile):
+			cmd = m.expand(expandFull)
+
 		case key.Matches(msg, m.keyMap.VisualMode):
 			m.toggleVisualMode()
 
@@ -199,11 +419,15 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		case key.Matches(msg, m.keyMap.Right):
 			if m.charMode {
 				m.moveCharCursor(1)
+				m.keepCharVisible()
+				m.updateViewportContent()
 			}
 
 		case key.Matches(msg, m.keyMap.Left):
 			if m.charMode {
 				m.moveCharCursor(-1)
+				m.keepCharVisible()
+				m.updateViewportContent()
 			}
 
 		default:
@@ -221,9 +445,61 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		}
 	}
 
+	if m.skipHidden(prevCursor) {
+		m.syncViewport()
+	}
+
+	if file := m.cursorFile(); file != prevFile && file >= 0 {
+		path := m.diffs[file].NewPath
+		cmd = tea.Batch(cmd, func() tea.Msg {
+			return types.CursorFileChangedMsg{Path: path}
+		})
+	}
+
 	return m, cmd
 }
 
+// hscrollStep is the number of characters zh/zl scroll by
+const hscrollStep = 8
+
+// handlePendingKey completes a two-key motion started with ], [ or z
+func (m *Model) handlePendingKey(k string) {
+	prefix := m.pendingKey
+	m.pendingKey = ""
+
+	switch prefix + k {
+	case "zh":
+		m.scrollHorizontal(-hscrollStep)
+		return
+	case "zl":
+		m.scrollHorizontal(hscrollStep)
+		return
+	case "zH":
+		m.scrollHorizontal(-m.contentWidth() / 2)
+		return
+	case "zL":
+		m.scrollHorizontal(m.contentWidth() / 2)
+		return
+	case "z0":
+		m.scrollHorizontal(-m.hscroll)
+		return
+	case "]f":
+		m.nextFile()
+	case "[f":
+		m.prevFile()
+	case "]c":
+		m.nextChange()
+	case "[c":
+		m.prevChange()
+	default:
+		return
+	}
+	if m.visualMode {
+		m.selectEnd = m.cursor
+	}
+	m.syncViewport()
+}
+
 func (m *Model) moveCursor(delta int) {
 	total := m.totalLines()
 	if total == 0 {
@@ -251,15 +527,16 @@ func (m *Model) syncViewport() {
 		margin = m.viewport.Height() / 2
 	}
 
-	if m.cursor < m.viewport.YOffset()+margin {
-		offset := m.cursor - margin
+	cursorRow := m.cursorRow()
+	if cursorRow < m.viewport.YOffset()+margin {
+		offset := cursorRow - margin
 		if offset < 0 {
 			offset = 0
 		}
 		m.viewport.SetYOffset(offset)
-	} else if m.cursor >= m.viewport.YOffset()+m.viewport.Height()-margin {
-		offset := m.cursor - m.viewport.Height() + margin + 1
-		total := m.totalLines()
+	} else if cursorRow >= m.viewport.YOffset()+m.viewport.Height()-margin {
+		offset := cursorRow - m.viewport.Height() + margin + 1
+		total := m.totalRows
 		if offset > total-m.viewport.Height() {
 			offset = total - m.viewport.Height()
 		}
@@ -271,45 +548,203 @@ func (m *Model) syncViewport() {
 	m.updateViewportContent()
 }
 
-func (m *Model) totalLines() int {
-	count := 0
-	for _, fd := range m.diffs {
-		count++ // File header
-		for _, hunk := range fd.Hunks {
-			count += len(hunk.Lines)
-		}
+// cursorRow returns the rendered row of the cursor line
+func (m Model) cursorRow() int {
+	if m.cursor >= 0 && m.cursor < len(m.lineRows) {
+		return m.lineRows[m.cursor]
 	}
-	return count
+	return m.cursor
 }
 
-func (m *Model) nextHunk() {
-	lineNum := 0
-	for _, fd := range m.diffs {
-		lineNum++ // File header
-		for _, hunk := range fd.Hunks {
-			// Find first line of next hunk after cursor
-			if lineNum > m.cursor {
-				m.cursor = lineNum
+// linePos locates a flattened line index within the diff. hunk and line
+// are -1 for file header lines. For expanded context (extra) hunk is the
+// gap index: the gap before that hunk, or after the last one.
+type linePos struct {
+	file  int
+	hunk  int
+	line  int
+	extra bool
+}
+
+// segment is a run of flattened lines: a file header, a hunk, or the
+// expanded context of a gap
+type segment struct {
+	file  int
+	hunk  int
+	extra bool
+	start int
+	n     int
+}
+
+// walk calls fn for every segment in display order until fn returns false
+func (m Model) walk(fn func(seg segment) bool) {
+	n := 0
+	for fi, fd := range m.diffs {
+		if !fn(segment{file: fi, hunk: -1, start: n, n: 1}) {
+			return
+		}
+		n++
+		for g := 0; g <= len(fd.Hunks); g++ {
+			if extra := m.extraLines(fi, g); len(extra) > 0 {
+				if !fn(segment{file: fi, hunk: g, extra: true, start: n, n: len(extra)}) {
+					return
+				}
+				n += len(extra)
+			}
+			if g == len(fd.Hunks) {
+				break
+			}
+			lines := len(fd.Hunks[g].Lines)
+			if !fn(segment{file: fi, hunk: g, start: n, n: lines}) {
 				return
 			}
-			lineNum += len(hunk.Lines)
+			n += lines
+		}
+	}
+}
+
+// locate maps a flattened line index to its file, hunk and hunk line
+func (m Model) locate(index int) (linePos, bool) {
+	var pos linePos
+	found := false
+	m.walk(func(seg segment) bool {
+		if index < seg.start || index >= seg.start+seg.n {
+			return true
+		}
+		pos = linePos{file: seg.file, hunk: seg.hunk, line: index - seg.start, extra: seg.extra}
+		if seg.hunk < 0 {
+			pos.line = -1
+		}
+		found = true
+		return false
+	})
+	return pos, found
+}
+
+// lineAt returns the diff line at a flattened index, or nil for headers
+func (m Model) lineAt(index int) *diff.Line {
+	pos, ok := m.locate(index)
+	if !ok || pos.hunk < 0 {
+		return nil
+	}
+	if pos.extra {
+		return &m.extraLines(pos.file, pos.hunk)[pos.line]
+	}
+	return &m.diffs[pos.file].Hunks[pos.hunk].Lines[pos.line]
+}
+
+// hunkAt returns the position of a line inside a real hunk; file headers
+// and expanded context are rejected
+func (m Model) hunkAt(index int) (linePos, bool) {
+	pos, ok := m.locate(index)
+	if !ok || pos.hunk < 0 || pos.extra {
+		return linePos{}, false
+	}
+	return pos, true
+}
+
+// fileStart returns the flattened index of a file's header line
+func (m Model) fileStart(file int) int {
+	start := m.totalLines()
+	m.walk(func(seg segment) bool {
+		if seg.file == file {
+			start = seg.start
+			return false
+		}
+		return true
+	})
+	return start
+}
+
+// cursorFile returns the index of the file under the cursor, or -1
+func (m Model) cursorFile() int {
+	pos, ok := m.locate(m.cursor)
+	if !ok {
+		return -1
+	}
+	return pos.file
+}
+
+func (m *Model) nextFile() {
+	if file := m.cursorFile(); file >= 0 && file+1 < len(m.diffs) {
+		m.cursor = m.fileStart(file + 1)
+	}
+}
+
+func (m *Model) prevFile() {
+	file := m.cursorFile()
+	if file < 0 {
+		return
+	}
+	if start := m.fileStart(file); m.cursor > start || file == 0 {
+		m.cursor = start
+	} else {
+		m.cursor = m.fileStart(file - 1)
+	}
+}
+
+// isChangeStart reports whether a line begins a block of added/removed lines
+func (m Model) isChangeStart(index int) bool {
+	line := m.lineAt(index)
+	if line == nil || (line.Type != diff.LineAdded && line.Type != diff.LineRemoved) {
+		return false
+	}
+	prev := m.lineAt(index - 1)
+	return prev == nil || (prev.Type != diff.LineAdded && prev.Type != diff.LineRemoved)
+}
+
+func (m *Model) nextChange() {
+	total := m.totalLines()
+	for i := m.cursor + 1; i < total; i++ {
+		if m.isChangeStart(i) {
+			m.cursor = i
+			return
 		}
 	}
 }
 
+func (m *Model) prevChange() {
+	for i := m.cursor - 1; i >= 0; i-- {
+		if m.isChangeStart(i) {
+			m.cursor = i
+			return
+		}
+	}
+}
+
+func (m *Model) totalLines() int {
+	count := 0
+	m.walk(func(seg segment) bool {
+		count += seg.n
+		return true
+	})
+	return count
+}
+
+func (m *Model) nextHunk() {
+	m.walk(func(seg segment) bool {
+		// Find first line of next hunk after cursor
+		if seg.hunk >= 0 && !seg.extra && seg.start > m.cursor {
+			m.cursor = seg.start
+			return false
+		}
+		return true
+	})
+}
+
 func (m *Model) prevHunk() {
-	lineNum := 0
-	lastHunkStart := 0
-	for _, fd := range m.diffs {
-		lineNum++ // File header
-		for _, hunk := range fd.Hunks {
-			if lineNum >= m.cursor {
-				m.cursor = lastHunkStart
-				return
-			}
-			lastHunkStart = lineNum
-			lineNum += len(hunk.Lines)
+	target := -1
+	m.walk(func(seg segment) bool {
+		if seg.start >= m.cursor {
+			return false
 		}
+		if seg.hunk >= 0 && !seg.extra {
+			target = seg.start
+		}
+		return true
+	})
+	if target >= 0 {
+		m.cursor = target
 	}
 }
 
@@ -323,6 +758,7 @@ func (m *Model) toggleVisualMode() {
 			m.charMode = true
 			m.charStart = 0
 			m.charCursor = 0
+			m.keepCharVisible()
 		}
 	} else {
 		m.charMode = false
@@ -332,6 +768,12 @@ func (m *Model) toggleVisualMode() {
 }
 
 func (m *Model) updateViewportContent() {
+	m.lineRows = m.lineRows[:0]
+	m.rowFiles = m.rowFiles[:0]
+	m.fileRows = m.fileRows[:0]
+	m.totalRows = 0
+	m.wordHidden = nil
+
 	if len(m.diffs) == 0 {
 		m.viewport.SetContent(m.contextStyle.Render("No diff to display"))
 		return
@@ -339,58 +781,155 @@ func (m *Model) updateViewportContent() {
 
 	var b strings.Builder
 	lineNum := 0
+	fileIdx := 0
+
+	// emitRow writes rendered content and records which file its rows belong to
+	emitRow := func(content string) {
+		b.WriteString(content)
+		b.WriteString("\n")
+		for n := strings.Count(content, "\n") + 1; n > 0; n-- {
+			m.rowFiles = append(m.rowFiles, fileIdx)
+		}
+		m.totalRows = len(m.rowFiles)
+	}
+	// markLine records the row where a flattened line is rendered
+	markLine := func(row int) {
+		m.lineRows = append(m.lineRows, row)
+	}
 
 	halfWidth := m.width / 2
 	if halfWidth < 20 {
 		halfWidth = 20
 	}
-	gutterWidth := 6
-	contentWidth := halfWidth - gutterWidth - 2
+	contentWidth := m.contentWidth()
 	divider := m.separatorStyle.Render("\u2502")
 
-	for _, fd := range m.diffs {
+	for fi, fd := range m.diffs {
+		fileIdx = fi
+		// Word diffs interleave deletions with the new text, so they are
+		// always laid out inline
+		words := m.wordDiff(fi)
+		unified := m.isUnified() || words
+		m.fileRows = append(m.fileRows, m.totalRows)
+		markLine(m.totalRows)
 		header := fmt.Sprintf("--- %s\n+++ %s", fd.OldPath, fd.NewPath)
 		if m.isLineSelected(lineNum) {
-			b.WriteString(m.selectedStyle.Render(m.headerStyle.Render(header)))
+			emitRow(m.selectedStyle.Render(m.headerStyle.Render(header)))
 		} else {
-			b.WriteString(m.headerStyle.Render(header))
+			emitRow(m.headerStyle.Render(header))
 		}
-		b.WriteString("\n")
 		lineNum++
 
 		if fd.IsBinary {
-			b.WriteString(m.contextStyle.Render("Binary file differs"))
-			b.WriteString("\n")
+			emitRow(m.contextStyle.Render("Binary file differs"))
 			continue
 		}
 
-		for _, hunk := range fd.Hunks {
-			lines := hunk.Lines
+		fileTokens := m.fileSyntax(fi)
+		for hi := 0; hi <= len(fd.Hunks); hi++ {
+			extraTokens := m.extraTokens(fi, hi)
+			for j, line := range m.extraLines(fi, hi) {
+				if j > 0 {
+					if hidden := line.NewNum - m.extraLines(fi, hi)[j-1].NewNum - 1; hidden > 0 {
+						emitRow(m.renderFold(hidden))
+					}
+				}
+				d := m.decorFor(lineNum, nil, extraTokens[j])
+				var row string
+				if unified {
+					row = m.renderLineHighlighted(line, d)
+				} else {
+					left := m.renderSBSSideRows(line.OldNum, " ", line.Content, contentWidth, m.contextStyle, d)
+					right := m.renderSBSSideRows(line.NewNum, " ", line.Content, contentWidth, m.contextStyle, d)
+					row = m.joinSBS(left, right, divider, contentWidth)
+				}
+				if m.isLineSelected(lineNum) {
+					row = m.selectedStyle.Render(row)
+				}
+				markLine(m.totalRows)
+				emitRow(row)
+				lineNum++
+			}
+			if hi == len(fd.Hunks) {
+				break
+			}
+
+			lines := fd.Hunks[hi].Lines
+			changes, partner := m.hunkCharChanges(fi, hi)
+			tokens := make([][]syntax.Token, len(lines))
+			if fileTokens != nil {
+				tokens = fileTokens[hi]
+			}
+
+			if unified {
+				var deleted [][]diff.WordDeletion
+				var hidden []bool
+				if words {
+					changes, deleted, hidden = m.wordBlocks(fi, hi)
+				}
+				for j, line := range lines {
+					if hidden != nil && hidden[j] {
+						// Shown within the added lines that follow
+						if m.wordHidden == nil {
+							m.wordHidden = make(map[int]bool)
+						}
+						m.wordHidden[lineNum] = true
+						markLine(m.totalRows)
+						lineNum++
+						continue
+					}
+					if label := m.moveLabel(fi, hi, j); label != "" {
+						emitRow(label)
+					}
+					d := m.decorFor(lineNum, changes[j], tokens[j])
+					m.moveDecor(&d, fi, hi, j)
+					m.originDecor(&d, hi, j)
+					if deleted != nil {
+						line = wordDecor(line, &d, deleted[j])
+					}
+					row := m.pickMark(m.renderLineHighlighted(line, d), line, fi, hi)
+					if m.isLineSelected(lineNum) {
+						row = m.selectedStyle.Render(row)
+					}
+					markLine(m.totalRows)
+					emitRow(row)
+					if label := m.conflictLabel(line, fi, hi); label != "" {
+						emitRow(label)
+					}
+					lineNum++
+				}
+				continue
+			}
+
 			i := 0
 			for i < len(lines) {
 				line := lines[i]
 
 				if line.Type == diff.LineHunkHeader {
-					hunkContent := m.renderHunkHeaderSBS(line, halfWidth)
+					hunkContent := m.pickMark(m.renderHunkHeaderSBS(line, halfWidth), line, fi, hi)
 					if m.isLineSelected(lineNum) {
 						hunkContent = m.selectedStyle.Render(hunkContent)
 					}
-					b.WriteString(hunkContent)
-					b.WriteString("\n")
+					markLine(m.totalRows)
+					emitRow(hunkContent)
+					if label := m.conflictLabel(line, fi, hi); label != "" {
+						emitRow(label)
+					}
 					lineNum++
 					i++
 					continue
 				}
 
 				if line.Type == diff.LineContext {
-					left := m.renderSBSSide(line.OldNum, " ", line.Content, contentWidth, m.contextStyle)
-					right := m.renderSBSSide(line.NewNum, " ", line.Content, contentWidth, m.contextStyle)
-					row := left + divider + right
+					d := m.decorFor(lineNum, nil, tokens[i])
+					left := m.renderSBSSideRows(line.OldNum, " ", line.Content, contentWidth, m.contextStyle, d)
+					right := m.renderSBSSideRows(line.NewNum, " ", line.Content, contentWidth, m.contextStyle, d)
+					row := m.joinSBS(left, right, divider, contentWidth)
 					if m.isLineSelected(lineNum) {
 						row = m.selectedStyle.Render(row)
 					}
-					b.WriteString(row)
-					b.WriteString("\n")
+					markLine(m.totalRows)
+					emitRow(row)
 					lineNum++
 					i++
 					continue
@@ -398,64 +937,57 @@ func (m *Model) updateViewportContent() {
 
 				var removed, added []diff.Line
 				removedStart := lineNum
+				removedIdx := i
 				for i < len(lines) && lines[i].Type == diff.LineRemoved {
 					removed = append(removed, lines[i])
 					i++
 					lineNum++
 				}
 				addedStart := lineNum
+				addedIdx := i
 				for i < len(lines) && lines[i].Type == diff.LineAdded {
 					added = append(added, lines[i])
 					i++
 					lineNum++
 				}
 
-				pairCount := min(len(removed), len(added))
-				type charDiffPair struct {
-					oldChanges []diff.CharChange
-					newChanges []diff.CharChange
-				}
-				charDiffs := make([]charDiffPair, pairCount)
-				for j := 0; j < pairCount; j++ {
-					old, new := diff.ComputeCharDiff(removed[j].Content, added[j].Content)
-					charDiffs[j] = charDiffPair{old, new}
+				// Moves starting inside the block are announced above it
+				for j := removedIdx; j < i; j++ {
+					if label := m.moveLabel(fi, hi, j); label != "" {
+						emitRow(label)
+					}
 				}
 
-				maxLen := max(len(removed), len(added))
-				for j := 0; j < maxLen; j++ {
-					var left, right string
-					rowLineNum := -1
-					if j < len(removed) {
-						var changes []diff.CharChange
-						if j < pairCount {
-							changes = charDiffs[j].oldChanges
+				// A paired row holds two flattened lines, so selection is
+				// shown on each side independently. Wrapped pairs take as
+				// many rows as the longer side.
+				lineRow := make([]int, len(removed)+len(added))
+				for _, r := range alignRows(removedIdx, addedIdx, i, partner) {
+					var left, right []string
+					if k := r[0]; k >= 0 {
+						j := k - removedIdx
+						d := m.decorFor(removedStart+j, changes[k], tokens[k])
+						m.moveDecor(&d, fi, hi, k)
+						left = m.renderSBSSideRows(removed[j].OldNum, "-", removed[j].Content, contentWidth, m.removedStyle, d)
+						if m.isLineSelected(removedStart + j) {
+							left = m.selectRows(left)
 						}
-						left = m.renderSBSSideHighlighted(removed[j].OldNum, "-", removed[j].Content, contentWidth, m.removedStyle, changes)
-						rowLineNum = removedStart + j
-					} else {
-						left = m.renderSBSEmpty(contentWidth)
+						lineRow[j] = m.totalRows
 					}
-					if j < len(added) {
-						var changes []diff.CharChange
-						if j < pairCount {
-							changes = charDiffs[j].newChanges
-						}
-						right = m.renderSBSSideHighlighted(added[j].NewNum, "+", added[j].Content, contentWidth, m.addedStyle, changes)
-						if rowLineNum < 0 {
-							rowLineNum = addedStart + j
-						}
-					} else {
-						right = m.renderSBSEmpty(contentWidth)
-						if rowLineNum < 0 {
-							rowLineNum = removedStart + j
+					if k := r[1]; k >= 0 {
+						j := k - addedIdx
+						d := m.decorFor(addedStart+j, changes[k], tokens[k])
+						m.moveDecor(&d, fi, hi, k)
+						right = m.renderSBSSideRows(added[j].NewNum, "+", added[j].Content, contentWidth, m.addedStyle, d)
+						if m.isLineSelected(addedStart + j) {
+							right = m.selectRows(right)
 						}
+						lineRow[len(removed)+j] = m.totalRows
 					}
-					row := left + divider + right
-					if rowLineNum >= 0 && m.isLineSelected(rowLineNum) {
-						row = m.selectedStyle.Render(row)
-					}
-					b.WriteString(row)
-					b.WriteString("\n")
+					emitRow(m.joinSBS(left, right, divider, contentWidth))
+				}
+				for _, row := range lineRow {
+					markLine(row)
 				}
 			}
 		}
@@ -464,6 +996,77 @@ func (m *Model) updateViewportContent() {
 	m.viewport.SetContent(b.String())
 }
 
+// charBlock is the cached result of blockCharChanges for one hunk
+type charBlock struct {
+	changes [][]diff.CharChange
+	partner []int
+}
+
+// hunkCharChanges returns blockCharChanges for a hunk, computing and caching
+// the changes of the whole file on first use
+func (m *Model) hunkCharChanges(file, hunk int) ([][]diff.CharChange, []int) {
+	blocks, ok := m.charCache[file]
+	if !ok {
+		if m.charCache == nil {
+			m.charCache = make(map[int][]charBlock)
+		}
+		tokenizer := m.fileTokenizer(file)
+		hunks := m.diffs[file].Hunks
+		blocks = make([]charBlock, len(hunks))
+		for hi, h := range hunks {
+			blocks[hi].changes, blocks[hi].partner = blockCharChanges(h.Lines, tokenizer)
+		}
+		m.charCache[file] = blocks
+	}
+	return blocks[hunk].changes, blocks[hunk].partner
+}
+
+// blockCharChanges computes character-level changes for every line of a
+// hunk, aligning each run of removed lines with the added run that follows
+// it. partner holds the index of the line each line is paired with, or -1;
+// unpaired lines get no changes.
+func blockCharChanges(lines []diff.Line, tokenizer diff.Tokenizer) (changes [][]diff.CharChange, partner []int) {
+	changes = make([][]diff.CharChange, len(lines))
+	partner = make([]int, len(lines))
+	for i := range partner {
+		partner[i] = -1
+	}
+	for _, p := range diff.PairAdjacentLines(lines) {
+		old, new := diff.ComputeCharDiffWith(lines[p[0]].Content, lines[p[1]].Content, tokenizer)
+		changes[p[0]], changes[p[1]] = old, new
+		partner[p[0]], partner[p[1]] = p[1], p[0]
+	}
+	return changes, partner
+}
+
+// alignRows lays out a block of removed lines at removedIdx and the added
+// lines at addedIdx as side-by-side rows of hunk line indices, -1 for an
+// empty side
+func alignRows(removedIdx, addedIdx, end int, partner []int) [][2]int {
+	var pairs [][2]int
+	for k := removedIdx; k < addedIdx; k++ {
+		if p := partner[k]; p >= addedIdx && p < end {
+			pairs = append(pairs, [2]int{k - removedIdx, p - addedIdx})
+		}
+	}
+	rows := diff.AlignBlock(addedIdx-removedIdx, end-addedIdx, pairs)
+	for k := range rows {
+		if rows[k][0] >= 0 {
+			rows[k][0] += removedIdx
+		}
+		if rows[k][1] >= 0 {
+			rows[k][1] += addedIdx
+		}
+	}
+	return rows
+}
+
+// renderFold renders the marker for unchanged lines left hidden between two
+// pieces of expanded context
+func (m Model) renderFold(hidden int) string {
+	return m.lineNumStyle.Render(fmt.Sprintf("  ⋯ %d lines hidden", hidden))
+}
+
 func (m Model) renderMarker(marker string) string {
 	switch marker {
 	case "+":
@@ -475,59 +1078,252 @@ func (m Model) renderMarker(marker string) string {
 	}
 }
 
-func (m Model) renderSBSSideHighlighted(num int, marker string, content string, contentWidth int, baseStyle lipgloss.Style, changes []diff.CharChange) string {
-	numStr := fmt.Sprintf("%4d", num)
+// decor is the highlighting layered over one line's content
+type decor struct {
+	changes []diff.CharChange
+	tokens  []syntax.Token
+	// sel is the character selection [start, end) and cursor the character
+	// cursor position, or -1 when the line is not in char mode
+	sel    [2]int
+	cursor int
+	// base replaces the line's add/remove style, e.g. for moved lines
+	base *lipgloss.Style
+	// deleted are ranges of removed words spliced into an added line by
+	// word diffs, shown struck through
+	deleted []diff.CharChange
+	// tag is the provenance letter shown before the line numbers in the
+	// three-way view, "" elsewhere
+	tag string
+}
+
+// decorFor builds the decoration of a flattened line, adding the character
+// selection when it is the cursor line in char mode
+func (m Model) decorFor(lineNum int, changes []diff.CharChange, tokens []syntax.Token) decor {
+	d := decor{changes: changes, tokens: tokens, cursor: -1}
+	if m.charMode && lineNum == m.cursor {
+		d.sel = [2]int{min(m.charStart, m.charCursor), max(m.charStart, m.charCursor)}
+		d.cursor = m.charCursor
+	}
+	return d
+}
+
+// renderSBSSideRows renders one side of a side-by-side row, one string per
+// visual row; continuation rows of a wrapped line leave the gutter blank
+func (m Model) renderSBSSideRows(num int, marker string, content string, contentWidth int, baseStyle lipgloss.Style, d decor) []string {
 	separator := m.separatorStyle.Render("|")
+	if d.base != nil {
+		baseStyle = *d.base
+	}
+	rows := m.contentRows(marker, content, contentWidth, baseStyle, d)
+	for k := range rows {
+		if k == 0 {
+			rows[k] = m.lineNumStyle.Render(fmt.Sprintf("%4d", num)) + " " + separator + m.renderMarker(marker) + rows[k]
+		} else {
+			rows[k] = m.lineNumStyle.Render("    ") + " " + separator + " " + rows[k]
+		}
+	}
+	return rows
+}
 
-	runeCount := utf8.RuneCountInString(content)
-	if runeCount > contentWidth {
-		runes := []rune(content)
-		content = string(runes[:contentWidth])
-		runeCount = contentWidth
+// joinSBS zips left and right column rows, padding the shorter side
+func (m Model) joinSBS(left, right []string, divider string, contentWidth int) string {
+	n := max(len(left), len(right))
+	rows := make([]string, n)
+	for k := 0; k < n; k++ {
+		l, r := m.renderSBSEmpty(contentWidth), m.renderSBSEmpty(contentWidth)
+		if k < len(left) {
+			l = left[k]
+		}
+		if k < len(right) {
+			r = right[k]
+		}
+		rows[k] = l + divider + r
 	}
+	return strings.Join(rows, "\n")
+}
 
-	var styledContent string
-	if len(changes) == 0 {
-		padded := content + strings.Repeat(" ", contentWidth-runeCount)
-		styledContent = baseStyle.Render(padded)
-	} else {
-		var highlightStyle lipgloss.Style
-		switch marker {
-		case "-":
-			highlightStyle = baseStyle.Background(lipgloss.Color(m.removedHighlightBg)).Bold(true)
-		case "+":
-			highlightStyle = baseStyle.Background(lipgloss.Color(m.addedHighlightBg)).Bold(true)
-		default:
-			highlightStyle = baseStyle.Bold(true)
-		}
-		runes := []rune(content)
-		pos := 0
-		var buf strings.Builder
-		for _, ch := range changes {
-			start := min(ch.Start, len(runes))
-			end := min(ch.End, len(runes))
-			if pos < start {
-				buf.WriteString(baseStyle.Render(string(runes[pos:start])))
-			}
-			if start < end {
-				buf.WriteString(highlightStyle.Render(string(runes[start:end])))
-			}
-			pos = end
+func (m Model) selectRows(rows []string) []string {
+	for k := range rows {
+		rows[k] = m.selectedStyle.Render(rows[k])
+	}
+	return rows
+}
+
+// contentRows renders line content as one row when scrolling horizontally,
+// or as many contentWidth-wide rows as needed when wrapping
+func (m Model) contentRows(marker string, content string, contentWidth int, baseStyle lipgloss.Style, d decor) []string {
+	runes := []rune(content)
+	if !m.wrap {
+		return []string{m.highlightContent(marker, runes, m.hscroll, contentWidth, baseStyle, d)}
+	}
+	var rows []string
+	for from := 0; from < len(runes) || from == 0; from += contentWidth {
+		rows = append(rows, m.highlightContent(marker, runes, from, contentWidth, baseStyle, d))
+	}
+	return rows
+}
+
+// highlightContent renders contentWidth characters of a line starting at
+// rune offset from, padded with spaces, in baseStyle. Syntax tokens recolor
+// the foreground and the character ranges in changes are emphasised with a
+// stronger background, so both layer on top of the add/remove tint. The
+// character selection is shown in reverse video.
+func (m Model) highlightContent(marker string, runes []rune, from, contentWidth int, baseStyle lipgloss.Style, d decor) string {
+	from = min(from, len(runes))
+	visible := runes[from:min(from+contentWidth, len(runes))]
+	padding := strings.Repeat(" ", contentWidth-len(visible))
+
+	if len(d.changes) == 0 && len(d.tokens) == 0 && len(d.deleted) == 0 && d.cursor < 0 {
+		return baseStyle.Render(string(visible) + padding)
+	}
+
+	var highlightStyle lipgloss.Style
+	switch marker {
+	case "-":
+		highlightStyle = baseStyle.Background(lipgloss.Color(m.removedHighlightBg)).Bold(true)
+	case "+":
+		highlightStyle = baseStyle.Background(lipgloss.Color(m.addedHighlightBg)).Bold(true)
+	default:
+		highlightStyle = baseStyle.Bold(true)
+	}
+
+	// Resolve the syntax kind and emphasis of every visible rune, then
+	// render runs that share both
+	kinds := make([]syntax.Kind, len(visible))
+	for _, t := range d.tokens {
+		for i := max(t.Start-from, 0); i < min(t.End-from, len(visible)); i++ {
+			kinds[i] = t.Kind
 		}
-		if pos < len(runes) {
-			buf.WriteString(baseStyle.Render(string(runes[pos:])))
+	}
+	const (
+		plain = iota
+		changed
+		deleted
+		selected
+	)
+	emphasis := make([]int, len(visible))
+	for _, ch := range d.changes {
+		for i := max(ch.Start-from, 0); i < min(ch.End-from, len(visible)); i++ {
+			emphasis[i] = changed
 		}
-		if remaining := contentWidth - runeCount; remaining > 0 {
-			buf.WriteString(strings.Repeat(" ", remaining))
+	}
+	for _, ch := range d.deleted {
+		for i := max(ch.Start-from, 0); i < min(ch.End-from, len(visible)); i++ {
+			emphasis[i] = deleted
 		}
-		styledContent = buf.String()
 	}
+	if d.cursor >= 0 {
+		for i := max(d.sel[0]-from, 0); i < min(d.sel[1]-from, len(visible)); i++ {
+			emphasis[i] = selected
+		}
+		if c := d.cursor - from; c >= 0 && c < len(visible) {
+			emphasis[c] = selected
+		}
+	}
+
+	var buf strings.Builder
+	for start := 0; start < len(visible); {
+		end := start + 1
+		for end < len(visible) && kinds[end] == kinds[start] && emphasis[end] == emphasis[start] {
+			end++
+		}
+		style := baseStyle
+		switch emphasis[start] {
+		case changed:
+			style = highlightStyle
+		case deleted:
+			style = m.removedStyle.Background(lipgloss.Color(m.removedHighlightBg)).Strikethrough(true)
+		case selected:
+			style = style.Reverse(true)
+		}
+		if color, ok := syntaxColors[kinds[start]]; ok {
+			style = style.Foreground(color)
+			if kinds[start] == syntax.Comment {
+				style = style.Italic(true)
+			}
+		}
+		buf.WriteString(style.Render(string(visible[start:end])))
+		start = end
+	}
+	// A cursor past the last character shows as a reversed space
+	if c := d.cursor - from; c == len(visible) && padding != "" {
+		buf.WriteString(baseStyle.Reverse(true).Render(" "))
+		padding = padding[1:]
+	}
+	if padding != "" {
+		buf.WriteString(baseStyle.Render(padding))
+	}
+
+	return buf.String()
+}
 
-	return m.lineNumStyle.Render(numStr) + " " + separator + m.renderMarker(marker) + styledContent
+// syntaxColors maps token kinds to foreground colors; plain text keeps the
+// add/remove/context color
+var syntaxColors = map[syntax.Kind]color.Color{
+	syntax.Keyword:  lipgloss.Color("#cba6f7"),
+	syntax.Type:     lipgloss.Color("#f9e2af"),
+	syntax.Builtin:  lipgloss.Color("#fab387"),
+	syntax.Function: lipgloss.Color("#89b4fa"),
+	syntax.String:   lipgloss.Color("#94e2d5"),
+	syntax.Number:   lipgloss.Color("#fab387"),
+	syntax.Comment:  lipgloss.Color("#7f849c"),
 }
 
-func (m Model) renderSBSSide(num int, marker string, content string, contentWidth int, style lipgloss.Style) string {
-	return m.renderSBSSideHighlighted(num, marker, content, contentWidth, style, nil)
+// fileTokenizer returns the tokenizer for char-level diffs of a file's
+// language
+func (m Model) fileTokenizer(file int) diff.Tokenizer {
+	lang := syntax.Detect(m.diffs[file].NewPath)
+	if lang == nil {
+		return diff.DefaultTokenizer
+	}
+	return diff.TokenizerFor(lang.Name)
+}
+
+// maxHighlightLines is the largest file diff that gets syntax highlighting
+const maxHighlightLines = 5000
+
+// fileSyntax returns the syntax tokens of a file's diff lines, indexed by
+// hunk and line, computing and caching them on first use. Old and new
+// sides are lexed separately so multi-line comments and strings carry over
+// correctly; context lines follow the new side. Unknown languages and huge
+// diffs get no tokens.
+func (m *Model) fileSyntax(file int) [][][]syntax.Token {
+	if cached, ok := m.syntaxCache[file]; ok {
+		return cached
+	}
+	if m.syntaxCache == nil {
+		m.syntaxCache = make(map[int][][][]syntax.Token)
+	}
+
+	fd := m.diffs[file]
+	lang := syntax.Detect(fd.NewPath)
+	total := 0
+	for _, hunk := range fd.Hunks {
+		total += len(hunk.Lines)
+	}
+	if lang == nil || fd.IsBinary || total > maxHighlightLines {
+		m.syntaxCache[file] = nil
+		return nil
+	}
+
+	tokens := make([][][]syntax.Token, len(fd.Hunks))
+	for hi, hunk := range fd.Hunks {
+		tokens[hi] = make([][]syntax.Token, len(hunk.Lines))
+		var oldState, newState syntax.State
+		for li, line := range hunk.Lines {
+			switch line.Type {
+			case diff.LineRemoved:
+				tokens[hi][li], oldState = lang.Tokenize(line.Content, oldState)
+			case diff.LineAdded:
+				tokens[hi][li], newState = lang.Tokenize(line.Content, newState)
+			case diff.LineContext:
+				tokens[hi][li], newState = lang.Tokenize(line.Content, newState)
+				oldState = newState
+			}
+		}
+	}
+	m.syntaxCache[file] = tokens
+	return tokens
 }
 
 func (m Model) renderSBSEmpty(contentWidth int) string {
@@ -542,22 +1338,50 @@ func (m Model) renderHunkHeaderSBS(line diff.Line, halfWidth int) string {
 }
 
 func (m Model) renderLine(line diff.Line, lineNum int) string {
+	return m.renderLineHighlighted(line, decor{cursor: -1})
+}
+
+// unifiedGutterWidth is the width of the "old new | m" prefix of a unified row
+const unifiedGutterWidth = 13
+
+// renderLineHighlighted renders a line of the unified layout with old and
+// new line-number gutters, syntax and character-level highlighting. Wrapped
+// lines continue on further rows with a blank gutter.
+func (m Model) renderLineHighlighted(line diff.Line, d decor) string {
 	separator := m.separatorStyle.Render("|")
+	contentWidth := m.contentWidth()
 
+	var numStr, marker string
+	style := m.contextStyle
 	switch line.Type {
 	case diff.LineHunkHeader:
 		hunkMarker := m.hunkStyle.Render("@@")
 		return fmt.Sprintf("         %s %s %s", hunkMarker, m.hunkStyle.Render(line.Content), hunkMarker)
 	case diff.LineAdded:
-		numStr := fmt.Sprintf("%4s %4d", "", line.NewNum)
-		return m.lineNumStyle.Render(numStr) + " " + separator + " " + m.renderMarker("+") + m.addedStyle.Render(line.Content)
+		numStr, marker, style = fmt.Sprintf("%4s %4d", "", line.NewNum), "+", m.addedStyle
 	case diff.LineRemoved:
-		numStr := fmt.Sprintf("%4d %4s", line.OldNum, "")
-		return m.lineNumStyle.Render(numStr) + " " + separator + " " + m.renderMarker("-") + m.removedStyle.Render(line.Content)
+		numStr, marker, style = fmt.Sprintf("%4d %4s", line.OldNum, ""), "-", m.removedStyle
 	default:
-		numStr := fmt.Sprintf("%4d %4d", line.OldNum, line.NewNum)
-		return m.lineNumStyle.Render(numStr) + " " + separator + " " + m.renderMarker(" ") + m.contextStyle.Render(line.Content)
+		numStr, marker = fmt.Sprintf("%4d %4d", line.OldNum, line.NewNum), " "
+		d.changes = nil
+	}
+	if d.base != nil {
+		style = *d.base
+	}
+	gutter := m.lineNumStyle.Render(numStr)
+	if d.tag != "" {
+		gutter = m.tagStyle(d.tag).Render(d.tag) + m.lineNumStyle.Render(fmt.Sprintf("%4s%4s", lineNumber(line.OldNum), lineNumber(line.NewNum)))
 	}
+
+	rows := m.contentRows(marker, line.Content, contentWidth, style, d)
+	for k := range rows {
+		if k == 0 {
+			rows[k] = gutter + " " + separator + " " + m.renderMarker(marker) + rows[k]
+		} else {
+			rows[k] = m.lineNumStyle.Render(strings.Repeat(" ", len(numStr))) + " " + separator + "  " + rows[k]
+		}
+	}
+	return strings.Join(rows, "\n")
 }
 
 func (m Model) isLineSelected(lineNum int) bool {
@@ -575,7 +1399,27 @@ func (m Model) isLineSelected(lineNum int) bool {
 }
 
 func (m Model) View() string {
-	return m.viewport.View()
+	view := m.viewport.View()
+	if len(m.diffs) < 2 {
+		return view
+	}
+
+	// Keep the header of the file at the top of the viewport visible
+	top := m.viewport.YOffset()
+	if top <= 0 || top >= len(m.rowFiles) {
+		return view
+	}
+	file := m.rowFiles[top]
+	if m.fileRows[file] >= top {
+		return view
+	}
+	lines := strings.SplitN(view, "\n", 2)
+	fd := m.diffs[file]
+	header := m.stickyStyle.Width(m.width).Render(fmt.Sprintf(" %s  (%d/%d)", fd.NewPath, file+1, len(m.diffs)))
+	if len(lines) == 2 {
+		return header + "\n" + lines[1]
+	}
+	return header
 }
 
 type CharSelection struct {
@@ -602,23 +1446,11 @@ func (m Model) GetCharacterSelection() *CharSelection {
 }
 
 func (m Model) getCurrentLineContent() (string, diff.LineType) {
-	lineNum := 0
-	for _, fd := range m.diffs {
-		if lineNum == m.cursor {
-			return "", diff.LineContext // File header
-		}
-		lineNum++
-
-		for _, hunk := range fd.Hunks {
-			for _, line := range hunk.Lines {
-				if lineNum == m.cursor {
-					return line.Content, line.Type
-				}
-				lineNum++
-			}
-		}
+	line := m.lineAt(m.cursor)
+	if line == nil {
+		return "", diff.LineContext // File header
 	}
-	return "", diff.LineContext
+	return line.Content, line.Type
 }
 
 func (m Model) isCurrentLineChangeable() bool {
@@ -641,6 +1473,7 @@ func (m *Model) moveCharCursor(delta int) {
 
 // CharStagingInfo contains all info needed to stage characters
 type CharStagingInfo struct {
+	Path          string
 	Hunk          diff.Hunk
 	HunkLineIndex int // Index of line within hunk
 	CharStart     int
@@ -652,35 +1485,111 @@ func (m Model) GetCharStagingInfo() *CharStagingInfo {
 		return nil
 	}
 
-	lineNum := 0
-	for _, fd := range m.diffs {
-		lineNum++
+	pos, ok := m.hunkAt(m.cursor)
+	if !ok {
+		return nil
+	}
+	fd := m.diffs[pos.file]
+	hunk := fd.Hunks[pos.hunk]
+	line := hunk.Lines[pos.line]
+	if line.Type != diff.LineAdded && line.Type != diff.LineRemoved {
+		return nil
+	}
 
-		for _, hunk := range fd.Hunks {
-			for i, line := range hunk.Lines {
-				if lineNum == m.cursor {
-					if line.Type != diff.LineAdded && line.Type != diff.LineRemoved {
-						return nil
-					}
+	start, end := m.charStart, m.charCursor
+	if start > end {
+		start, end = end, start
+	}
 
-					start, end := m.charStart, m.charCursor
-					if start > end {
-						start, end = end, start
-					}
+	return &CharStagingInfo{
+		Path:          fd.NewPath,
+		Hunk:          hunk,
+		HunkLineIndex: pos.line,
+		CharStart:     start,
+		CharEnd:       end,
+	}
+}
 
-					return &CharStagingInfo{
-						Hunk:          hunk,
-						HunkLineIndex: i,
-						CharStart:     start,
-						CharEnd:       end,
-					}
-				}
-				lineNum++
-			}
+// HunkStagingInfo identifies a whole hunk to stage or unstage
+type HunkStagingInfo struct {
+	Path string
+	Hunk diff.Hunk
+}
+
+// GetHunkStagingInfo returns the hunk under the cursor, or nil on a file
+// header or expanded context
+func (m Model) GetHunkStagingInfo() *HunkStagingInfo {
+	pos, ok := m.hunkAt(m.cursor)
+	if !ok {
+		return nil
+	}
+	fd := m.diffs[pos.file]
+	return &HunkStagingInfo{Path: fd.NewPath, Hunk: fd.Hunks[pos.hunk]}
+}
+
+// LineStagingInfo identifies changed lines within one hunk
+type LineStagingInfo struct {
+	Path        string
+	Hunk        diff.Hunk
+	LineIndices []int // Indices into Hunk.Lines
+}
+
+// GetLineStagingInfo returns the changed lines under the visual selection,
+// or under the cursor when not in visual mode, grouped by hunk in diff order
+func (m Model) GetLineStagingInfo() []LineStagingInfo {
+	start, end := m.cursor, m.cursor
+	if m.visualMode {
+		start, end = m.selectStart, m.selectEnd
+		if start > end {
+			start, end = end, start
 		}
 	}
+	// Removed lines folded into a word diff are shown with the first
+	// added line, so selecting it takes them along
+	for start > 0 && m.wordHidden[start-1] {
+		start--
+	}
 
-	return nil
+	var infos []LineStagingInfo
+	for i := start; i <= end; i++ {
+		pos, ok := m.hunkAt(i)
+		if !ok {
+			continue
+		}
+		fd := m.diffs[pos.file]
+		hunk := fd.Hunks[pos.hunk]
+		t := hunk.Lines[pos.line].Type
+		if t != diff.LineAdded && t != diff.LineRemoved {
+			continue
+		}
+		if n := len(infos); n > 0 && infos[n-1].Path == fd.NewPath && infos[n-1].Hunk.Header == hunk.Header &&
+			infos[n-1].Hunk.OldStart == hunk.OldStart {
+			infos[n-1].LineIndices = append(infos[n-1].LineIndices, pos.line)
+			continue
+		}
+		infos = append(infos, LineStagingInfo{Path: fd.NewPath, Hunk: hunk, LineIndices: []int{pos.line}})
+	}
+	return infos
+}
+
+// ExitVisualMode leaves visual and character mode
+func (m *Model) ExitVisualMode() {
+	m.visualMode = false
+	m.charMode = false
+	m.charStart = 0
+	m.charCursor = 0
+	m.updateViewportContent()
+}
+
+// HasPendingKey reports whether the first key of a two-key motion such as
+// ]c or zh is waiting for the second
+func (m Model) HasPendingKey() bool {
+	return m.pendingKey != ""
+}
+
+// IsInVisualMode reports whether a visual line selection is active
+func (m Model) IsInVisualMode() bool {
+	return m.visualMode
 }
 
 func (m Model) IsInCharMode() bool {
@@ -1,10 +1,12 @@
 package diffview
 
 import (
+	"strings"
 	"testing"
 
 	"charm.land/bubbles/v2/key"
 	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/Danny-Dasilva/gdiff/internal/types"
 	"github.com/Danny-Dasilva/gdiff/pkg/diff"
 )
@@ -17,6 +19,10 @@ func newTestKeyMap() types.KeyMap {
 		Right:      key.NewBinding(key.WithKeys("l")),
 		VisualMode: key.NewBinding(key.WithKeys("v")),
 		Escape:     key.NewBinding(key.WithKeys("esc")),
+		NextChange: key.NewBinding(key.WithKeys("]")),
+		PrevChange: key.NewBinding(key.WithKeys("[")),
+
+		ScrollSideways: key.NewBinding(key.WithKeys("z")),
 	}
 }
 
@@ -243,3 +249,299 @@ func TestCharacterModeOnlyForChangedLines(t *testing.T) {
 		t.Error("visual mode should still work on context lines")
 	}
 }
+
+func multiFileDiffs() []diff.FileDiff {
+	file := func(path string) diff.FileDiff {
+		return diff.FileDiff{
+			OldPath: path,
+			NewPath: path,
+			Hunks: []diff.Hunk{
+				{
+					OldStart: 1,
+					OldCount: 2,
+					NewStart: 1,
+					NewCount: 2,
+					Lines: []diff.Line{
+						{Type: diff.LineContext, Content: "same", OldNum: 1, NewNum: 1},
+						{Type: diff.LineRemoved, Content: "old", OldNum: 2},
+						{Type: diff.LineAdded, Content: "new", NewNum: 2},
+					},
+				},
+			},
+		}
+	}
+	return []diff.FileDiff{file("a.go"), file("b.go"), file("c.go")}
+}
+
+func TestFileJumps(t *testing.T) {
+	m := New(newTestKeyMap(), false)
+	m.SetFocused(true)
+	m.SetDiff("", multiFileDiffs())
+	m.SetSize(80, 24)
+
+	// Each file is a header plus three lines: a.go at 0, b.go at 4, c.go at 8
+	var cmd tea.Cmd
+	m, _ = m.Update(keyPress("]"))
+	m, cmd = m.Update(keyPress("f"))
+	if m.cursor != 4 {
+		t.Fatalf("]f: cursor = %d, want 4", m.cursor)
+	}
+	if cmd == nil {
+		t.Fatal("]f: expected a CursorFileChangedMsg")
+	}
+	if msg, ok := cmd().(types.CursorFileChangedMsg); !ok || msg.Path != "b.go" {
+		t.Errorf("]f: got %#v, want CursorFileChangedMsg for b.go", cmd())
+	}
+
+	m.moveCursor(2)
+	m, _ = m.Update(keyPress("["))
+	m, _ = m.Update(keyPress("f"))
+	if m.cursor != 4 {
+		t.Errorf("[f inside a file: cursor = %d, want its header 4", m.cursor)
+	}
+	m, _ = m.Update(keyPress("["))
+	m, _ = m.Update(keyPress("f"))
+	if m.cursor != 0 {
+		t.Errorf("[f on a header: cursor = %d, want previous file 0", m.cursor)
+	}
+
+	m, _ = m.Update(keyPress("]"))
+	m, _ = m.Update(keyPress("c"))
+	if m.cursor != 2 {
+		t.Errorf("]c: cursor = %d, want first change 2", m.cursor)
+	}
+
+	if !m.JumpToFile("c.go") || m.CursorPath() != "c.go" {
+		t.Errorf("JumpToFile(c.go): cursor path = %s", m.CursorPath())
+	}
+}
+
+func TestLineStagingInfoAcrossFiles(t *testing.T) {
+	m := New(newTestKeyMap(), false)
+	m.SetFocused(true)
+	m.SetDiff("", multiFileDiffs())
+	m.SetSize(80, 24)
+
+	// Select from a.go's removed line through b.go's removed line
+	m.visualMode = true
+	m.selectStart, m.selectEnd = 2, 6
+	m.cursor = 6
+
+	infos := m.GetLineStagingInfo()
+	if len(infos) != 2 {
+		t.Fatalf("got %d hunks, want 2", len(infos))
+	}
+	if infos[0].Path != "a.go" || len(infos[0].LineIndices) != 2 {
+		t.Errorf("first = %s %v, want a.go with 2 lines", infos[0].Path, infos[0].LineIndices)
+	}
+	if infos[1].Path != "b.go" || len(infos[1].LineIndices) != 1 || infos[1].LineIndices[0] != 1 {
+		t.Errorf("second = %s %v, want b.go [1]", infos[1].Path, infos[1].LineIndices)
+	}
+}
+
+func TestUnifiedLayoutRows(t *testing.T) {
+	m := New(newTestKeyMap(), false)
+	m.SetFocused(true)
+	m.SetDiff("", multiFileDiffs())
+
+	m.SetSize(60, 24)
+	if !m.isUnified() {
+		t.Fatal("narrow pane should fall back to the unified layout")
+	}
+	// Unified: every hunk line has its own row after a two-row file header
+	for line, want := range map[int]int{0: 0, 1: 2, 2: 3, 3: 4, 4: 5} {
+		if got := m.lineRows[line]; got != want {
+			t.Errorf("unified: line %d on row %d, want %d", line, got, want)
+		}
+	}
+
+	m.SetSize(160, 24)
+	if m.isUnified() {
+		t.Fatal("wide pane should default to side by side")
+	}
+	// Side by side: the removed and added lines share a row
+	if m.lineRows[2] != m.lineRows[3] {
+		t.Errorf("side-by-side: paired lines on rows %d and %d", m.lineRows[2], m.lineRows[3])
+	}
+
+	m.ToggleLayout()
+	if !m.isUnified() || m.lineRows[3] != 4 {
+		t.Errorf("after toggle: unified=%v, added line on row %d", m.isUnified(), m.lineRows[3])
+	}
+
+	// A layout picked by hand holds in a narrow pane, both ways
+	m.SetSize(60, 24)
+	m.ToggleLayout()
+	if m.isUnified() || m.LayoutName() != "side-by-side" {
+		t.Errorf("toggle in a narrow pane should switch to side by side, got %s", m.LayoutName())
+	}
+	m.ToggleLayout()
+	if !m.isUnified() || m.LayoutName() != "unified" {
+		t.Errorf("second toggle should switch back to unified, got %s", m.LayoutName())
+	}
+}
+
+func TestFileSyntaxCache(t *testing.T) {
+	m := New(newTestKeyMap(), false)
+	diffs := multiFileDiffs()
+	diffs[1].NewPath = "notes.txt"
+	m.SetDiff("", diffs)
+
+	tokens := m.fileSyntax(0)
+	if tokens == nil || len(tokens[0]) != 3 {
+		t.Fatalf("expected tokens for every line of a.go, got %v", tokens)
+	}
+	if _, ok := m.syntaxCache[0]; !ok {
+		t.Error("a.go tokens should be cached")
+	}
+	if m.fileSyntax(1) != nil {
+		t.Error("unknown language should not be highlighted")
+	}
+
+	// Poison the cache; a new diff must be lexed again
+	m.syntaxCache[0] = nil
+	m.SetDiff("", diffs)
+	if m.fileSyntax(0) == nil {
+		t.Error("SetDiff should reset the syntax cache")
+	}
+}
+
+func TestCharChangesCache(t *testing.T) {
+	m := New(newTestKeyMap(), false)
+	m.SetDiff("", multiFileDiffs())
+
+	m.hunkCharChanges(0, 0)
+	if _, ok := m.charCache[0]; !ok {
+		t.Fatal("a.go character changes should be cached")
+	}
+
+	// Poison the cache; a new diff must be compared again
+	m.charCache[0] = nil
+	m.SetDiff("", multiFileDiffs())
+	if m.charCache[0] == nil {
+		t.Error("SetDiff should reset the character change cache")
+	}
+}
+
+func longLineDiff() []diff.FileDiff {
+	long := strings.Repeat("abcdefghij", 20)
+	return []diff.FileDiff{
+		{
+			OldPath: "min.js",
+			NewPath: "min.js",
+			Hunks: []diff.Hunk{
+				{
+					OldStart: 1,
+					OldCount: 1,
+					NewStart: 1,
+					NewCount: 1,
+					Lines: []diff.Line{
+						{Type: diff.LineRemoved, Content: long, OldNum: 1},
+						{Type: diff.LineAdded, Content: long + "XYZ", NewNum: 1},
+						{Type: diff.LineContext, Content: "short", OldNum: 2, NewNum: 2},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestSoftWrapKeepsSidesAligned(t *testing.T) {
+	m := New(newTestKeyMap(), false)
+	m.SetFocused(true)
+	m.SetDiff("min.js", longLineDiff())
+	m.SetSize(160, 40)
+
+	// Side by side with 72-character columns: 203 characters need 3 rows
+	m.ToggleWrap()
+	if m.lineRows[1] != 2 || m.lineRows[2] != 2 {
+		t.Errorf("paired lines should start on row 2, got %d and %d", m.lineRows[1], m.lineRows[2])
+	}
+	if m.lineRows[3] != 5 {
+		t.Errorf("context line should follow the 3 wrapped rows, got row %d", m.lineRows[3])
+	}
+	if !strings.Contains(ansi.Strip(m.viewport.GetContent()), "XYZ") {
+		t.Error("wrapped content should include the end of the line")
+	}
+}
+
+func TestHorizontalScroll(t *testing.T) {
+	m := New(newTestKeyMap(), false)
+	m.SetFocused(true)
+	m.SetDiff("min.js", longLineDiff())
+	m.SetSize(160, 40)
+
+	if strings.Contains(ansi.Strip(m.viewport.GetContent()), "XYZ") {
+		t.Fatal("end of the long line should start off screen")
+	}
+	for i := 0; i < 20; i++ {
+		m, _ = m.Update(keyPress("z"))
+		m, _ = m.Update(keyPress("l"))
+	}
+	if !strings.Contains(ansi.Strip(m.viewport.GetContent()), "XYZ") {
+		t.Error("zl should scroll the end of the line into view")
+	}
+	m, _ = m.Update(keyPress("z"))
+	m, _ = m.Update(keyPress("0"))
+	if m.hscroll != 0 {
+		t.Errorf("z0 should reset the scroll, got %d", m.hscroll)
+	}
+
+	// The prefix follows the keymap
+	keys := newTestKeyMap()
+	keys.ScrollSideways = key.NewBinding(key.WithKeys("s"))
+	m = New(keys, false)
+	m.SetFocused(true)
+	m.SetDiff("min.js", longLineDiff())
+	m.SetSize(160, 40)
+	m, _ = m.Update(keyPress("z"))
+	m, _ = m.Update(keyPress("l"))
+	if m.hscroll != 0 {
+		t.Errorf("z should not scroll once rebound, got %d", m.hscroll)
+	}
+	m, _ = m.Update(keyPress("s"))
+	m, _ = m.Update(keyPress("l"))
+	if m.hscroll == 0 {
+		t.Error("the rebound prefix should scroll")
+	}
+}
+
+func TestCharCursorScrollsIntoView(t *testing.T) {
+	m := New(newTestKeyMap(), false)
+	m.SetFocused(true)
+	m.SetDiff("min.js", longLineDiff())
+	m.SetSize(160, 40)
+
+	m.moveCursor(2)
+	m, _ = m.Update(keyPress("v"))
+	for i := 0; i < 100; i++ {
+		m, _ = m.Update(keyPress("l"))
+	}
+	width := m.contentWidth()
+	if m.charCursor < m.hscroll || m.charCursor >= m.hscroll+width {
+		t.Errorf("char cursor %d outside visible range [%d, %d)", m.charCursor, m.hscroll, m.hscroll+width)
+	}
+}
+
+func TestSideBySideAlignsPairs(t *testing.T) {
+	lines := []diff.Line{
+		{Type: diff.LineRemoved, Content: "first := load(a)", OldNum: 1},
+		{Type: diff.LineRemoved, Content: "second := load(b)", OldNum: 2},
+		{Type: diff.LineAdded, Content: "first := load(a, opts)", NewNum: 1},
+		{Type: diff.LineAdded, Content: "log.Printf(\"loading\")", NewNum: 2},
+		{Type: diff.LineAdded, Content: "second := load(b, opts)", NewNum: 3},
+	}
+	m := New(newTestKeyMap(), false)
+	m.SetFocused(true)
+	m.SetDiff("a.go", []diff.FileDiff{{OldPath: "a.go", NewPath: "a.go", Hunks: []diff.Hunk{{Lines: lines}}}})
+	m.SetSize(160, 40)
+
+	// Flattened lines: header, then the hunk lines in order. The inserted
+	// log line gets a row of its own instead of shifting the pairs.
+	if m.lineRows[1] != m.lineRows[3] || m.lineRows[2] != m.lineRows[5] {
+		t.Errorf("pairs not aligned: rows %v", m.lineRows)
+	}
+	if m.lineRows[4] == m.lineRows[3] || m.lineRows[4] == m.lineRows[5] {
+		t.Errorf("inserted line shares a row with a pair: rows %v", m.lineRows)
+	}
+}
@@ -0,0 +1,127 @@
+package diffview
+
+import (
+	"fmt"
+
+	"charm.land/lipgloss/v2"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+)
+
+// moveEnd locates a line inside one end of a moved block
+type moveEnd struct {
+	move   int
+	to     bool // destination (added) end rather than the source
+	offset int  // line within the block
+}
+
+// Background tints of moved blocks, alternating between adjacent moves
+// like git's zebra mode
+var (
+	movedFromBg = [2]string{"#2d1f3a", "#3a2347"}
+	movedToBg   = [2]string{"#1a2f33", "#1f3a40"}
+)
+
+// indexMoves detects moved blocks in the current diff
+func (m *Model) indexMoves() {
+	m.moves = diff.DetectMoves(m.diffs)
+	m.moveEnds = make(map[diff.LineRef]moveEnd)
+	for i, mv := range m.moves {
+		for k, ref := range mv.From {
+			m.moveEnds[ref] = moveEnd{move: i, offset: k}
+		}
+		for k, ref := range mv.To {
+			m.moveEnds[ref] = moveEnd{move: i, to: true, offset: k}
+		}
+	}
+}
+
+func (m Model) moveAt(file, hunk, line int) (moveEnd, bool) {
+	end, ok := m.moveEnds[diff.LineRef{File: file, Hunk: hunk, Line: line}]
+	return end, ok
+}
+
+// counterpart returns the line at the other end of a move
+func (m Model) counterpart(end moveEnd) diff.LineRef {
+	mv := m.moves[end.move]
+	if end.to {
+		return mv.From[end.offset]
+	}
+	return mv.To[end.offset]
+}
+
+func (m Model) refLine(ref diff.LineRef) diff.Line {
+	return m.diffs[ref.File].Hunks[ref.Hunk].Lines[ref.Line]
+}
+
+// moveDecor restyles a moved line. Exact moves are dimmed since nothing in
+// them changed; edited moves are char-diffed against their counterpart.
+func (m Model) moveDecor(d *decor, file, hunk, line int) {
+	end, ok := m.moveAt(file, hunk, line)
+	if !ok {
+		return
+	}
+
+	bg := movedFromBg
+	if end.to {
+		bg = movedToBg
+	}
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("#cdd6f4")).Background(lipgloss.Color(bg[end.move%2]))
+
+	if m.moves[end.move].Exact {
+		style = style.Faint(true)
+		d.changes = nil
+	} else {
+		self := m.refLine(diff.LineRef{File: file, Hunk: hunk, Line: line})
+		other := m.refLine(m.counterpart(end))
+		tokenizer := m.fileTokenizer(file)
+		if end.to {
+			_, d.changes = diff.ComputeCharDiffWith(other.Content, self.Content, tokenizer)
+		} else {
+			d.changes, _ = diff.ComputeCharDiffWith(self.Content, other.Content, tokenizer)
+		}
+	}
+	d.base = &style
+}
+
+// moveLabel returns the annotation shown above the first line of each end
+// of a move, or "" for other lines
+func (m Model) moveLabel(file, hunk, line int) string {
+	end, ok := m.moveAt(file, hunk, line)
+	if !ok || end.offset != 0 {
+		return ""
+	}
+	ref := m.counterpart(end)
+	other := m.refLine(ref)
+	path := m.diffs[ref.File].NewPath
+
+	label := fmt.Sprintf("  ⇢ moved to %s:%d", path, other.NewNum)
+	if end.to {
+		label = fmt.Sprintf("  ⇠ moved from %s:%d", m.diffs[ref.File].OldPath, other.OldNum)
+	}
+	if !m.moves[end.move].Exact {
+		label += " (edited)"
+	}
+	return m.lineNumStyle.Render(label + "  % to jump")
+}
+
+// jumpMove moves the cursor to the matching line at the other end of the
+// move under it. Returns false if the cursor is not on a moved line.
+func (m *Model) jumpMove() bool {
+	pos, ok := m.hunkAt(m.cursor)
+	if !ok {
+		return false
+	}
+	end, ok := m.moveAt(pos.file, pos.hunk, pos.line)
+	if !ok {
+		return false
+	}
+	ref := m.counterpart(end)
+	m.walk(func(seg segment) bool {
+		if seg.file == ref.File && seg.hunk == ref.Hunk && !seg.extra {
+			m.cursor = seg.start + ref.Line
+			return false
+		}
+		return true
+	})
+	return true
+}
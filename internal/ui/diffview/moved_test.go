@@ -0,0 +1,74 @@
+package diffview
+
+import (
+	"strings"
+	"testing"
+
+	"charm.land/bubbles/v2/key"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const movedFixture = `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,6 +1,2 @@
+ package a
+-func helper(x int) int {
+-	return x * 2
+-}
+ 
+ var y = 1
+diff --git a/b.go b/b.go
+--- a/b.go
++++ b/b.go
+@@ -1,2 +1,6 @@
+ package b
++
++func helper(x int) int {
++	return x * 3
++}
+ var z = 2
+`
+
+func TestMovedBlocks(t *testing.T) {
+	keys := newTestKeyMap()
+	keys.JumpMove = key.NewBinding(key.WithKeys("%"))
+	m := New(keys, false)
+	m.SetFocused(true)
+	m.SetDiff("", diff.Parse(movedFixture))
+	m.SetSize(160, 40)
+
+	if len(m.moves) != 1 {
+		t.Fatalf("got %d moves, want 1", len(m.moves))
+	}
+
+	content := ansi.Strip(m.viewport.GetContent())
+	for _, want := range []string{"moved to b.go:3 (edited)", "moved from a.go:2 (edited)"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("missing %q in rendered diff", want)
+		}
+	}
+
+	// header, hunk header, context, then the first moved line
+	m.cursor = 3
+	m, _ = m.Update(keyPress("%"))
+	line := m.lineAt(m.cursor)
+	if line == nil || line.Type != diff.LineAdded || line.Content != "func helper(x int) int {" {
+		t.Fatalf("jump landed on %+v", line)
+	}
+
+	m.moveCursor(1)
+	m, _ = m.Update(keyPress("%"))
+	if line := m.lineAt(m.cursor); line == nil || line.Content != "\treturn x * 2" {
+		t.Errorf("jump back landed on %+v", line)
+	}
+
+	// The edited line is char-diffed against its counterpart
+	d := decor{cursor: -1}
+	pos, _ := m.hunkAt(m.cursor)
+	m.moveDecor(&d, pos.file, pos.hunk, pos.line)
+	if d.base == nil || len(d.changes) != 1 {
+		t.Errorf("moved line decor = %+v, want moved style and one change", d)
+	}
+}
@@ -0,0 +1,57 @@
+package diffview
+
+import "github.com/Danny-Dasilva/gdiff/pkg/diff"
+
+// picked reports whether a hunk is marked. Marks are kept by path and hunk
+// header so they survive switching between files.
+func (m Model) picked(file, hunk int) bool {
+	fd := m.diffs[file]
+	for _, p := range m.picks {
+		if p.Path == fd.NewPath && p.Hunk.Header == fd.Hunks[hunk].Header {
+			return true
+		}
+	}
+	return false
+}
+
+// TogglePick marks or unmarks the hunk under the cursor for picking,
+// exporting or applying, and reports whether it is now marked; ok is false
+// off a hunk
+func (m *Model) TogglePick() (marked, ok bool) {
+	pos, ok := m.hunkAt(m.cursor)
+	if !ok {
+		return false, false
+	}
+	fd := m.diffs[pos.file]
+	hunk := fd.Hunks[pos.hunk]
+	for i, p := range m.picks {
+		if p.Path == fd.NewPath && p.Hunk.Header == hunk.Header {
+			m.picks = append(m.picks[:i], m.picks[i+1:]...)
+			m.updateViewportContent()
+			return false, true
+		}
+	}
+	m.picks = append(m.picks, HunkStagingInfo{Path: fd.NewPath, Hunk: hunk})
+	m.updateViewportContent()
+	return true, true
+}
+
+// PickedHunks returns the marked hunks, in marking order
+func (m Model) PickedHunks() []HunkStagingInfo {
+	return m.picks
+}
+
+// pickMark replaces the leading space of a rendered hunk header with a
+// marker when the hunk is picked
+func (m Model) pickMark(row string, line diff.Line, file, hunk int) string {
+	if line.Type != diff.LineHunkHeader || len(m.picks) == 0 || !m.picked(file, hunk) || row == "" {
+		return row
+	}
+	return m.hunkStyle.Render("●") + row[1:]
+}
+
+// ClearPicks unmarks every hunk
+func (m *Model) ClearPicks() {
+	m.picks = nil
+	m.updateViewportContent()
+}
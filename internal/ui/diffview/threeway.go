@@ -0,0 +1,58 @@
+package diffview
+
+import (
+	"strconv"
+
+	"charm.land/lipgloss/v2"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+)
+
+// Provenance tags shown in the gutter of the three-way view
+var provenanceTags = map[diff.Provenance]string{
+	diff.Unchanged: " ",
+	diff.Staged:    "S",
+	diff.Unstaged:  "U",
+	diff.IndexOnly: "I",
+}
+
+// SetThreeWay shows a file's merged HEAD to worktree diff with the
+// provenance of each line: S staged, U unstaged, I added in the index and
+// removed in the worktree. The view is always unified.
+func (m *Model) SetThreeWay(path string, tw diff.ThreeWay) {
+	m.origins = tw.Origins
+	m.setDiff(path, []diff.FileDiff{tw.File})
+}
+
+// IsThreeWay reports whether a three-way diff is shown
+func (m Model) IsThreeWay() bool {
+	return m.origins != nil
+}
+
+// originDecor tags a line of the three-way view with its provenance
+func (m Model) originDecor(d *decor, hunk, line int) {
+	if m.origins == nil || hunk >= len(m.origins) || line >= len(m.origins[hunk]) {
+		return
+	}
+	d.tag = provenanceTags[m.origins[hunk][line].Provenance]
+}
+
+// tagStyle colors a provenance tag
+func (m Model) tagStyle(tag string) lipgloss.Style {
+	switch tag {
+	case "S":
+		return m.hunkStyle
+	case "U":
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("#f9e2af")).Bold(true)
+	case "I":
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("#cba6f7")).Bold(true)
+	}
+	return m.lineNumStyle
+}
+
+// lineNumber formats a line number for the gutter, blank for 0
+func lineNumber(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
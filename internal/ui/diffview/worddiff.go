@@ -0,0 +1,199 @@
+package diffview
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+	"github.com/Danny-Dasilva/gdiff/pkg/syntax"
+)
+
+// wordDiffMode selects when paragraphs are word-diffed
+type wordDiffMode int
+
+const (
+	wordDiffAuto wordDiffMode = iota // Files with a configured extension
+	wordDiffOn
+	wordDiffOff
+)
+
+// SetWordDiffExtensions sets the file extensions that get word diffs in
+// auto mode, e.g. ".md"
+func (m *Model) SetWordDiffExtensions(exts []string) {
+	m.wordExts = make(map[string]bool)
+	for _, ext := range exts {
+		m.wordExts[strings.ToLower(ext)] = true
+	}
+	m.updateViewportContent()
+}
+
+// ToggleWordDiff cycles between automatic, forced and disabled word diffs
+func (m *Model) ToggleWordDiff() {
+	m.wordMode = (m.wordMode + 1) % 3
+	m.updateViewportContent()
+	m.skipHidden(m.cursor)
+	m.syncViewport()
+}
+
+// WordDiffName describes the word diff mode in effect
+func (m Model) WordDiffName() string {
+	switch m.wordMode {
+	case wordDiffOn:
+		return "on"
+	case wordDiffOff:
+		return "off"
+	}
+	return "auto"
+}
+
+// wordDiff reports whether a file is rendered as a word diff
+func (m Model) wordDiff(file int) bool {
+	switch m.wordMode {
+	case wordDiffOn:
+		return true
+	case wordDiffOff:
+		return false
+	}
+	return m.wordExts[strings.ToLower(filepath.Ext(m.diffs[file].NewPath))]
+}
+
+// wordBlocks word-diffs every block of removed lines followed by added
+// lines in a hunk. The removed lines are folded into the added ones for
+// display, so they are reported as hidden. Blocks touching a moved line
+// keep the line layout.
+func (m Model) wordBlocks(file, hunk int) (changes [][]diff.CharChange, deleted [][]diff.WordDeletion, hidden []bool) {
+	lines := m.diffs[file].Hunks[hunk].Lines
+	changes = make([][]diff.CharChange, len(lines))
+	deleted = make([][]diff.WordDeletion, len(lines))
+	hidden = make([]bool, len(lines))
+
+	for i := 0; i < len(lines); {
+		if lines[i].Type != diff.LineRemoved {
+			i++
+			continue
+		}
+		removedStart := i
+		for i < len(lines) && lines[i].Type == diff.LineRemoved {
+			i++
+		}
+		addedStart := i
+		for i < len(lines) && lines[i].Type == diff.LineAdded {
+			i++
+		}
+		if addedStart == i {
+			continue
+		}
+		moved := false
+		for j := removedStart; j < i; j++ {
+			if _, ok := m.moveAt(file, hunk, j); ok {
+				moved = true
+			}
+		}
+		if moved {
+			continue
+		}
+
+		var oldText, newText []string
+		for _, l := range lines[removedStart:addedStart] {
+			oldText = append(oldText, l.Content)
+		}
+		for _, l := range lines[addedStart:i] {
+			newText = append(newText, l.Content)
+		}
+		res := diff.WordDiff(oldText, newText)
+		for j := range oldText {
+			changes[removedStart+j] = res.Old[j]
+			hidden[removedStart+j] = true
+		}
+		for j := range newText {
+			changes[addedStart+j] = res.New[j]
+		}
+		for _, del := range res.Deleted {
+			deleted[addedStart+del.Line] = append(deleted[addedStart+del.Line], del)
+		}
+	}
+	return changes, deleted, hidden
+}
+
+// spliceDeletions inlines deleted words into an added line. It returns the
+// displayed content, the deleted ranges within it, and the display offset
+// of every rune of the original content (plus one past the end) so other
+// ranges can be remapped.
+func spliceDeletions(content string, deleted []diff.WordDeletion) (string, []diff.CharChange, []int) {
+	runes := []rune(content)
+	var b strings.Builder
+	var dels []diff.CharChange
+	offsets := make([]int, len(runes)+1)
+	pos, next := 0, 0
+	for i := 0; i <= len(runes); i++ {
+		for next < len(deleted) && deleted[next].At == i {
+			text := []rune(deleted[next].Text)
+			dels = append(dels, diff.CharChange{Start: pos, End: pos + len(text)})
+			b.WriteString(string(text))
+			pos += len(text)
+			next++
+		}
+		offsets[i] = pos
+		if i < len(runes) {
+			b.WriteRune(runes[i])
+			pos++
+		}
+	}
+	return b.String(), dels, offsets
+}
+
+// wordDecor splices a line's deleted words into it for display, remapping
+// its changes and syntax tokens to the spliced content. The character
+// cursor line is left as is so char staging addresses the real line.
+func wordDecor(line diff.Line, d *decor, deleted []diff.WordDeletion) diff.Line {
+	if len(deleted) == 0 || d.cursor >= 0 {
+		return line
+	}
+	content, dels, offsets := spliceDeletions(line.Content, deleted)
+	// A range's end maps to just after its last rune, not past any
+	// deletion spliced in after it
+	end := func(e int) int {
+		if e == 0 {
+			return 0
+		}
+		return offsets[e-1] + 1
+	}
+	changes := make([]diff.CharChange, len(d.changes))
+	for k, c := range d.changes {
+		changes[k] = diff.CharChange{Start: offsets[c.Start], End: end(c.End), Added: c.Added}
+	}
+	tokens := make([]syntax.Token, len(d.tokens))
+	for k, t := range d.tokens {
+		t.Start, t.End = offsets[t.Start], end(t.End)
+		tokens[k] = t
+	}
+	d.changes, d.tokens, d.deleted = changes, tokens, dels
+	line.Content = content
+	return line
+}
+
+// skipHidden moves the cursor off lines folded into a word diff, onto the
+// added lines they are shown with. When that is where the cursor came from
+// (moving up out of the block) it continues upwards instead. Returns true
+// if the cursor moved.
+func (m *Model) skipHidden(prev int) bool {
+	if !m.wordHidden[m.cursor] {
+		return false
+	}
+	total := m.totalLines()
+	next := m.cursor
+	for next < total && m.wordHidden[next] {
+		next++
+	}
+	if next == prev || next == total {
+		next = m.cursor
+		for next > 0 && m.wordHidden[next] {
+			next--
+		}
+	}
+	m.cursor = next
+	if m.visualMode {
+		m.selectEnd = m.cursor
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package diffview
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const proseFixture = `diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1,4 +1,4 @@
+ # Title
+-The quick brown fox jumps over
+-the lazy dog.
++The quick red fox jumps
++over the lazy dog.
+ End
+`
+
+func TestWordDiffFoldsRemovedLines(t *testing.T) {
+	m := New(newTestKeyMap(), false)
+	m.SetFocused(true)
+	m.SetWordDiffExtensions([]string{".md"})
+	m.SetDiff("README.md", diff.Parse(proseFixture))
+	m.SetSize(160, 40)
+
+	content := ansi.Strip(m.viewport.GetContent())
+	if strings.Contains(content, "The quick brown fox jumps over") {
+		t.Errorf("removed line rendered on its own:\n%s", content)
+	}
+	if !strings.Contains(content, "The quick brownred fox jumps") {
+		t.Errorf("deleted word not inlined:\n%s", content)
+	}
+	if strings.Contains(content, "lazy dog.lazy") || strings.Count(content, "lazy") != 1 {
+		t.Errorf("reflowed words should not be marked:\n%s", content)
+	}
+
+	// header, hunk header, context, then the two folded removed lines
+	m.cursor = 2
+	m, _ = m.Update(keyPress("j"))
+	line := m.lineAt(m.cursor)
+	if line == nil || line.Type != diff.LineAdded || line.Content != "The quick red fox jumps" {
+		t.Fatalf("cursor landed on %+v, want first added line", line)
+	}
+	m, _ = m.Update(keyPress("k"))
+	if m.cursor != 2 {
+		t.Errorf("moving up from the block landed on %d, want 2", m.cursor)
+	}
+
+	// Line staging on the first added line takes the folded removed
+	// lines along
+	m.cursor = 5
+	infos := m.GetLineStagingInfo()
+	if len(infos) != 1 || len(infos[0].LineIndices) != 3 {
+		t.Errorf("line staging info = %+v, want hunk lines 2-4", infos)
+	}
+
+	// Char staging addresses the real added line
+	m.toggleVisualMode()
+	if info := m.GetCharStagingInfo(); info == nil || info.HunkLineIndex != 4 {
+		t.Errorf("char staging info = %+v, want hunk line 4", info)
+	}
+}
+
+func TestWordDiffToggle(t *testing.T) {
+	m := New(newTestKeyMap(), false)
+	m.SetFocused(true)
+	m.SetWordDiffExtensions([]string{".md"})
+	m.SetDiff("README.md", diff.Parse(proseFixture))
+	m.SetSize(160, 40)
+
+	for _, want := range []string{"on", "off"} {
+		m.ToggleWordDiff()
+		if got := m.WordDiffName(); got != want {
+			t.Fatalf("WordDiffName() = %q, want %q", got, want)
+		}
+	}
+	content := ansi.Strip(m.viewport.GetContent())
+	if !strings.Contains(content, "The quick brown fox jumps over") {
+		t.Errorf("removed line missing with word diff off:\n%s", content)
+	}
+}
+
+func TestSpliceDeletions(t *testing.T) {
+	content, dels, offsets := spliceDeletions("a c", []diff.WordDeletion{{At: 2, Text: "b "}})
+	if content != "a b c" {
+		t.Errorf("content = %q, want %q", content, "a b c")
+	}
+	if len(dels) != 1 || dels[0].Start != 2 || dels[0].End != 4 {
+		t.Errorf("deleted ranges = %+v", dels)
+	}
+	if offsets[2] != 4 {
+		t.Errorf("offset of c = %d, want 4", offsets[2])
+	}
+}
@@ -9,6 +9,7 @@ import (
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
 	"github.com/Danny-Dasilva/gdiff/internal/types"
+	"github.com/Danny-Dasilva/gdiff/internal/ui/diffstat"
 	"github.com/Danny-Dasilva/gdiff/pkg/diff"
 )
 
@@ -44,8 +45,21 @@ type Model struct {
 	stagedCollapsed   bool
 	unstagedCollapsed bool
 
+	// Multi-select state: explicitly marked files plus an optional
+	// visual range anchored at rangeAnchor
+	marked      map[string]bool
+	rangeMode   bool
+	rangeAnchor int
+
+	// Per-file line counts from git diff --numstat, keyed like marks
+	stats    map[string][2]int
+	maxChurn int
+
 	// Styles
 	normalStyle   lipgloss.Style
+	markStyle     lipgloss.Style
+	addedStyle    lipgloss.Style
+	removedStyle  lipgloss.Style
 	selectedStyle lipgloss.Style
 	focusedStyle  lipgloss.Style
 	headerStyle   lipgloss.Style
@@ -65,6 +79,10 @@ func New(keyMap types.KeyMap) Model {
 		focusedStyle:  lipgloss.NewStyle().Background(lipgloss.Color("62")),
 		headerStyle:   lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("252")),
 		countStyle:    lipgloss.NewStyle().Foreground(lipgloss.Color("243")).Italic(true),
+		markStyle:     lipgloss.NewStyle().Foreground(lipgloss.Color("141")).Bold(true),
+		addedStyle:    lipgloss.NewStyle().Foreground(lipgloss.Color("78")),
+		removedStyle:  lipgloss.NewStyle().Foreground(lipgloss.Color("204")),
+		marked:        make(map[string]bool),
 		statusStyles: map[diff.FileStatus]lipgloss.Style{
 			diff.StatusModified:  lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Bold(true), // Orange
 			diff.StatusAdded:     lipgloss.NewStyle().Foreground(lipgloss.Color("78")).Bold(true),  // Green
@@ -134,6 +152,20 @@ func (m *Model) SetFiles(files []diff.FileEntry) {
 	if m.cursor >= len(m.rows) {
 		m.cursor = max(0, len(m.rows)-1)
 	}
+
+	// Drop marks for files that are no longer listed
+	present := make(map[string]bool, len(files))
+	for _, f := range files {
+		present[markKey(f)] = true
+	}
+	for k := range m.marked {
+		if !present[k] {
+			delete(m.marked, k)
+		}
+	}
+	if m.rangeAnchor >= len(m.rows) {
+		m.rangeMode = false
+	}
 }
 
 // rebuildRows creates the virtual list based on collapse state
@@ -180,6 +212,20 @@ func (m *Model) toggleHeaderCollapse() bool {
 	return false
 }
 
+// SetStats records added/removed line counts for staged and unstaged files
+func (m *Model) SetStats(staged, unstaged map[string][2]int) {
+	m.stats = make(map[string][2]int, len(staged)+len(unstaged))
+	m.maxChurn = 0
+	for path, s := range staged {
+		m.stats["staged:"+path] = s
+		m.maxChurn = max(m.maxChurn, s[0]+s[1])
+	}
+	for path, s := range unstaged {
+		m.stats[path] = s
+		m.maxChurn = max(m.maxChurn, s[0]+s[1])
+	}
+}
+
 // SetSize updates the component dimensions
 func (m *Model) SetSize(width, height int) {
 	m.width = width
@@ -211,6 +257,164 @@ func (m Model) SelectedFile() *diff.FileEntry {
 	return nil
 }
 
+// SelectPath moves the cursor to the given file without emitting a
+// selection, expanding its section if it is collapsed. Returns false if the
+// file is not listed.
+func (m *Model) SelectPath(path string, staged bool) bool {
+	section := m.unstaged
+	if staged {
+		section = m.staged
+	}
+	found := false
+	for _, f := range section {
+		if f.Path == path {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+
+	if staged && m.stagedCollapsed {
+		m.stagedCollapsed = false
+		m.rebuildRows()
+	} else if !staged && m.unstagedCollapsed {
+		m.unstagedCollapsed = false
+		m.rebuildRows()
+	}
+
+	for i := range m.rows {
+		if f := m.fileAt(i); f != nil && f.Path == path && f.Staged == staged {
+			m.cursor = i
+			return true
+		}
+	}
+	return false
+}
+
+// markKey identifies a file row; a path can be listed in both sections
+func markKey(f diff.FileEntry) string {
+	if f.Staged {
+		return "staged:" + f.Path
+	}
+	return f.Path
+}
+
+// fileAt returns the file on the given row, or nil for header rows
+func (m Model) fileAt(row int) *diff.FileEntry {
+	if row < 0 || row >= len(m.rows) {
+		return nil
+	}
+	r := m.rows[row]
+	switch r.rowType {
+	case rowStagedFile:
+		if r.fileIndex < len(m.staged) {
+			return &m.staged[r.fileIndex]
+		}
+	case rowChangesFile:
+		if r.fileIndex < len(m.unstaged) {
+			return &m.unstaged[r.fileIndex]
+		}
+	}
+	return nil
+}
+
+// inRange reports whether a row falls inside the active visual range
+func (m Model) inRange(row int) bool {
+	if !m.rangeMode {
+		return false
+	}
+	start, end := m.rangeAnchor, m.cursor
+	if start > end {
+		start, end = end, start
+	}
+	return row >= start && row <= end
+}
+
+// isMarked reports whether the file on a row is part of the selection
+func (m Model) isMarked(row int) bool {
+	f := m.fileAt(row)
+	if f == nil {
+		return false
+	}
+	return m.marked[markKey(*f)] || m.inRange(row)
+}
+
+// MarkedFiles returns the marked files, including any active visual range,
+// in display order. Returns nil when nothing is marked.
+func (m Model) MarkedFiles() []diff.FileEntry {
+	var files []diff.FileEntry
+	seen := make(map[string]bool)
+	add := func(f diff.FileEntry) {
+		k := markKey(f)
+		if !seen[k] {
+			seen[k] = true
+			files = append(files, f)
+		}
+	}
+
+	for _, f := range m.staged {
+		if m.marked[markKey(f)] {
+			add(f)
+		}
+	}
+	for _, f := range m.unstaged {
+		if m.marked[markKey(f)] {
+			add(f)
+		}
+	}
+	if m.rangeMode {
+		for i := range m.rows {
+			if f := m.fileAt(i); f != nil && m.inRange(i) {
+				add(*f)
+			}
+		}
+	}
+	return files
+}
+
+// HasMarks reports whether any file is marked
+func (m Model) HasMarks() bool {
+	return len(m.marked) > 0 || m.rangeMode
+}
+
+// ClearMarks removes all marks and ends the visual range
+func (m *Model) ClearMarks() {
+	m.marked = make(map[string]bool)
+	m.rangeMode = false
+}
+
+// toggleMark flips the mark on the file under the cursor
+func (m *Model) toggleMark() {
+	f := m.SelectedFile()
+	if f == nil {
+		return
+	}
+	k := markKey(*f)
+	if m.marked[k] {
+		delete(m.marked, k)
+	} else {
+		m.marked[k] = true
+	}
+}
+
+// toggleRange starts a visual range at the cursor, or commits the
+// current range into the set of marked files
+func (m *Model) toggleRange() {
+	if !m.rangeMode {
+		m.rangeMode = true
+		m.rangeAnchor = m.cursor
+		return
+	}
+	for i := range m.rows {
+		if f := m.fileAt(i); f != nil && m.inRange(i) {
+			m.marked[markKey(*f)] = true
+		}
+	}
+	m.rangeMode = false
+}
+
 // Init implements tea.Model
 func (m Model) Init() tea.Cmd {
 	return nil
@@ -274,6 +478,19 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				},
 			)
 
+		case key.Matches(msg, m.keyMap.ToggleMark):
+			m.toggleMark()
+			if m.cursor < len(m.rows)-1 {
+				m.cursor++
+				return m, m.emitFileSelected()
+			}
+
+		case key.Matches(msg, m.keyMap.VisualLine):
+			m.toggleRange()
+
+		case key.Matches(msg, m.keyMap.Escape):
+			m.ClearMarks()
+
 		case key.Matches(msg, m.keyMap.SpaceToggle):
 			if m.toggleHeaderCollapse() {
 				break
@@ -515,11 +732,11 @@ func (m Model) View() string {
 
 		case rowStagedFile:
 			file := m.staged[row.fileIndex]
-			line = m.renderFileLine(file)
+			line = m.renderFileLine(file, m.isMarked(i))
 
 		case rowChangesFile:
 			file := m.unstaged[row.fileIndex]
-			line = m.renderFileLine(file)
+			line = m.renderFileLine(file, m.isMarked(i))
 		}
 
 		// Apply selection style
@@ -549,7 +766,7 @@ func (m Model) View() string {
 	return b.String()
 }
 
-func (m Model) renderFileLine(file diff.FileEntry) string {
+func (m Model) renderFileLine(file diff.FileEntry, marked bool) string {
 	statusStyle, ok := m.statusStyles[file.Status]
 	if !ok {
 		statusStyle = m.normalStyle
@@ -568,15 +785,44 @@ func (m Model) renderFileLine(file diff.FileEntry) string {
 		iconStyle = m.iconStyles["default"]
 	}
 
+	stat := m.renderStat(file)
+
 	filename := filepath.Base(file.Path)
-	maxLen := m.width - 10
+	maxLen := m.width - 10 - lipgloss.Width(stat)
 	if len(filename) > maxLen && maxLen > 3 {
 		filename = filename[:maxLen-3] + "..."
 	}
 
-	return fmt.Sprintf("    %s %s %s",
+	gutter := "    "
+	if marked {
+		gutter = "  " + m.markStyle.Render("▌") + " "
+	}
+
+	return fmt.Sprintf("%s%s %s %s%s",
+		gutter,
 		iconStyle.Render(iconInfo.icon),
 		filename,
 		statusStyle.Render(indicator),
+		stat,
+	)
+}
+
+// statBarWidth is the width of the mini diffstat bar next to each file
+const statBarWidth = 5
+
+// renderStat renders "+a -r" counts and a mini bar like git diff --stat.
+// Returns an empty string when no counts are known for the file.
+func (m Model) renderStat(file diff.FileEntry) string {
+	s, ok := m.stats[markKey(file)]
+	if !ok || s[0]+s[1] == 0 {
+		return ""
+	}
+
+	plus, minus := diffstat.Scale(s[0], s[1], m.maxChurn, statBarWidth)
+	return fmt.Sprintf(" %s %s %s%s",
+		m.addedStyle.Render(fmt.Sprintf("+%d", s[0])),
+		m.removedStyle.Render(fmt.Sprintf("-%d", s[1])),
+		m.addedStyle.Render(strings.Repeat("+", plus)),
+		m.removedStyle.Render(strings.Repeat("-", minus)),
 	)
 }
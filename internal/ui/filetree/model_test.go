@@ -0,0 +1,101 @@
+package filetree
+
+import (
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/Danny-Dasilva/gdiff/internal/types"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+)
+
+func newTestTree() Model {
+	m := New(types.DefaultKeyMap())
+	m.SetSize(40, 20)
+	m.SetFocused(true)
+	// Rows: 0 staged header, 1 a.go, 2 changes header, 3 a.go, 4 b.go, 5 c.go
+	m.SetFiles([]diff.FileEntry{
+		{Path: "a.go", Staged: true, IndexStatus: diff.StatusModified},
+		{Path: "a.go", WorkStatus: diff.StatusModified},
+		{Path: "b.go", WorkStatus: diff.StatusModified},
+		{Path: "c.go", WorkStatus: diff.StatusUntracked},
+	})
+	return m
+}
+
+func press(m Model, keys ...string) Model {
+	for _, k := range keys {
+		msg := tea.KeyPressMsg{Code: rune(k[0]), Text: k}
+		if k == "esc" {
+			msg = tea.KeyPressMsg{Code: tea.KeyEscape}
+		}
+		m, _ = m.Update(msg)
+	}
+	return m
+}
+
+func paths(files []diff.FileEntry) []string {
+	var out []string
+	for _, f := range files {
+		p := f.Path
+		if f.Staged {
+			p = "staged:" + p
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func TestMarkedFiles(t *testing.T) {
+	m := newTestTree()
+	if m.MarkedFiles() != nil || m.HasMarks() {
+		t.Fatal("nothing should be marked yet")
+	}
+
+	// Mark c.go, then the staged a.go; marks come back in display order
+	m.cursor = 5
+	m = press(m, "m")
+	m.cursor = 1
+	m = press(m, "m")
+	if got := paths(m.MarkedFiles()); len(got) != 2 || got[0] != "staged:a.go" || got[1] != "c.go" {
+		t.Errorf("MarkedFiles() = %v, want [staged:a.go c.go]", got)
+	}
+
+	// The same path in the other section is a separate mark
+	m.cursor = 1
+	m = press(m, "m")
+	if got := paths(m.MarkedFiles()); len(got) != 1 || got[0] != "c.go" {
+		t.Errorf("after unmarking staged a.go: %v, want [c.go]", got)
+	}
+
+	// Files that disappear lose their marks
+	m.SetFiles([]diff.FileEntry{{Path: "b.go", WorkStatus: diff.StatusModified}})
+	if m.HasMarks() {
+		t.Errorf("marks should be dropped with their files, got %v", m.marked)
+	}
+}
+
+func TestRangeMarking(t *testing.T) {
+	m := newTestTree()
+
+	// A range over the changes section skips its header
+	m.cursor = 2
+	m = press(m, "V", "j", "j")
+	if got := paths(m.MarkedFiles()); len(got) != 2 || got[0] != "a.go" || got[1] != "b.go" {
+		t.Fatalf("active range = %v, want [a.go b.go]", got)
+	}
+
+	// Committing the range keeps its files marked after the cursor moves
+	m = press(m, "V", "j")
+	if m.rangeMode {
+		t.Fatal("second V should end the range")
+	}
+	if got := paths(m.MarkedFiles()); len(got) != 2 || got[1] != "b.go" {
+		t.Errorf("after committing the range: %v, want [a.go b.go]", got)
+	}
+
+	m = press(m, "esc")
+	if m.HasMarks() {
+		t.Error("escape should clear all marks")
+	}
+}
@@ -77,6 +77,9 @@ func (m Model) View() string {
 		{"Ctrl+u", "half up"},
 		{"}", "next hunk"},
 		{"{", "prev hunk"},
+		{"]f/[f", "next/prev file"},
+		{"%", "other end of move"},
+		{"zh/zl", "scroll left/right"},
 		{"Tab", "switch pane"},
 	})
 
@@ -85,12 +88,27 @@ func (m Model) View() string {
 		{"a", "stage file"},
 		{"S", "stage hunk"},
 		{"d", "discard"},
+		{"m", "mark file (tree)"},
+		{"m", "mark hunk (diff)"},
+		{"Z", "stash"},
 		{"v", "visual mode"},
 		{"V", "visual lines"},
+		{"K", "preview stage"},
+		{"e", "edit hunk"},
 	})
 
 	viewCol := buildSection(headerStyle, keyStyle, descStyle, "View", []keybinding{
 		{"t", "staged view"},
+		{"T", "three-way"},
+		{"D", "diffstat"},
+		{"F", "all files"},
+		{"L", "layout"},
+		{"W", "soft-wrap"},
+		{"w", "word diff"},
+		{"o", "diff options"},
+		{"</>", "more context"},
+		{"E", "reveal gap"},
+		{"f", "full file"},
 		{"?", "help"},
 		{"q", "quit"},
 	})
@@ -107,6 +125,12 @@ func (m Model) View() string {
 		{"P", "force push"},
 	})
 
+	patchCol := buildSection(headerStyle, keyStyle, descStyle, "Patch", []keybinding{
+		{"X", "export"},
+		{"I", "open"},
+		{"r/R", "apply wt/index"},
+	})
+
 	colGap := "    "
 
 	leftCols := lipgloss.JoinHorizontal(lipgloss.Top,
@@ -121,6 +145,8 @@ func (m Model) View() string {
 		commitCol,
 		colGap,
 		pushCol,
+		colGap,
+		patchCol,
 	)
 
 	body := lipgloss.JoinVertical(lipgloss.Left,
@@ -0,0 +1,145 @@
+// Package prompt provides a one-line text prompt shown as a centered modal
+package prompt
+
+import (
+	"strings"
+
+	"charm.land/bubbles/v2/key"
+	"charm.land/bubbles/v2/textinput"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+)
+
+var (
+	colorMauve   = lipgloss.Color("#cba6f7")
+	colorBase    = lipgloss.Color("#1e1e2e")
+	colorText    = lipgloss.Color("#cdd6f4")
+	colorOverlay = lipgloss.Color("#6c7086")
+)
+
+var (
+	submitBinding = key.NewBinding(key.WithKeys("enter"))
+	cancelBinding = key.NewBinding(key.WithKeys("esc"))
+)
+
+// SubmitMsg is sent when the prompt is confirmed; ID is the one passed to Show
+type SubmitMsg struct {
+	ID    string
+	Value string
+}
+
+// CancelMsg is sent when the prompt is dismissed
+type CancelMsg struct {
+	ID string
+}
+
+// Model is a hidden-by-default prompt asking for one line of text
+type Model struct {
+	input   textinput.Model
+	id      string
+	title   string
+	visible bool
+	width   int
+	height  int
+}
+
+// New creates a hidden prompt
+func New() Model {
+	ti := textinput.New()
+	ti.Prompt = "> "
+	ti.CharLimit = 4096
+	ti.SetWidth(50)
+	return Model{input: ti}
+}
+
+// Show opens the prompt with a title and an initial value. id is echoed
+// back in SubmitMsg and CancelMsg so one prompt can serve several actions.
+func (m *Model) Show(id, title, value string) tea.Cmd {
+	m.id = id
+	m.title = title
+	m.visible = true
+	m.input.SetValue(value)
+	m.input.CursorEnd()
+	return m.input.Focus()
+}
+
+// Hide closes the prompt without sending a message
+func (m *Model) Hide() {
+	m.visible = false
+	m.input.Blur()
+}
+
+// Visible returns whether the prompt is shown
+func (m Model) Visible() bool {
+	return m.visible
+}
+
+// SetSize updates the prompt dimensions
+func (m *Model) SetSize(width, height int) {
+	m.width = width
+	m.height = height
+	m.input.SetWidth(max(min(width-16, 70), 20))
+}
+
+// Update edits the text; Enter submits and Esc cancels
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	if !m.visible {
+		return m, nil
+	}
+
+	if keyMsg, ok := msg.(tea.KeyPressMsg); ok {
+		id := m.id
+		switch {
+		case key.Matches(keyMsg, submitBinding):
+			m.Hide()
+			value := strings.TrimSpace(m.input.Value())
+			return m, func() tea.Msg {
+				return SubmitMsg{ID: id, Value: value}
+			}
+		case key.Matches(keyMsg, cancelBinding):
+			m.Hide()
+			return m, func() tea.Msg {
+				return CancelMsg{ID: id}
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// View renders the prompt as a centered modal. Returns empty string when hidden.
+func (m Model) View() string {
+	if !m.visible {
+		return ""
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.NewStyle().Bold(true).Foreground(colorText).Render(m.title),
+		"",
+		m.input.View(),
+		"",
+		lipgloss.NewStyle().Foreground(colorOverlay).Render("Enter confirm • Esc cancel"),
+	)
+
+	modal := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorMauve).
+		Background(colorBase).
+		Padding(1, 2).
+		Render(content)
+
+	padLeft := max((m.width-lipgloss.Width(modal))/2, 0)
+	padTop := max((m.height-lipgloss.Height(modal))/2, 0)
+
+	var b strings.Builder
+	b.WriteString(strings.Repeat("\n", padTop))
+	indent := strings.Repeat(" ", padLeft)
+	for _, line := range strings.Split(modal, "\n") {
+		b.WriteString(indent)
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
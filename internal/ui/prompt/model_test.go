@@ -0,0 +1,36 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+func TestSubmitAndCancel(t *testing.T) {
+	m := New()
+	m.SetSize(80, 24)
+	m.Show("save", "Save to:", "out")
+	if !m.Visible() || !strings.Contains(m.View(), "Save to:") {
+		t.Fatal("Show should open the prompt with its title")
+	}
+
+	m, _ = m.Update(tea.KeyPressMsg{Code: '.', Text: "."})
+	m, _ = m.Update(tea.KeyPressMsg{Code: 'p', Text: "p"})
+	m, cmd := m.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if m.Visible() || cmd == nil {
+		t.Fatal("Enter should close the prompt with a message")
+	}
+	if msg, ok := cmd().(SubmitMsg); !ok || msg.ID != "save" || msg.Value != "out.p" {
+		t.Errorf("got %#v, want SubmitMsg{save, out.p}", cmd())
+	}
+
+	m.Show("open", "Open:", "")
+	m, cmd = m.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if msg, ok := cmd().(CancelMsg); m.Visible() || !ok || msg.ID != "open" {
+		t.Errorf("Esc should cancel, got %#v", cmd())
+	}
+	if m.View() != "" {
+		t.Error("a hidden prompt renders nothing")
+	}
+}
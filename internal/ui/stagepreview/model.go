@@ -0,0 +1,200 @@
+// Package stagepreview provides the overlay showing what the index copy of
+// a file will look like once a line or character selection is staged
+package stagepreview
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+	"github.com/charmbracelet/x/ansi"
+)
+
+var (
+	colorMauve   = lipgloss.Color("#cba6f7")
+	colorBase    = lipgloss.Color("#1e1e2e")
+	colorGreen   = lipgloss.Color("#a6e3a1")
+	colorRed     = lipgloss.Color("#f38ba8")
+	colorText    = lipgloss.Color("#cdd6f4")
+	colorOverlay = lipgloss.Color("#6c7086")
+)
+
+// Context is the number of unchanged index lines shown around the changes
+const Context = 3
+
+var (
+	confirmBinding = key.NewBinding(key.WithKeys("enter", "y"))
+	cancelBinding  = key.NewBinding(key.WithKeys("esc", "n", "q"))
+)
+
+// ConfirmMsg is sent when the previewed stage is accepted
+type ConfirmMsg struct{}
+
+// CancelMsg is sent when the preview is dismissed without staging
+type CancelMsg struct{}
+
+// Model is a hidden-by-default overlay listing the region of the index
+// that a pending stage changes, as it will read afterwards
+type Model struct {
+	path    string
+	lines   []diff.Line
+	err     error
+	visible bool
+	width   int
+	height  int
+}
+
+// New creates a hidden preview
+func New() Model {
+	return Model{}
+}
+
+// Show opens the preview for a file. lines is the whole index copy after
+// staging as returned by git.PreviewStage; err, when set, is why the stage
+// would not apply and leaves only cancelling possible.
+func (m *Model) Show(path string, lines []diff.Line, err error) {
+	m.path = path
+	m.lines = lines
+	m.err = err
+	m.visible = true
+}
+
+// Hide closes the preview without sending a message
+func (m *Model) Hide() {
+	m.visible = false
+}
+
+// Visible returns whether the preview is shown
+func (m Model) Visible() bool {
+	return m.visible
+}
+
+// SetSize updates the preview dimensions
+func (m *Model) SetSize(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+// Update handles Enter/y to stage and Esc/n to cancel
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	if !m.visible {
+		return m, nil
+	}
+
+	keyMsg, ok := msg.(tea.KeyPressMsg)
+	if !ok {
+		return m, nil
+	}
+	switch {
+	case key.Matches(keyMsg, confirmBinding) && m.err == nil:
+		m.Hide()
+		return m, func() tea.Msg { return ConfirmMsg{} }
+	case key.Matches(keyMsg, cancelBinding):
+		m.Hide()
+		return m, func() tea.Msg { return CancelMsg{} }
+	}
+	return m, nil
+}
+
+// Region returns the bounds of the changed lines plus Context lines either
+// side, as indices into the lines passed to Show
+func (m Model) Region() (start, end int) {
+	start, end = -1, -1
+	for i, l := range m.lines {
+		if l.Type != diff.LineContext {
+			if start < 0 {
+				start = i
+			}
+			end = i + 1
+		}
+	}
+	if start < 0 {
+		return 0, 0
+	}
+	return max(start-Context, 0), min(end+Context, len(m.lines))
+}
+
+// View renders the preview as a centered modal. Returns empty string when hidden.
+func (m Model) View() string {
+	if !m.visible {
+		return ""
+	}
+
+	dimStyle := lipgloss.NewStyle().Foreground(colorOverlay)
+	textStyle := lipgloss.NewStyle().Foreground(colorText)
+	addedStyle := lipgloss.NewStyle().Foreground(colorGreen)
+	removedStyle := lipgloss.NewStyle().Foreground(colorRed).Strikethrough(true)
+
+	contentWidth := max(min(m.width-24, 100), 20)
+	maxRows := max(m.height-12, 3)
+
+	var rows []string
+	hint := "Enter/y stage • Esc/n cancel"
+	switch start, end := m.Region(); {
+	case m.err != nil:
+		rows = append(rows, lipgloss.NewStyle().Foreground(colorRed).Render("Would not apply: "+m.err.Error()))
+		hint = "Esc close"
+	case start == end:
+		rows = append(rows, dimStyle.Render("Staging this selection leaves the index unchanged"))
+	default:
+		for i := start; i < end; i++ {
+			if len(rows) == maxRows-1 && end-i > 1 {
+				rows = append(rows, dimStyle.Render(fmt.Sprintf("… %d more lines", end-i)))
+				break
+			}
+			l := m.lines[i]
+			content := ansi.Truncate(l.Content, contentWidth, "…")
+			gutter := dimStyle.Render(fmt.Sprintf("%4s %4s ", number(l.OldNum), number(l.NewNum)))
+			switch l.Type {
+			case diff.LineAdded:
+				rows = append(rows, gutter+addedStyle.Render("+"+content))
+			case diff.LineRemoved:
+				rows = append(rows, gutter+removedStyle.Render("-"+content))
+			default:
+				rows = append(rows, gutter+textStyle.Render(" "+content))
+			}
+		}
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.NewStyle().Bold(true).Foreground(colorText).Render("Index after staging "+m.path),
+		dimStyle.Render("index line now, then after • + enters the index • - leaves it"),
+		"",
+		strings.Join(rows, "\n"),
+		"",
+		dimStyle.Render(hint),
+	)
+
+	modal := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorMauve).
+		Background(colorBase).
+		Padding(1, 2).
+		Render(content)
+
+	padLeft := max((m.width-lipgloss.Width(modal))/2, 0)
+	padTop := max((m.height-lipgloss.Height(modal))/2, 0)
+
+	var b strings.Builder
+	b.WriteString(strings.Repeat("\n", padTop))
+	indent := strings.Repeat(" ", padLeft)
+	for _, line := range strings.Split(modal, "\n") {
+		b.WriteString(indent)
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// number formats a line number for the gutter, blank for 0
+func number(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
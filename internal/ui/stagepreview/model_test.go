@@ -0,0 +1,46 @@
+package stagepreview
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/Danny-Dasilva/gdiff/pkg/diff"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestRegionAndConfirm(t *testing.T) {
+	var lines []diff.Line
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, diff.Line{Type: diff.LineContext, Content: "line", OldNum: i, NewNum: i})
+	}
+	lines[5] = diff.Line{Type: diff.LineRemoved, Content: "old", OldNum: 6}
+	lines = append(lines[:6], append([]diff.Line{{Type: diff.LineAdded, Content: "new", NewNum: 6}}, lines[6:]...)...)
+
+	m := New()
+	m.SetSize(100, 40)
+	m.Show("f.txt", lines, nil)
+	if start, end := m.Region(); start != 2 || end != 10 {
+		t.Errorf("Region() = %d, %d, want 2, 10", start, end)
+	}
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "-old") || !strings.Contains(view, "+new") {
+		t.Errorf("view is missing the changed lines:\n%s", view)
+	}
+
+	m, cmd := m.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if _, ok := cmd().(ConfirmMsg); m.Visible() || !ok {
+		t.Errorf("Enter should confirm, got %#v", cmd())
+	}
+
+	m.Show("f.txt", nil, errors.New("patch does not apply"))
+	if m, cmd = m.Update(tea.KeyPressMsg{Code: tea.KeyEnter}); cmd != nil || !m.Visible() {
+		t.Error("Enter should do nothing when the stage would not apply")
+	}
+	if _, cmd = m.Update(tea.KeyPressMsg{Code: tea.KeyEscape}); cmd == nil {
+		t.Fatal("Esc should cancel")
+	}
+	if _, ok := cmd().(CancelMsg); !ok {
+		t.Errorf("got %#v, want CancelMsg", cmd())
+	}
+}
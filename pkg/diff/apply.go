@@ -0,0 +1,78 @@
+package diff
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ApplyLines applies hunks in memory to the lines of a file and returns
+// every line of the result with what happened to it: context lines were
+// kept, removed lines dropped and added lines inserted. OldNum and NewNum
+// are the line numbers before and after. Like git apply, a hunk whose old
+// lines are not at its stated position is looked for nearby, and a hunk
+// that matches nowhere is an error.
+func ApplyLines(old []string, hunks []Hunk) ([]Line, error) {
+	hunks = slices.Clone(hunks)
+	slices.SortStableFunc(hunks, func(a, b Hunk) int { return a.OldStart - b.OldStart })
+
+	var out []Line
+	next := 0 // index of the first old line not yet copied
+	newNum := 1
+	keep := func(to int) {
+		for ; next < to; next++ {
+			out = append(out, Line{Type: LineContext, Content: old[next], OldNum: next + 1, NewNum: newNum})
+			newNum++
+		}
+	}
+
+	for _, h := range hunks {
+		var want []string
+		for _, l := range h.Lines {
+			if l.Type == LineContext || l.Type == LineRemoved {
+				want = append(want, l.Content)
+			}
+		}
+		// A hunk removing nothing inserts after its start line
+		pos := h.OldStart - 1
+		if len(want) == 0 {
+			pos = h.OldStart
+		}
+		at := findLines(old, want, max(pos, next), next)
+		if at < 0 {
+			return nil, fmt.Errorf("hunk %s does not match the file", h.Header)
+		}
+		keep(at)
+
+		for _, l := range h.Lines {
+			switch l.Type {
+			case LineContext:
+				keep(next + 1)
+			case LineRemoved:
+				out = append(out, Line{Type: LineRemoved, Content: old[next], OldNum: next + 1})
+				next++
+			case LineAdded:
+				out = append(out, Line{Type: LineAdded, Content: l.Content, NewNum: newNum})
+				newNum++
+			}
+		}
+	}
+	keep(len(old))
+	return out, nil
+}
+
+// findLines returns where want occurs in lines at or after from, trying
+// pos first and then positions ever further from it, or -1
+func findLines(lines, want []string, pos, from int) int {
+	matches := func(at int) bool {
+		return at >= from && at+len(want) <= len(lines) && slices.Equal(lines[at:at+len(want)], want)
+	}
+	for d := 0; pos-d >= from || pos+d <= len(lines); d++ {
+		if matches(pos - d) {
+			return pos - d
+		}
+		if matches(pos + d) {
+			return pos + d
+		}
+	}
+	return -1
+}
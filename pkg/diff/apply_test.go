@@ -0,0 +1,43 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyLines(t *testing.T) {
+	old := []string{"a", "b", "c", "d", "e"}
+	// The first hunk's header is two lines off, as after an earlier stage
+	hunks := Parse(`diff --git a/f.txt b/f.txt
+--- a/f.txt
++++ b/f.txt
+@@ -4,2 +4,2 @@
+ b
+-c
++C
+@@ -5,0 +6,1 @@
++x
+`)[0].Hunks
+
+	got, err := ApplyLines(old, hunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, l := range got {
+		mark := map[LineType]string{LineContext: " ", LineAdded: "+", LineRemoved: "-"}[l.Type]
+		lines = append(lines, mark+l.Content)
+	}
+	want := []string{" a", " b", "-c", "+C", " d", " e", "+x"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %v, want %v", lines, want)
+	}
+	if got[6].NewNum != 6 || got[2].OldNum != 3 {
+		t.Errorf("numbers: x new %d, c old %d", got[6].NewNum, got[2].OldNum)
+	}
+
+	bad := Parse("diff --git a/f.txt b/f.txt\n--- a/f.txt\n+++ b/f.txt\n@@ -1,1 +1,1 @@\n-z\n+Z\n")
+	if _, err := ApplyLines(old, bad[0].Hunks); err == nil {
+		t.Error("expected an error for a hunk that does not match")
+	}
+}
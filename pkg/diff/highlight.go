@@ -2,7 +2,6 @@ package diff
 
 import (
 	"regexp"
-	"strings"
 	"unicode/utf8"
 
 	"github.com/sergi/go-diff/diffmatchpatch"
@@ -50,16 +49,42 @@ var codeTokenPattern = regexp.MustCompile(
 	`[a-zA-Z_]\w*|[0-9]+(?:\.[0-9]+)?|&&|\|\||[<>=!]=|:=|->|\.\.\.?|[^\s]|\s+`,
 )
 
-// sentinel is a separator unlikely to appear in code tokens.
-const sentinel = "\x00"
+// maxCharDiffTokens is the number of distinct tokens a line pair may hold;
+// each is encoded as a private-use rune for the token-level diff
+const maxCharDiffTokens = 0xFFFD
+
+// encodeTokens maps every distinct token of both lines to its own rune
+func encodeTokens(oldTokens, newTokens []string) ([]rune, []rune, bool) {
+	codes := make(map[string]rune)
+	encode := func(tokens []string) ([]rune, bool) {
+		out := make([]rune, len(tokens))
+		for i, t := range tokens {
+			c, ok := codes[t]
+			if !ok {
+				if len(codes) >= maxCharDiffTokens {
+					return nil, false
+				}
+				c = rune(0xF0000 + len(codes))
+				codes[t] = c
+			}
+			out[i] = c
+		}
+		return out, true
+	}
+	oldRunes, ok := encode(oldTokens)
+	if !ok {
+		return nil, nil, false
+	}
+	newRunes, ok := encode(newTokens)
+	return oldRunes, newRunes, ok
+}
 
-// tokenize splits a string into code-aware tokens, returning tokens and their
-// byte offsets in the original string.
-func tokenize(s string) (tokens []string, offsets []int) {
-	locs := codeTokenPattern.FindAllStringIndex(s, -1)
-	for _, loc := range locs {
-		tokens = append(tokens, s[loc[0]:loc[1]])
-		offsets = append(offsets, loc[0])
+// tokenize splits a string into tokens, returning tokens and their byte
+// offsets in the original string.
+func tokenize(s string, tokenizer Tokenizer) (tokens []string, offsets []int) {
+	for _, b := range tokenizer(s) {
+		tokens = append(tokens, s[b[0]:b[1]])
+		offsets = append(offsets, b[0])
 	}
 	return
 }
@@ -67,6 +92,12 @@ func tokenize(s string) (tokens []string, offsets []int) {
 // ComputeCharDiff computes character-level differences between two strings
 // using a word+character hybrid algorithm backed by Myers diff (sergi/go-diff).
 func ComputeCharDiff(oldStr, newStr string) ([]CharChange, []CharChange) {
+	return ComputeCharDiffWith(oldStr, newStr, DefaultTokenizer)
+}
+
+// ComputeCharDiffWith is ComputeCharDiff with the word boundaries of a
+// language-aware tokenizer; see TokenizerFor.
+func ComputeCharDiffWith(oldStr, newStr string, tokenizer Tokenizer) ([]CharChange, []CharChange) {
 	if oldStr == newStr {
 		return nil, nil
 	}
@@ -76,8 +107,8 @@ func ComputeCharDiff(oldStr, newStr string) ([]CharChange, []CharChange) {
 		return nil, nil
 	}
 
-	oldTokens, oldOffsets := tokenize(oldStr)
-	newTokens, newOffsets := tokenize(newStr)
+	oldTokens, oldOffsets := tokenize(oldStr, tokenizer)
+	newTokens, newOffsets := tokenize(newStr, tokenizer)
 
 	// Handle edge cases: empty token lists
 	if len(oldTokens) == 0 && len(newTokens) == 0 {
@@ -90,14 +121,15 @@ func ComputeCharDiff(oldStr, newStr string) ([]CharChange, []CharChange) {
 		return []CharChange{{Start: 0, End: utf8.RuneCountInString(oldStr), Added: false}}, nil
 	}
 
-	// Diff at the token level using Myers via sergi/go-diff.
-	// Join tokens with a sentinel separator so diffmatchpatch treats each
-	// token atomically.
-	oldJoined := strings.Join(oldTokens, sentinel)
-	newJoined := strings.Join(newTokens, sentinel)
+	// Diff at the token level using Myers via sergi/go-diff. Each distinct
+	// token is encoded as one rune so diffmatchpatch treats it atomically.
+	oldRunes, newRunes, ok := encodeTokens(oldTokens, newTokens)
+	if !ok {
+		return nil, nil
+	}
 
 	dmp := diffmatchpatch.New()
-	diffs := dmp.DiffMain(oldJoined, newJoined, false)
+	diffs := dmp.DiffMainRunes(oldRunes, newRunes, false)
 	diffs = dmp.DiffCleanupSemantic(diffs)
 
 	// Walk through the token-level diffs and collect changed regions.
@@ -109,17 +141,10 @@ func ComputeCharDiff(oldStr, newStr string) ([]CharChange, []CharChange) {
 	newTokIdx := 0
 
 	for _, d := range diffs {
-		if d.Text == "" {
+		tokCount := utf8.RuneCountInString(d.Text)
+		if tokCount == 0 {
 			continue
 		}
-		// Count tokens in this diff segment by splitting on sentinel.
-		parts := strings.Split(d.Text, sentinel)
-		tokCount := 0
-		for _, p := range parts {
-			if p != "" {
-				tokCount++
-			}
-		}
 
 		switch d.Type {
 		case diffmatchpatch.DiffEqual:
@@ -303,8 +328,18 @@ func levenshteinSimilarity(a, b string) float64 {
 	return 1.0 - float64(dist)/float64(maxLen)
 }
 
-// PairAdjacentLines finds pairs of removed/added lines that are likely related
-// using Levenshtein similarity scoring for better matching.
+// minPairSimilarity is how alike a removed and an added line must be for
+// them to be paired
+const minPairSimilarity = 0.4
+
+// maxAlignCells bounds the similarity matrix of one block; larger blocks are
+// paired in order instead of aligned
+const maxAlignCells = 2500
+
+// PairAdjacentLines finds pairs of removed/added lines that are likely related.
+// Each run of removed lines is aligned with the added run that follows it,
+// keeping both in order and leaving lines without a counterpart unpaired, so
+// a line inserted in the middle of a block does not shift the pairs after it.
 // Returns pairs of (oldIndex, newIndex) for lines that should be compared.
 func PairAdjacentLines(lines []Line) [][2]int {
 	var pairs [][2]int
@@ -322,66 +357,98 @@ func PairAdjacentLines(lines []Line) [][2]int {
 		for i < len(lines) && lines[i].Type == LineRemoved {
 			i++
 		}
-		removeEnd := i
 
 		// Find consecutive added lines
 		addStart := i
 		for i < len(lines) && lines[i].Type == LineAdded {
 			i++
 		}
-		addEnd := i
-
-		removeCount := removeEnd - removeStart
-		addCount := addEnd - addStart
-
-		if removeCount == 0 || addCount == 0 {
-			continue
-		}
 
-		// Fast path: if counts are equal, check if sequential pairing is good
-		if removeCount == addCount {
+		// Fast path: blocks of equal length that are alike line by line
+		// are paired in order without aligning them
+		if n := addStart - removeStart; n > 0 && n == i-addStart {
 			totalSim := 0.0
-			for j := 0; j < removeCount; j++ {
-				totalSim += levenshteinSimilarity(
-					lines[removeStart+j].Content,
-					lines[addStart+j].Content,
-				)
+			for j := 0; j < n; j++ {
+				totalSim += levenshteinSimilarity(lines[removeStart+j].Content, lines[addStart+j].Content)
 			}
-			avgSim := totalSim / float64(removeCount)
-			if avgSim > 0.5 {
-				for j := 0; j < removeCount; j++ {
+			if totalSim/float64(n) > 0.5 {
+				for j := 0; j < n; j++ {
 					pairs = append(pairs, [2]int{removeStart + j, addStart + j})
 				}
 				continue
 			}
 		}
 
-		// Build similarity matrix and do greedy matching
-		usedAdd := make([]bool, addCount)
+		var oldLines, newLines []string
+		for _, l := range lines[removeStart:addStart] {
+			oldLines = append(oldLines, l.Content)
+		}
+		for _, l := range lines[addStart:i] {
+			newLines = append(newLines, l.Content)
+		}
+		for _, p := range AlignLines(oldLines, newLines) {
+			pairs = append(pairs, [2]int{removeStart + p[0], addStart + p[1]})
+		}
+	}
+
+	return pairs
+}
 
-		for ri := 0; ri < removeCount; ri++ {
-			bestJ := -1
-			bestSim := 0.4 // threshold
-			for aj := 0; aj < addCount; aj++ {
-				if usedAdd[aj] {
-					continue
-				}
-				sim := levenshteinSimilarity(
-					lines[removeStart+ri].Content,
-					lines[addStart+aj].Content,
-				)
-				if sim > bestSim {
-					bestSim = sim
-					bestJ = aj
-				}
+// AlignLines pairs removed lines with added lines by dynamic programming,
+// maximizing the total similarity of the pairs. Pairs keep the order of
+// both sides and need a similarity above minPairSimilarity; any line may stay
+// unpaired. Returns (oldIndex, newIndex) pairs in order.
+func AlignLines(oldLines, newLines []string) [][2]int {
+	n, m := len(oldLines), len(newLines)
+	if n == 0 || m == 0 {
+		return nil
+	}
+	if n*m > maxAlignCells {
+		var pairs [][2]int
+		for j := 0; j < min(n, m); j++ {
+			if levenshteinSimilarity(oldLines[j], newLines[j]) > minPairSimilarity {
+				pairs = append(pairs, [2]int{j, j})
 			}
-			if bestJ >= 0 {
-				pairs = append(pairs, [2]int{removeStart + ri, addStart + bestJ})
-				usedAdd[bestJ] = true
+		}
+		return pairs
+	}
+
+	// score[i][j] is the best total for oldLines[i:] and newLines[j:]
+	sim := make([][]float64, n)
+	score := make([][]float64, n+1)
+	for i := range score {
+		score[i] = make([]float64, m+1)
+	}
+	for i := range sim {
+		sim[i] = make([]float64, m)
+		for j := range sim[i] {
+			sim[i][j] = levenshteinSimilarity(oldLines[i], newLines[j])
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			best := max(score[i+1][j], score[i][j+1])
+			if sim[i][j] > minPairSimilarity {
+				best = max(best, sim[i][j]+score[i+1][j+1])
 			}
+			score[i][j] = best
 		}
 	}
 
+	// Walk the table forwards, preferring to pair whenever pairing is optimal
+	var pairs [][2]int
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case sim[i][j] > minPairSimilarity && score[i][j] == sim[i][j]+score[i+1][j+1]:
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+		case score[i][j] == score[i+1][j]:
+			i++
+		default:
+			j++
+		}
+	}
 	return pairs
 }
 
@@ -393,6 +460,12 @@ type HighlightedLine struct {
 
 // ComputeHighlightedDiff computes character-level highlighting for a hunk
 func ComputeHighlightedDiff(hunk Hunk) []HighlightedLine {
+	return ComputeHighlightedDiffWith(hunk, DefaultTokenizer)
+}
+
+// ComputeHighlightedDiffWith is ComputeHighlightedDiff with a language-aware
+// tokenizer, as the diff view uses
+func ComputeHighlightedDiffWith(hunk Hunk, tokenizer Tokenizer) []HighlightedLine {
 	result := make([]HighlightedLine, len(hunk.Lines))
 
 	// Initialize with no highlighting
@@ -413,7 +486,7 @@ func ComputeHighlightedDiff(hunk Hunk) []HighlightedLine {
 		oldLine := hunk.Lines[oldIdx].Content
 		newLine := hunk.Lines[newIdx].Content
 
-		oldChanges, newChanges := ComputeCharDiff(oldLine, newLine)
+		oldChanges, newChanges := ComputeCharDiffWith(oldLine, newLine, tokenizer)
 		result[oldIdx].Changes = oldChanges
 		result[newIdx].Changes = newChanges
 	}
@@ -1,6 +1,7 @@
 package diff
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 	"unicode/utf8"
@@ -343,6 +344,21 @@ func TestPairAdjacentLinesUnequal(t *testing.T) {
 		}
 	})
 
+	t.Run("large equal blocks pair in order", func(t *testing.T) {
+		// Too big to align; alike line by line, so every line pairs
+		var lines []Line
+		for i := 0; i < 200; i++ {
+			lines = append(lines, Line{Type: LineRemoved, Content: fmt.Sprintf("value%d := compute(%d)", i, i)})
+		}
+		for i := 0; i < 200; i++ {
+			lines = append(lines, Line{Type: LineAdded, Content: fmt.Sprintf("value%d := compute(%d, opts)", i, i)})
+		}
+		pairs := PairAdjacentLines(lines)
+		if len(pairs) != 200 || pairs[199] != [2]int{199, 399} {
+			t.Errorf("expected 200 in-order pairs, got %d", len(pairs))
+		}
+	})
+
 	t.Run("single remove no adds", func(t *testing.T) {
 		lines := []Line{
 			{Type: LineRemoved, Content: "old1"},
@@ -560,3 +576,31 @@ func TestComputeHighlightedDiffIntegration(t *testing.T) {
 		}
 	})
 }
+
+func TestAlignLinesSkipsInsertedLines(t *testing.T) {
+	oldLines := []string{
+		"    first := load(a)",
+		"    second := load(b)",
+		"    third := load(c)",
+	}
+	newLines := []string{
+		"    first := load(a, opts)",
+		"    log.Printf(\"loading\")",
+		"    second := load(b, opts)",
+		"    third := load(c, opts)",
+	}
+	pairs := AlignLines(oldLines, newLines)
+	want := [][2]int{{0, 0}, {1, 2}, {2, 3}}
+	if len(pairs) != len(want) {
+		t.Fatalf("got pairs %v, want %v", pairs, want)
+	}
+	for i := range want {
+		if pairs[i] != want[i] {
+			t.Errorf("pair %d = %v, want %v", i, pairs[i], want[i])
+		}
+	}
+
+	if pairs := AlignLines([]string{"abc"}, []string{"xyz"}); len(pairs) != 0 {
+		t.Errorf("dissimilar lines should stay unpaired, got %v", pairs)
+	}
+}
@@ -0,0 +1,152 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// LineRef addresses one line of a multi-file diff
+type LineRef struct {
+	File int
+	Hunk int
+	Line int
+}
+
+// Move is a block of removed lines that reappears as added lines elsewhere
+// in the changeset. From and To correspond line by line.
+type Move struct {
+	From  []LineRef
+	To    []LineRef
+	Exact bool // false when lines were edited or reindented along the way
+}
+
+const (
+	// minMovedAlnum is the number of alphanumeric characters a block needs
+	// before it counts as moved, the same threshold git's --color-moved uses
+	minMovedAlnum = 20
+
+	// minMovedSimilarity is how alike two lines inside a moved block must
+	// be to continue it when they are not identical
+	minMovedSimilarity = 0.6
+
+	// maxMoveLines bounds the changed lines considered, since matching is
+	// quadratic on repetitive input
+	maxMoveLines = 20000
+)
+
+// movedLine is a changed line with the run of same-typed lines it belongs to
+type movedLine struct {
+	ref     LineRef
+	run     int
+	content string
+	key     string
+}
+
+// DetectMoves finds blocks of removed lines that were added back elsewhere,
+// within a hunk, across hunks or across files. Each line belongs to at most
+// one move, and a block only moves to a run of added lines other than the
+// one directly replacing it. Blocks start on an identical line and may
+// continue through lines that were edited slightly.
+func DetectMoves(files []FileDiff) []Move {
+	var removed, added []movedLine
+	run := 0
+	for fi, fd := range files {
+		for hi, h := range fd.Hunks {
+			prev := LineContext
+			for li, line := range h.Lines {
+				if line.Type != prev {
+					run++
+					prev = line.Type
+				}
+				ml := movedLine{
+					ref:     LineRef{File: fi, Hunk: hi, Line: li},
+					run:     run,
+					content: line.Content,
+					key:     strings.TrimSpace(line.Content),
+				}
+				switch line.Type {
+				case LineRemoved:
+					removed = append(removed, ml)
+				case LineAdded:
+					added = append(added, ml)
+				}
+			}
+		}
+	}
+	if len(removed) == 0 || len(added) == 0 || len(removed)+len(added) > maxMoveLines {
+		return nil
+	}
+
+	byKey := make(map[string][]int)
+	for j, l := range added {
+		if l.key != "" {
+			byKey[l.key] = append(byKey[l.key], j)
+		}
+	}
+
+	usedAdded := make([]bool, len(added))
+	var moves []Move
+	for i := 0; i < len(removed); {
+		bestStart, bestLen := -1, 0
+		for _, j := range byKey[removed[i].key] {
+			// The added run right after a removed run replaces it in place;
+			// char-level highlighting already covers that case
+			if usedAdded[j] || added[j].run == removed[i].run+1 {
+				continue
+			}
+			if n := matchLength(removed, added, usedAdded, i, j); n > bestLen {
+				bestStart, bestLen = j, n
+			}
+		}
+
+		if bestLen == 0 || alnumCount(removed[i:i+bestLen]) < minMovedAlnum {
+			i++
+			continue
+		}
+
+		mv := Move{Exact: true}
+		for k := 0; k < bestLen; k++ {
+			from, to := removed[i+k], added[bestStart+k]
+			usedAdded[bestStart+k] = true
+			mv.From = append(mv.From, from.ref)
+			mv.To = append(mv.To, to.ref)
+			if from.content != to.content {
+				mv.Exact = false
+			}
+		}
+		moves = append(moves, mv)
+		i += bestLen
+	}
+	return moves
+}
+
+// matchLength returns how many lines match starting at removed[i] and
+// added[j], staying inside both runs and ending on an identical line
+func matchLength(removed, added []movedLine, usedAdded []bool, i, j int) int {
+	n, last := 0, 0
+	for i+n < len(removed) && j+n < len(added) {
+		r, a := removed[i+n], added[j+n]
+		if r.run != removed[i].run || a.run != added[j].run || usedAdded[j+n] {
+			break
+		}
+		if r.key == a.key {
+			last = n + 1
+		} else if levenshteinSimilarity(r.key, a.key) < minMovedSimilarity {
+			break
+		}
+		n++
+	}
+	return last
+}
+
+func alnumCount(lines []movedLine) int {
+	count := 0
+	for _, l := range lines {
+		for _, r := range l.content {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				count++
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,82 @@
+package diff
+
+import (
+	"testing"
+)
+
+const movedDiff = `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,6 +1,2 @@
+ package a
+-func helper(x int) int {
+-	return x * 2
+-}
+ 
+ var y = 1
+diff --git a/b.go b/b.go
+--- a/b.go
++++ b/b.go
+@@ -1,2 +1,6 @@
+ package b
++
++func helper(x int) int {
++	return x * 3
++}
+ var z = 2
+`
+
+func TestDetectMovesAcrossFiles(t *testing.T) {
+	files := Parse(movedDiff)
+	moves := DetectMoves(files)
+	if len(moves) != 1 {
+		t.Fatalf("got %d moves, want 1", len(moves))
+	}
+
+	mv := moves[0]
+	if mv.Exact {
+		t.Error("move with an edited line should not be exact")
+	}
+	if len(mv.From) != 3 || len(mv.To) != 3 {
+		t.Fatalf("move spans %d -> %d lines, want 3 -> 3", len(mv.From), len(mv.To))
+	}
+	from := files[0].Hunks[0].Lines[mv.From[0].Line]
+	to := files[mv.To[0].File].Hunks[0].Lines[mv.To[0].Line]
+	if mv.From[0].File != 0 || mv.To[0].File != 1 || from.Content != to.Content {
+		t.Errorf("move starts at %+v -> %+v", mv.From[0], mv.To[0])
+	}
+	if files[1].Hunks[0].Lines[mv.To[1].Line].Content != "\treturn x * 3" {
+		t.Error("edited line should be paired with its counterpart")
+	}
+}
+
+func TestDetectMovesIgnoresInPlaceEdits(t *testing.T) {
+	files := Parse(`diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,3 +1,3 @@
+ package a
+-var someLongVariableName = computeTheValue()
++var someLongVariableName = computeTheValue()
+ var y = 1
+`)
+	if moves := DetectMoves(files); len(moves) != 0 {
+		t.Errorf("in-place replacement detected as %d move(s)", len(moves))
+	}
+}
+
+func TestDetectMovesNeedsEnoughContent(t *testing.T) {
+	files := Parse(`diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,5 +1,5 @@
+-}
+ package a
+ var y = 1
+ var z = 2
++}
+`)
+	if moves := DetectMoves(files); len(moves) != 0 {
+		t.Errorf("trivial line detected as %d move(s)", len(moves))
+	}
+}
@@ -19,6 +19,10 @@ func Parse(diffOutput string) []FileDiff {
 	var currentFile *FileDiff
 	var currentHunk *Hunk
 	oldLineNum, newLineNum := 0, 0
+	// Lines of the current hunk still expected on each side; once both run
+	// out, anything up to the next header (such as the commit message in
+	// git log -p output) is not part of the hunk
+	oldLeft, newLeft := 0, 0
 
 	for _, line := range lines {
 
@@ -75,11 +79,12 @@ func Parse(diffOutput string) []FileDiff {
 
 			oldLineNum = oldStart
 			newLineNum = newStart
+			oldLeft, newLeft = oldCount, newCount
 			continue
 		}
 
-		// Skip other header lines
-		if currentHunk == nil {
+		// Skip other header lines and anything after a complete hunk
+		if currentHunk == nil || (oldLeft <= 0 && newLeft <= 0 && !strings.HasPrefix(line, "\\")) {
 			continue
 		}
 
@@ -94,6 +99,8 @@ func Parse(diffOutput string) []FileDiff {
 			})
 			oldLineNum++
 			newLineNum++
+			oldLeft--
+			newLeft--
 		} else {
 			prefix := line[0]
 			content := ""
@@ -109,6 +116,7 @@ func Parse(diffOutput string) []FileDiff {
 					NewNum:  newLineNum,
 				})
 				newLineNum++
+				newLeft--
 			case '-':
 				currentHunk.Lines = append(currentHunk.Lines, Line{
 					Type:    LineRemoved,
@@ -116,6 +124,7 @@ func Parse(diffOutput string) []FileDiff {
 					OldNum:  oldLineNum,
 				})
 				oldLineNum++
+				oldLeft--
 			case ' ':
 				currentHunk.Lines = append(currentHunk.Lines, Line{
 					Type:    LineContext,
@@ -125,6 +134,8 @@ func Parse(diffOutput string) []FileDiff {
 				})
 				oldLineNum++
 				newLineNum++
+				oldLeft--
+				newLeft--
 			case '\\':
 				// "\ No newline at end of file" - skip
 				continue
@@ -1,6 +1,7 @@
 package diff
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -108,3 +109,109 @@ diff --git a/file2.go b/file2.go
 		t.Errorf("expected second file 'file2.go', got '%s'", result[1].OldPath)
 	}
 }
+
+// TestParseHunkEnds checks that a hunk ends once its header's line counts
+// are used up, so text that follows it is not taken for diff lines
+func TestParseHunkEnds(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  [][]string // per file, the hunk lines after each header
+	}{
+		{
+			name: "git log -p",
+			input: `commit 1111111111111111111111111111111111111111
+Author: Test <test@example.com>
+Date:   Mon Jan 1 00:00:00 2024 +0000
+
+    Second commit
+
+    - with a bullet
+    + and another
+
+diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+ a
+-b
++B
+
+commit 0000000000000000000000000000000000000000
+Author: Test <test@example.com>
+Date:   Mon Jan 1 00:00:00 2024 +0000
+
+    First commit
+
+diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -0,0 +1,2 @@
++a
++b
+`,
+			want: [][]string{{" a", "-b", "+B"}, {"+a", "+b"}},
+		},
+		{
+			name: "no newline after the last line",
+			input: `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++B
+\ No newline at end of file
+`,
+			want: [][]string{{" a", "-b", "+B"}},
+		},
+		{
+			name: "counts shorter than the body",
+			input: `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+ a
+-b
++B
+ c
+-d
+`,
+			want: [][]string{{" a", "-b", "+B"}},
+		},
+		{
+			name: "body shorter than the counts",
+			input: `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,3 +1,3 @@
+ a
+-b
++B`,
+			want: [][]string{{" a", "-b", "+B"}},
+		},
+	}
+
+	prefixes := map[LineType]string{LineContext: " ", LineAdded: "+", LineRemoved: "-"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Parse(tt.input)
+			if len(result) != len(tt.want) {
+				t.Fatalf("expected %d file diffs, got %d", len(tt.want), len(result))
+			}
+			for i, fd := range result {
+				if len(fd.Hunks) != 1 {
+					t.Fatalf("file %d: expected 1 hunk, got %d", i, len(fd.Hunks))
+				}
+				var got []string
+				for _, line := range fd.Hunks[0].Lines[1:] {
+					got = append(got, prefixes[line.Type]+line.Content)
+				}
+				if strings.Join(got, "\n") != strings.Join(tt.want[i], "\n") {
+					t.Errorf("file %d: hunk lines = %q, want %q", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package diff
+
+import (
+	"regexp"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// SelectRange returns the indices of the changed lines of a hunk that fall
+// within new-file lines from..to. A removed line sits at the new-file
+// position of the next line that survives it, so deleting lines 41-42
+// between new lines 40 and 41 is selected by a range containing 41.
+func SelectRange(h Hunk, from, to int) []int {
+	var indices []int
+	pos := h.NewStart + h.NewCount
+	for i := len(h.Lines) - 1; i >= 0; i-- {
+		l := h.Lines[i]
+		switch l.Type {
+		case LineContext:
+			pos = l.NewNum
+		case LineAdded:
+			pos = l.NewNum
+			if pos >= from && pos <= to {
+				indices = append(indices, i)
+			}
+		case LineRemoved:
+			if pos >= from && pos <= to {
+				indices = append(indices, i)
+			}
+		}
+	}
+	for i, j := 0, len(indices)-1; i < j; i, j = i+1, j-1 {
+		indices[i], indices[j] = indices[j], indices[i]
+	}
+	return indices
+}
+
+// SelectMatching returns the indices of the changed lines of a hunk whose
+// content matches re
+func SelectMatching(h Hunk, re *regexp.Regexp) []int {
+	var indices []int
+	for i, l := range h.Lines {
+		if (l.Type == LineAdded || l.Type == LineRemoved) && re.MatchString(l.Content) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// FindAddedLine returns the index of the added line with new-file number
+// num, or -1 when the hunk does not add that line
+func FindAddedLine(h Hunk, num int) int {
+	for i, l := range h.Lines {
+		if l.Type == LineAdded && l.NewNum == num {
+			return i
+		}
+	}
+	return -1
+}
+
+// PairedLine returns the index of the line an added or removed line
+// replaces, or -1 when it has none. Within a block of removed lines
+// followed by added lines, the nth added line pairs with the nth removed
+// one.
+func PairedLine(h Hunk, i int) int {
+	if i < 0 || i >= len(h.Lines) {
+		return -1
+	}
+	t := h.Lines[i].Type
+	if t != LineAdded && t != LineRemoved {
+		return -1
+	}
+	start := i
+	for start > 0 && (h.Lines[start-1].Type == LineAdded || h.Lines[start-1].Type == LineRemoved) {
+		start--
+	}
+	var removed, added []int
+block:
+	for j := start; j < len(h.Lines); j++ {
+		switch {
+		case h.Lines[j].Type == LineRemoved && len(added) == 0:
+			removed = append(removed, j)
+		case h.Lines[j].Type == LineAdded:
+			added = append(added, j)
+		default:
+			break block
+		}
+	}
+	own, other := removed, added
+	if t == LineAdded {
+		own, other = added, removed
+	}
+	for k, j := range own {
+		if j == i && k < len(other) {
+			return other[k]
+		}
+	}
+	return -1
+}
+
+// StageRange returns old with only the changes of new that touch runes
+// start to end (exclusive) of new applied: characters new inserts there
+// are added, and text it deletes at or inside the range is removed. The
+// rest of old is kept as it is.
+func StageRange(old, new string, start, end int) string {
+	dmp := diffmatchpatch.New()
+	var b []rune
+	pos := 0
+	for _, d := range dmp.DiffMainRunes([]rune(old), []rune(new), false) {
+		runes := []rune(d.Text)
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			b = append(b, runes...)
+			pos += len(runes)
+		case diffmatchpatch.DiffInsert:
+			for _, r := range runes {
+				if pos >= start && pos < end {
+					b = append(b, r)
+				}
+				pos++
+			}
+		case diffmatchpatch.DiffDelete:
+			if pos < start || pos > end {
+				b = append(b, runes...)
+			}
+		}
+	}
+	return string(b)
+}
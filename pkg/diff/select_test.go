@@ -0,0 +1,96 @@
+package diff
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// selectHunk changes new lines 11-12 and deletes old line 15
+var selectHunk = Hunk{
+	OldStart: 10, OldCount: 6, NewStart: 10, NewCount: 5,
+	Lines: []Line{
+		{Type: LineHunkHeader, Content: "@@ -10,6 +10,5 @@"},
+		{Type: LineContext, Content: "a", OldNum: 10, NewNum: 10},
+		{Type: LineRemoved, Content: "b", OldNum: 11},
+		{Type: LineAdded, Content: "B()", NewNum: 11},
+		{Type: LineAdded, Content: "log(B)", NewNum: 12},
+		{Type: LineContext, Content: "c", OldNum: 12, NewNum: 13},
+		{Type: LineContext, Content: "d", OldNum: 13, NewNum: 14},
+		{Type: LineRemoved, Content: "log(e)", OldNum: 14},
+	},
+}
+
+func TestSelectRange(t *testing.T) {
+	tests := []struct {
+		from, to int
+		want     []int
+	}{
+		{11, 11, []int{2, 3}},
+		{12, 12, []int{4}},
+		{13, 14, nil},
+		{15, 20, []int{7}},
+		{1, 100, []int{2, 3, 4, 7}},
+	}
+	for _, tt := range tests {
+		if got := SelectRange(selectHunk, tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SelectRange(%d, %d) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestSelectMatching(t *testing.T) {
+	got := SelectMatching(selectHunk, regexp.MustCompile(`^log\(`))
+	if want := []int{4, 7}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SelectMatching = %v, want %v", got, want)
+	}
+}
+
+func TestFindAddedLine(t *testing.T) {
+	if got := FindAddedLine(selectHunk, 12); got != 4 {
+		t.Errorf("FindAddedLine(12) = %d, want 4", got)
+	}
+	if got := FindAddedLine(selectHunk, 13); got != -1 {
+		t.Errorf("FindAddedLine(13) = %d, want -1 for a context line", got)
+	}
+}
+
+func TestStageRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		old, new   string
+		start, end int
+		want       string
+	}{
+		{"whole insertion", "hello world", "hello there world", 6, 12, "hello there world"},
+		{"part of an insertion", "hello world", "hello there world", 6, 9, "hello theworld"},
+		{"one of two changes", "x := compute(a)", "y := compute(a, b)", 14, 17, "x := compute(a, b)"},
+		{"replacement", "x := compute(a)", "y := compute(a, b)", 0, 1, "y := compute(a)"},
+		{"unchanged text", "x := compute(a)", "y := compute(a, b)", 5, 12, "x := compute(a)"},
+		{"deletion inside the range", "keep old text", "keep new text", 5, 8, "keep new text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StageRange(tt.old, tt.new, tt.start, tt.end); got != tt.want {
+				t.Errorf("StageRange() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPairedLine(t *testing.T) {
+	h := Hunk{Lines: []Line{
+		{Type: LineContext},
+		{Type: LineRemoved},
+		{Type: LineRemoved},
+		{Type: LineAdded},
+		{Type: LineAdded},
+		{Type: LineAdded},
+		{Type: LineContext},
+	}}
+	for i, want := range []int{-1, 3, 4, 1, 2, -1, -1} {
+		if got := PairedLine(h, i); got != want {
+			t.Errorf("PairedLine(%d) = %d, want %d", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package diff
+
+// AlignBlock lays out a block of n removed and m added lines as side-by-side
+// rows of block indices, -1 for an empty side. pairs holds (removed, added)
+// indices in order, as returned by AlignLines. Paired lines share a row and
+// the unpaired lines between two pairs fill rows next to each other.
+func AlignBlock(n, m int, pairs [][2]int) [][2]int {
+	var rows [][2]int
+	i, j := 0, 0
+	fill := func(toI, toJ int) {
+		for i < toI || j < toJ {
+			row := [2]int{-1, -1}
+			if i < toI {
+				row[0] = i
+				i++
+			}
+			if j < toJ {
+				row[1] = j
+				j++
+			}
+			rows = append(rows, row)
+		}
+	}
+	for _, p := range pairs {
+		fill(p[0], p[1])
+		rows = append(rows, p)
+		i, j = p[0]+1, p[1]+1
+	}
+	fill(n, m)
+	return rows
+}
+
+// SideBySide lays out the lines of a hunk as rows of (old, new) line
+// indices, -1 for an empty side. Hunk headers and context lines take both
+// sides of their row; changed lines are aligned per block using pairs from
+// PairAdjacentLines.
+func SideBySide(lines []Line, pairs [][2]int) [][2]int {
+	partner := make(map[int]int, len(pairs))
+	for _, p := range pairs {
+		partner[p[0]] = p[1]
+	}
+
+	var rows [][2]int
+	for i := 0; i < len(lines); {
+		if lines[i].Type != LineRemoved && lines[i].Type != LineAdded {
+			rows = append(rows, [2]int{i, i})
+			i++
+			continue
+		}
+		removedStart := i
+		for i < len(lines) && lines[i].Type == LineRemoved {
+			i++
+		}
+		addedStart := i
+		for i < len(lines) && lines[i].Type == LineAdded {
+			i++
+		}
+
+		var blockPairs [][2]int
+		for k := removedStart; k < addedStart; k++ {
+			if p, ok := partner[k]; ok && p >= addedStart && p < i {
+				blockPairs = append(blockPairs, [2]int{k - removedStart, p - addedStart})
+			}
+		}
+		for _, r := range AlignBlock(addedStart-removedStart, i-addedStart, blockPairs) {
+			row := [2]int{-1, -1}
+			if r[0] >= 0 {
+				row[0] = removedStart + r[0]
+			}
+			if r[1] >= 0 {
+				row[1] = addedStart + r[1]
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
@@ -0,0 +1,30 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSideBySide(t *testing.T) {
+	lines := []Line{
+		{Type: LineContext, Content: "a"},
+		{Type: LineRemoved, Content: "old b"},
+		{Type: LineRemoved, Content: "gone"},
+		{Type: LineAdded, Content: "new b"},
+		{Type: LineContext, Content: "c"},
+		{Type: LineAdded, Content: "d"},
+	}
+	got := SideBySide(lines, [][2]int{{1, 3}})
+	want := [][2]int{{0, 0}, {1, 3}, {2, -1}, {4, 4}, {-1, 5}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+}
+
+func TestAlignBlockFillsBetweenPairs(t *testing.T) {
+	got := AlignBlock(3, 3, [][2]int{{2, 0}})
+	want := [][2]int{{0, -1}, {1, -1}, {2, 0}, {-1, 1}, {-1, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+}
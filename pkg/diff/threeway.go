@@ -0,0 +1,250 @@
+package diff
+
+import "fmt"
+
+// Provenance says which of HEAD, the index and the working tree a line of a
+// three-way diff changed between
+type Provenance int
+
+const (
+	// Unchanged lines are the same in HEAD, the index and the worktree
+	Unchanged Provenance = iota
+	// Staged lines were added or removed in the index and the worktree
+	// agrees
+	Staged
+	// Unstaged lines were added or removed in the worktree only
+	Unstaged
+	// IndexOnly lines were added in the index and removed again in the
+	// worktree
+	IndexOnly
+)
+
+// Origin ties a line of a three-way diff to the lines of the staged and
+// unstaged diffs it stands for. Indices point into ThreeWay.Staged.Lines
+// and ThreeWay.Unstaged.Lines, -1 for none.
+type Origin struct {
+	Provenance Provenance
+	Staged     int
+	Unstaged   int
+}
+
+// ThreeWay is a file's diff from HEAD to the working tree with each change
+// attributed to the index or the worktree. File holds the merged hunks and
+// Origins[h][j] describes File.Hunks[h].Lines[j].
+type ThreeWay struct {
+	Staged   *Hunk // HEAD to index, the whole file as one hunk; nil when nothing is staged
+	Unstaged *Hunk // index to worktree, likewise
+	File     FileDiff
+	Origins  [][]Origin
+}
+
+// indexLine is a line of the index copy of a file and where it stands in
+// HEAD and the worktree
+type indexLine struct {
+	content  string
+	head     int // line number in HEAD, 0 when the index added it
+	work     int // line number in the worktree, 0 when removed there
+	staged   int // index of its addition in the staged hunk, -1
+	unstaged int // index of its removal in the unstaged hunk, -1
+}
+
+// MergeThreeWay merges a file's staged and unstaged diffs, each produced
+// with the whole file as context so it is a single hunk (or nil when that
+// side has no changes), into one diff from HEAD to the worktree. Changes
+// are grouped into hunks with context unchanged lines around them.
+func MergeThreeWay(path string, staged, unstaged *Hunk, context int) ThreeWay {
+	tw := ThreeWay{Staged: staged, Unstaged: unstaged, File: FileDiff{OldPath: path, NewPath: path}}
+
+	// The index is the new side of the staged diff and the old side of the
+	// unstaged one
+	var index []indexLine
+	switch {
+	case staged != nil:
+		for _, l := range staged.Lines {
+			if l.Type == LineContext || l.Type == LineAdded {
+				index = append(index, indexLine{content: l.Content, staged: -1, unstaged: -1})
+			}
+		}
+	case unstaged != nil:
+		for _, l := range unstaged.Lines {
+			if l.Type == LineContext || l.Type == LineRemoved {
+				index = append(index, indexLine{content: l.Content, staged: -1, unstaged: -1})
+			}
+		}
+	}
+
+	// Lines that are not in the index sit before index line k, or at the
+	// end for k == len(index)
+	removedBefore := make([][]Line, len(index)+1)
+	removedOrigin := make([][]Origin, len(index)+1)
+	addedBefore := make([][]Line, len(index)+1)
+	addedOrigin := make([][]Origin, len(index)+1)
+
+	k := 0
+	if staged != nil {
+		for i, l := range staged.Lines {
+			switch {
+			case l.Type == LineRemoved:
+				removedBefore[k] = append(removedBefore[k], Line{Type: LineRemoved, Content: l.Content, OldNum: l.OldNum})
+				removedOrigin[k] = append(removedOrigin[k], Origin{Provenance: Staged, Staged: i, Unstaged: -1})
+			case k >= len(index):
+			case l.Type == LineContext:
+				index[k].head = l.OldNum
+				k++
+			case l.Type == LineAdded:
+				index[k].staged = i
+				k++
+			}
+		}
+	} else {
+		for i := range index {
+			index[i].head = i + 1
+		}
+	}
+
+	k = 0
+	if unstaged != nil {
+		for i, l := range unstaged.Lines {
+			switch {
+			case l.Type == LineAdded:
+				addedBefore[k] = append(addedBefore[k], Line{Type: LineAdded, Content: l.Content, NewNum: l.NewNum})
+				addedOrigin[k] = append(addedOrigin[k], Origin{Provenance: Unstaged, Staged: -1, Unstaged: i})
+			case k >= len(index):
+			case l.Type == LineContext:
+				index[k].work = l.NewNum
+				k++
+			case l.Type == LineRemoved:
+				index[k].unstaged = i
+				k++
+			}
+		}
+	} else {
+		for i := range index {
+			index[i].work = i + 1
+		}
+	}
+
+	var lines []Line
+	var origins []Origin
+	for k := 0; k <= len(index); k++ {
+		lines = append(lines, removedBefore[k]...)
+		origins = append(origins, removedOrigin[k]...)
+		lines = append(lines, addedBefore[k]...)
+		origins = append(origins, addedOrigin[k]...)
+		if k == len(index) {
+			break
+		}
+
+		il := index[k]
+		o := Origin{Staged: il.staged, Unstaged: il.unstaged}
+		l := Line{Content: il.content, OldNum: il.head, NewNum: il.work}
+		switch {
+		case il.head > 0 && il.work > 0:
+			l.Type, o.Provenance = LineContext, Unchanged
+		case il.work > 0:
+			l.Type, o.Provenance = LineAdded, Staged
+		case il.head > 0:
+			l.Type, o.Provenance = LineRemoved, Unstaged
+		default:
+			l.Type, o.Provenance = LineRemoved, IndexOnly
+		}
+		lines = append(lines, l)
+		origins = append(origins, o)
+	}
+
+	tw.File.Hunks, tw.Origins = groupHunks(lines, origins, context)
+	return tw
+}
+
+// groupHunks splits merged lines into hunks holding each change with up to
+// context unchanged lines around it
+func groupHunks(lines []Line, origins []Origin, context int) ([]Hunk, [][]Origin) {
+	var hunks []Hunk
+	var hunkOrigins [][]Origin
+
+	start, end := -1, -1 // the current hunk is lines[start:end]
+	flush := func() {
+		if start < 0 {
+			return
+		}
+		h := Hunk{}
+		o := []Origin{{Staged: -1, Unstaged: -1}}
+		// An empty side starts at the line before the hunk
+		oldBefore, newBefore := 0, 0
+		for i := range start {
+			if lines[i].OldNum > 0 {
+				oldBefore = lines[i].OldNum
+			}
+			if lines[i].NewNum > 0 {
+				newBefore = lines[i].NewNum
+			}
+		}
+		for i := start; i < end; i++ {
+			l := lines[i]
+			if l.OldNum > 0 {
+				if h.OldCount == 0 {
+					h.OldStart = l.OldNum
+				}
+				h.OldCount++
+			}
+			if l.NewNum > 0 {
+				if h.NewCount == 0 {
+					h.NewStart = l.NewNum
+				}
+				h.NewCount++
+			}
+			h.Lines = append(h.Lines, l)
+			o = append(o, origins[i])
+		}
+		if h.OldCount == 0 {
+			h.OldStart = oldBefore
+		}
+		if h.NewCount == 0 {
+			h.NewStart = newBefore
+		}
+		h.Header = fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldCount, h.NewStart, h.NewCount)
+		h.Lines = append([]Line{{Type: LineHunkHeader, Content: h.Header}}, h.Lines...)
+		hunks = append(hunks, h)
+		hunkOrigins = append(hunkOrigins, o)
+		start, end = -1, -1
+	}
+
+	for i := range lines {
+		if origins[i].Provenance != Unchanged {
+			from := max(i-context, 0)
+			if start >= 0 && from > end {
+				flush()
+			}
+			if start < 0 {
+				start = from
+			}
+			end = min(i+context+1, len(lines))
+		}
+	}
+	flush()
+	return hunks, hunkOrigins
+}
+
+// StageIndices returns the lines of the unstaged hunk behind the selected
+// lines of merged hunk h, for staging them with the unstaged diff
+func (t ThreeWay) StageIndices(h int, lines []int) []int {
+	var indices []int
+	for _, j := range lines {
+		if o := t.Origins[h][j]; o.Unstaged >= 0 {
+			indices = append(indices, o.Unstaged)
+		}
+	}
+	return indices
+}
+
+// UnstageIndices returns the lines of the staged hunk behind the selected
+// lines of merged hunk h, for unstaging them with the staged diff
+func (t ThreeWay) UnstageIndices(h int, lines []int) []int {
+	var indices []int
+	for _, j := range lines {
+		if o := t.Origins[h][j]; o.Staged >= 0 {
+			indices = append(indices, o.Staged)
+		}
+	}
+	return indices
+}
@@ -0,0 +1,92 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// HEAD is a..f. The index changes b to B and adds x; the worktree drops x
+// again and changes f to F.
+const (
+	threeWayStaged = `diff --git a/f.txt b/f.txt
+--- a/f.txt
++++ b/f.txt
+@@ -1,6 +1,7 @@
+ a
+-b
++B
+ c
++x
+ d
+ e
+ f
+`
+	threeWayUnstaged = `diff --git a/f.txt b/f.txt
+--- a/f.txt
++++ b/f.txt
+@@ -1,7 +1,6 @@
+ a
+ B
+ c
+-x
+ d
+ e
+-f
++F
+`
+)
+
+func TestMergeThreeWay(t *testing.T) {
+	staged := Parse(threeWayStaged)[0].Hunks[0]
+	unstaged := Parse(threeWayUnstaged)[0].Hunks[0]
+
+	tw := MergeThreeWay("f.txt", &staged, &unstaged, 1)
+	if len(tw.File.Hunks) != 1 {
+		t.Fatalf("got %d hunks, want 1", len(tw.File.Hunks))
+	}
+	h := tw.File.Hunks[0]
+	if h.Header != "@@ -1,6 +1,6 @@" {
+		t.Errorf("header = %q", h.Header)
+	}
+
+	var got []string
+	for j, l := range h.Lines[1:] {
+		mark := map[LineType]string{LineContext: " ", LineAdded: "+", LineRemoved: "-"}[l.Type]
+		prov := "=SUI"[tw.Origins[0][j+1].Provenance]
+		got = append(got, string(prov)+mark+l.Content)
+	}
+	want := []string{"= a", "S-b", "S+B", "= c", "I-x", "= d", "= e", "U-f", "U+F"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lines = %v, want %v", got, want)
+	}
+
+	// x is the staged addition at line 5 of the staged hunk and the
+	// unstaged removal at line 4 of the unstaged one
+	if got := tw.StageIndices(0, []int{5}); !reflect.DeepEqual(got, []int{4}) {
+		t.Errorf("StageIndices(x) = %v, want [4]", got)
+	}
+	if got := tw.UnstageIndices(0, []int{2, 3, 8, 9}); !reflect.DeepEqual(got, []int{2, 3}) {
+		t.Errorf("UnstageIndices(b, B, f, F) = %v, want [2 3]", got)
+	}
+}
+
+func TestMergeThreeWayHunks(t *testing.T) {
+	staged := Parse(threeWayStaged)[0].Hunks[0]
+	unstaged := Parse(threeWayUnstaged)[0].Hunks[0]
+
+	var headers []string
+	for _, h := range MergeThreeWay("f.txt", &staged, &unstaged, 0).File.Hunks {
+		headers = append(headers, h.Header)
+	}
+	want := "@@ -2,1 +2,1 @@ @@ -3,0 +3,0 @@ @@ -6,1 +6,1 @@"
+	if got := strings.Join(headers, " "); got != want {
+		t.Errorf("headers = %s, want %s", got, want)
+	}
+
+	// With only one side changed, the other side's numbers follow the index
+	tw := MergeThreeWay("f.txt", nil, &unstaged, 0)
+	if got := tw.File.Hunks[0].Lines[1]; got.Content != "x" || got.OldNum != 4 || tw.Origins[0][1].Provenance != Unstaged {
+		t.Errorf("first unstaged-only line = %+v", got)
+	}
+}
@@ -0,0 +1,111 @@
+package diff
+
+import (
+	"regexp"
+	"sync"
+)
+
+// Tokenizer splits a line into the tokens ComputeCharDiff compares, returning
+// the [start, end) byte offsets of each token in order. Tokens must not be
+// empty or overlap; bytes left out of every token are never highlighted.
+type Tokenizer func(s string) [][2]int
+
+// RegexpTokenizer returns a Tokenizer yielding every match of re
+func RegexpTokenizer(re *regexp.Regexp) Tokenizer {
+	return func(s string) [][2]int {
+		var bounds [][2]int
+		for _, loc := range re.FindAllStringIndex(s, -1) {
+			if loc[1] > loc[0] {
+				bounds = append(bounds, [2]int{loc[0], loc[1]})
+			}
+		}
+		return bounds
+	}
+}
+
+// escapeToken matches a backslash escape sequence as one token, so an edited
+// escape does not bleed into the word after it
+const escapeToken = `\\(?:x[0-9a-fA-F]{2}|u\{[0-9a-fA-F]+\}|u[0-9a-fA-F]{4}|U[0-9a-fA-F]{8}|[0-7]{1,3}|.)`
+
+// DefaultTokenizer is used for languages without a tokenizer of their own
+var DefaultTokenizer = RegexpTokenizer(codeTokenPattern)
+
+var (
+	// C-like languages with backslash escapes in string literals
+	escapeTokenizer = RegexpTokenizer(regexp.MustCompile(
+		escapeToken + `|` + codeTokenPattern.String(),
+	))
+
+	// Rust adds lifetimes, char literals, paths and raw identifiers
+	rustTokenizer = RegexpTokenizer(regexp.MustCompile(
+		`'(?:` + escapeToken + `|[^'\\])'|'[a-zA-Z_]\w*|r#[a-zA-Z_]\w*|` +
+			escapeToken + `|::|=>|\.\.=|` + codeTokenPattern.String(),
+	))
+
+	// Shell variables and expansions, dashed command names and flags
+	shellTokenizer = RegexpTokenizer(regexp.MustCompile(
+		`\$\{[^}\s]*\}|\$\(\(?|\$[a-zA-Z_]\w*|\$[0-9#?@*$!-]|` +
+			`[0-9]?[<>]&[0-9-]?|--?[a-zA-Z][\w-]*|[a-zA-Z_][\w-]*|[0-9]+|` + escapeToken +
+			`|&&|\|\||;;|>>|<<|\|&|[^\s]|\s+`,
+	))
+
+	// CSS dashed properties and custom properties, hex colors and ids,
+	// dimensions and !important
+	cssTokenizer = RegexpTokenizer(regexp.MustCompile(
+		`#[\w-]+|-{0,2}[a-zA-Z_][\w-]*|-?[0-9]*\.?[0-9]+(?:[a-zA-Z]+|%)?|` +
+			`![a-zA-Z]+|::|[~|^$*]?=|[^\s]|\s+`,
+	))
+
+	// Lisp symbols run up to the next delimiter, so dashes, ?, ! and * are
+	// part of them; :keywords and reader macros stay whole
+	lispTokenizer = RegexpTokenizer(regexp.MustCompile(
+		escapeToken + "|#'|#\\(|,@|[^\\s()\\[\\]{}\"'`;,\\\\]+|[^\\s]|\\s+",
+	))
+)
+
+var (
+	tokenizersMu sync.RWMutex
+	tokenizers   = map[string]Tokenizer{
+		"go":         escapeTokenizer,
+		"javascript": escapeTokenizer,
+		"typescript": escapeTokenizer,
+		"python":     escapeTokenizer,
+		"ruby":       escapeTokenizer,
+		"java":       escapeTokenizer,
+		"scala":      escapeTokenizer,
+		"groovy":     escapeTokenizer,
+		"csharp":     escapeTokenizer,
+		"kotlin":     escapeTokenizer,
+		"c":          escapeTokenizer,
+		"cpp":        escapeTokenizer,
+		"swift":      escapeTokenizer,
+		"json":       escapeTokenizer,
+		"proto":      escapeTokenizer,
+		"elixir":     escapeTokenizer,
+		"rust":       rustTokenizer,
+		"shell":      shellTokenizer,
+		"make":       shellTokenizer,
+		"dockerfile": shellTokenizer,
+		"css":        cssTokenizer,
+		"lisp":       lispTokenizer,
+	}
+)
+
+// RegisterTokenizer sets the tokenizer used for a language, named as in
+// pkg/syntax, replacing any previous one
+func RegisterTokenizer(lang string, t Tokenizer) {
+	tokenizersMu.Lock()
+	defer tokenizersMu.Unlock()
+	tokenizers[lang] = t
+}
+
+// TokenizerFor returns the tokenizer registered for a language, or
+// DefaultTokenizer
+func TokenizerFor(lang string) Tokenizer {
+	tokenizersMu.RLock()
+	defer tokenizersMu.RUnlock()
+	if t, ok := tokenizers[lang]; ok {
+		return t
+	}
+	return DefaultTokenizer
+}
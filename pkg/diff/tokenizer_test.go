@@ -0,0 +1,100 @@
+package diff
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// tokenStrings renders a tokenizer's output as the non-space tokens joined
+// by "|" for compact comparisons
+func tokenStrings(t Tokenizer, s string) string {
+	var parts []string
+	for _, b := range t(s) {
+		if tok := s[b[0]:b[1]]; strings.TrimSpace(tok) != "" {
+			parts = append(parts, tok)
+		}
+	}
+	return strings.Join(parts, "|")
+}
+
+func TestTokenizers(t *testing.T) {
+	tests := []struct {
+		lang string
+		line string
+		want string
+	}{
+		{"", `x := a.b(c) && d`, `x|:=|a|.|b|(|c|)|&&|d`},
+		{"go", `s := "a\nb\x41"`, `s|:=|"|a|\n|b|\x41|"`},
+		{"javascript", `"ét\u{1F600}"`, `"|é|t|\u{1F600}|"`},
+		{"python", `print("tab\there")`, `print|(|"|tab|\t|here|"|)`},
+		{"rust", `fn f<'a>(x: &'a str) -> char { 'x' }`, `fn|f|<|'a|>|(|x|:|&|'a|str|)|->|char|{|'x'|}`},
+		{"rust", `std::mem::take(r#type, '\n', 0..=9)`, `std|::|mem|::|take|(|r#type|,|'\n'|,|0|..=|9|)`},
+		{"shell", `echo "$HOME/${DIR}" $1 $(pwd) 2>&1`, `echo|"|$HOME|/|${DIR}|"|$1|$(|pwd|)|2>&1`},
+		{"shell", `apt-get install -y --no-install-recommends curl && rm -rf x`, `apt-get|install|-y|--no-install-recommends|curl|&&|rm|-rf|x`},
+		{"css", `.btn-primary { --gap: 1.5rem; color: #fff !important; }`, `.|btn-primary|{|--gap|:|1.5rem|;|color|:|#fff|!important|;|}`},
+		{"css", `-webkit-transition: width 50%;`, `-webkit-transition|:|width|50%|;`},
+		{"lisp", `(defun my-fun? (x) (list* :key 'x #'car ,@rest))`, `(|defun|my-fun?|(|x|)|(|list*|:key|'|x|#'|car|,@|rest|)|)`},
+		{"lisp", `(str "a\"b")`, `(|str|"|a|\"|b|"|)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang+" "+tt.line, func(t *testing.T) {
+			if got := tokenStrings(TokenizerFor(tt.lang), tt.line); got != tt.want {
+				t.Errorf("tokens = %s\nwant     %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizersCoverEveryByte(t *testing.T) {
+	line := "  x-y $Z 'a' \\n #f0f (é) \t"
+	for _, lang := range []string{"", "go", "rust", "shell", "css", "lisp"} {
+		pos := 0
+		for _, b := range TokenizerFor(lang)(line) {
+			if b[0] != pos || b[1] <= b[0] {
+				t.Fatalf("%q: token %v does not continue at %d", lang, b, pos)
+			}
+			pos = b[1]
+		}
+		if pos != len(line) {
+			t.Errorf("%q: tokens end at %d, want %d", lang, pos, len(line))
+		}
+	}
+}
+
+func TestRegisterTokenizer(t *testing.T) {
+	t.Cleanup(func() {
+		tokenizersMu.Lock()
+		defer tokenizersMu.Unlock()
+		delete(tokenizers, "test-words")
+	})
+
+	called := false
+	words := RegexpTokenizer(regexp.MustCompile(`\S+|\s+`))
+	RegisterTokenizer("test-words", func(s string) [][2]int {
+		called = true
+		return words(s)
+	})
+	if got := TokenizerFor("test-words")("a-b c"); !called || !reflect.DeepEqual(got, [][2]int{{0, 3}, {3, 4}, {4, 5}}) {
+		t.Errorf("registered tokenizer not used: called=%v tokens=%v", called, got)
+	}
+
+	old, new := ComputeCharDiffWith("a-b c", "a-x c", TokenizerFor("test-words"))
+	if len(old) != 1 || old[0].Start != 2 || len(new) != 1 || new[0].Start != 2 {
+		t.Errorf("got old=%v new=%v, want the changed character only", old, new)
+	}
+
+	if reflect.ValueOf(TokenizerFor("unregistered")).Pointer() != reflect.ValueOf(DefaultTokenizer).Pointer() {
+		t.Error("unregistered languages should fall back to DefaultTokenizer")
+	}
+}
+
+func TestComputeCharDiffWithLanguage(t *testing.T) {
+	// Renaming a dashed Lisp symbol is one word change, not two
+	old, new := ComputeCharDiffWith("(my-old-name x)", "(my-new-name x)", TokenizerFor("lisp"))
+	if len(old) != 1 || len(new) != 1 {
+		t.Errorf("got old=%v new=%v, want a single change on each side", old, new)
+	}
+}
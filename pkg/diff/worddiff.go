@@ -0,0 +1,195 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// WordDeletion is removed text placed at the spot in the new lines where
+// it used to be, for inline rendering
+type WordDeletion struct {
+	Line int // Index into the new lines
+	At   int // Rune offset within that line
+	Text string
+}
+
+// WordDiffResult holds the word-level changes of a paragraph. Old and New
+// are indexed like the lines passed to WordDiff, with rune offsets.
+type WordDiffResult struct {
+	Old     [][]CharChange
+	New     [][]CharChange
+	Deleted []WordDeletion
+}
+
+// wordToken is a word or whitespace run. Line is -1 for the virtual
+// separator standing in for a line break.
+type wordToken struct {
+	text       string
+	line       int
+	start, end int
+	space      bool
+}
+
+// tokenizeWords splits lines into words and whitespace runs, joined by
+// separators so text reflowed across lines still matches
+func tokenizeWords(lines []string) []wordToken {
+	var tokens []wordToken
+	for li, line := range lines {
+		if li > 0 {
+			tokens = append(tokens, wordToken{text: " ", line: -1, space: true})
+		}
+		runes := []rune(line)
+		for i := 0; i < len(runes); {
+			space := unicode.IsSpace(runes[i])
+			j := i + 1
+			for j < len(runes) && unicode.IsSpace(runes[j]) == space {
+				j++
+			}
+			tokens = append(tokens, wordToken{text: string(runes[i:j]), line: li, start: i, end: j, space: space})
+			i = j
+		}
+	}
+	return tokens
+}
+
+// maxWordTokens is the number of distinct words a paragraph pair may hold;
+// each is encoded as a private-use rune for the diff
+const maxWordTokens = 0xFFFD
+
+// WordDiff compares removed lines with the added lines replacing them as
+// whole paragraphs at word granularity, like git diff --word-diff. Line
+// breaks and whitespace amounts are ignored, so a reflowed paragraph only
+// shows the words that actually changed.
+func WordDiff(oldLines, newLines []string) WordDiffResult {
+	res := WordDiffResult{
+		Old: make([][]CharChange, len(oldLines)),
+		New: make([][]CharChange, len(newLines)),
+	}
+	oldTokens, newTokens := tokenizeWords(oldLines), tokenizeWords(newLines)
+
+	// Encode tokens as runes, all whitespace sharing one code
+	codes := make(map[string]rune)
+	encode := func(tokens []wordToken) ([]rune, bool) {
+		out := make([]rune, len(tokens))
+		for i, t := range tokens {
+			key := t.text
+			if t.space {
+				key = " "
+			}
+			c, ok := codes[key]
+			if !ok {
+				if len(codes) >= maxWordTokens {
+					return nil, false
+				}
+				c = rune(0xF0000 + len(codes))
+				codes[key] = c
+			}
+			out[i] = c
+		}
+		return out, true
+	}
+	oldRunes, ok1 := encode(oldTokens)
+	newRunes, ok2 := encode(newTokens)
+	if !ok1 || !ok2 {
+		return res
+	}
+
+	dmp := diffmatchpatch.New()
+	diffs := dmp.DiffCleanupSemantic(dmp.DiffMainRunes(oldRunes, newRunes, false))
+
+	oi, ni := 0, 0
+	for _, d := range diffs {
+		n := utf8.RuneCountInString(d.Text)
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			oi += n
+			ni += n
+		case diffmatchpatch.DiffDelete:
+			if !allSpace(oldTokens[oi : oi+n]) {
+				markTokens(res.Old, oldTokens[oi:oi+n], false)
+				res.Deleted = append(res.Deleted, deletionAt(newTokens, ni, newLines, oldTokens[oi:oi+n])...)
+			}
+			oi += n
+		case diffmatchpatch.DiffInsert:
+			if !allSpace(newTokens[ni : ni+n]) {
+				markTokens(res.New, newTokens[ni:ni+n], true)
+			}
+			ni += n
+		}
+	}
+	return res
+}
+
+func allSpace(tokens []wordToken) bool {
+	for _, t := range tokens {
+		if !t.space {
+			return false
+		}
+	}
+	return true
+}
+
+// markTokens records changed tokens per line, merging adjacent ranges and
+// leaving out whitespace at either end of a run
+func markTokens(changes [][]CharChange, tokens []wordToken, added bool) {
+	for len(tokens) > 0 && tokens[0].space {
+		tokens = tokens[1:]
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1].space {
+		tokens = tokens[:len(tokens)-1]
+	}
+	for _, t := range tokens {
+		if t.line < 0 {
+			continue
+		}
+		cs := changes[t.line]
+		if n := len(cs); n > 0 && cs[n-1].End == t.start {
+			cs[n-1].End = t.end
+		} else if !t.space {
+			cs = append(cs, CharChange{Start: t.start, End: t.end, Added: added})
+		}
+		changes[t.line] = cs
+	}
+}
+
+// deletionAt joins deleted tokens into one deletion anchored before new
+// token ni, or after the last line when there is none. Separators and blank
+// lines have no position of their own, so a deletion there goes to the end
+// of the previous word, or before the next one when nothing precedes it.
+func deletionAt(newTokens []wordToken, ni int, newLines []string, deleted []wordToken) []WordDeletion {
+	if len(newLines) == 0 {
+		return nil
+	}
+	line, at := len(newLines)-1, utf8.RuneCountInString(newLines[len(newLines)-1])
+	if ni < len(newTokens) {
+		if prev := nearestToken(newTokens, ni-1, -1); newTokens[ni].line < 0 && prev >= 0 {
+			line, at = newTokens[prev].line, newTokens[prev].end
+		} else if next := nearestToken(newTokens, ni, 1); next >= 0 {
+			line, at = newTokens[next].line, newTokens[next].start
+		}
+	}
+
+	var b strings.Builder
+	for _, t := range deleted {
+		b.WriteString(t.text)
+	}
+	text := strings.TrimSpace(strings.Join(strings.Fields(b.String()), " "))
+	if text == "" {
+		return nil
+	}
+	return []WordDeletion{{Line: line, At: at, Text: text}}
+}
+
+// nearestToken walks from token i in direction step to the first token on
+// a line, returning -1 when there is none
+func nearestToken(tokens []wordToken, i, step int) int {
+	for ; i >= 0 && i < len(tokens); i += step {
+		if tokens[i].line >= 0 {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,86 @@
+package diff
+
+import (
+	"testing"
+)
+
+// changedText returns the text covered by changes on one line
+func changedText(line string, changes []CharChange) []string {
+	runes := []rune(line)
+	var out []string
+	for _, c := range changes {
+		out = append(out, string(runes[c.Start:c.End]))
+	}
+	return out
+}
+
+func TestWordDiffIgnoresReflow(t *testing.T) {
+	old := []string{
+		"The quick brown fox jumps over",
+		"the lazy dog.",
+	}
+	new := []string{
+		"The quick red fox",
+		"jumps over the lazy dog.",
+	}
+	res := WordDiff(old, new)
+
+	if got := changedText(old[0], res.Old[0]); len(got) != 1 || got[0] != "brown" {
+		t.Errorf("old changes = %q, want [brown]", got)
+	}
+	if len(res.Old[1]) != 0 || len(res.New[1]) != 0 {
+		t.Errorf("reflowed line should have no changes, got %v / %v", res.Old[1], res.New[1])
+	}
+	if got := changedText(new[0], res.New[0]); len(got) != 1 || got[0] != "red" {
+		t.Errorf("new changes = %q, want [red]", got)
+	}
+	if len(res.Deleted) != 1 || res.Deleted[0] != (WordDeletion{Line: 0, At: 10, Text: "brown"}) {
+		t.Errorf("deleted = %+v, want brown before red", res.Deleted)
+	}
+}
+
+func TestWordDiffMergesAdjacentWords(t *testing.T) {
+	res := WordDiff([]string{"keep this text"}, []string{"keep all of that text"})
+	if got := changedText("keep all of that text", res.New[0]); len(got) != 1 || got[0] != "all of that" {
+		t.Errorf("new changes = %q, want one run", got)
+	}
+}
+
+func TestWordDiffDeletionAtEnd(t *testing.T) {
+	res := WordDiff([]string{"first line", "second line trailing"}, []string{"first line", "second line"})
+	if len(res.Deleted) != 1 {
+		t.Fatalf("deleted = %+v", res.Deleted)
+	}
+	if d := res.Deleted[0]; d.Line != 1 || d.At != len("second line") || d.Text != "trailing" {
+		t.Errorf("deletion = %+v, want at end of second line", d)
+	}
+}
+
+func TestWordDiffDeletionBetweenBlankLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new []string
+		want     WordDeletion
+	}{
+		{
+			name: "after a word",
+			old:  []string{"alpha", "removed", "", "beta"},
+			new:  []string{"alpha", "", "", "beta"},
+			want: WordDeletion{Line: 0, At: len("alpha"), Text: "removed"},
+		},
+		{
+			name: "before the first word",
+			old:  []string{"", "removed", "", "beta"},
+			new:  []string{"", "", "", "beta"},
+			want: WordDeletion{Line: 3, At: 0, Text: "removed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := WordDiff(tt.old, tt.new)
+			if len(res.Deleted) != 1 || res.Deleted[0] != tt.want {
+				t.Errorf("deleted = %+v, want %+v", res.Deleted, tt.want)
+			}
+		})
+	}
+}
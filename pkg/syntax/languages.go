@@ -0,0 +1,294 @@
+package syntax
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Language describes how to highlight one language
+type Language struct {
+	Name             string
+	LineComment      []string
+	BlockComments    [][2]string
+	MultiLineStrings [][2]string
+	Quotes           string
+	CaseSensitive    bool
+
+	keywords map[string]bool
+	types    map[string]bool
+	builtins map[string]bool
+}
+
+func newLanguage(l Language, keywords, types, builtins string) *Language {
+	set := func(words string) map[string]bool {
+		m := make(map[string]bool)
+		for _, w := range strings.Fields(words) {
+			m[w] = true
+		}
+		return m
+	}
+	l.keywords = set(keywords)
+	l.types = set(types)
+	l.builtins = set(builtins)
+	return &l
+}
+
+var (
+	cComments = [][2]string{{"/*", "*/"}}
+
+	golang = newLanguage(Language{
+		Name: "go", LineComment: []string{"//"}, BlockComments: cComments,
+		MultiLineStrings: [][2]string{{"`", "`"}}, Quotes: `"'`, CaseSensitive: true,
+	},
+		"break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var",
+		"bool byte complex64 complex128 error float32 float64 int int8 int16 int32 int64 rune string uint uint8 uint16 uint32 uint64 uintptr any comparable",
+		"true false nil iota append cap clear close complex copy delete imag len make max min new panic print println real recover")
+
+	javascript = newLanguage(Language{
+		Name: "javascript", LineComment: []string{"//"}, BlockComments: cComments,
+		MultiLineStrings: [][2]string{{"`", "`"}}, Quotes: `"'`, CaseSensitive: true,
+	},
+		"async await break case catch class const continue debugger default delete do else export extends finally for from function if import in instanceof let new of return static super switch this throw try typeof var void while with yield",
+		"Array Boolean Date Error Map Number Object Promise RegExp Set String Symbol",
+		"true false null undefined NaN Infinity console window document require module")
+
+	typescript = newLanguage(Language{
+		Name: "typescript", LineComment: []string{"//"}, BlockComments: cComments,
+		MultiLineStrings: [][2]string{{"`", "`"}}, Quotes: `"'`, CaseSensitive: true,
+	},
+		"abstract as async await break case catch class const continue declare default delete do else enum export extends finally for from function if implements import in instanceof interface keyof let namespace new of private protected public readonly return satisfies static super switch this throw try type typeof var void while yield",
+		"any boolean never number object string symbol unknown void Array Date Error Map Promise Record Partial Readonly Set",
+		"true false null undefined console require")
+
+	python = newLanguage(Language{
+		Name: "python", LineComment: []string{"#"},
+		MultiLineStrings: [][2]string{{`"""`, `"""`}, {"'''", "'''"}}, Quotes: `"'`, CaseSensitive: true,
+	},
+		"and as assert async await break class continue def del elif else except finally for from global if import in is lambda match case nonlocal not or pass raise return try while with yield",
+		"bool bytes dict float frozenset int list object set str tuple type",
+		"True False None self cls print len range enumerate isinstance super open zip map filter sorted")
+
+	rust = newLanguage(Language{
+		Name: "rust", LineComment: []string{"//"}, BlockComments: cComments, Quotes: `"`, CaseSensitive: true,
+	},
+		"as async await break const continue crate dyn else enum extern fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait type unsafe use where while",
+		"bool char f32 f64 i8 i16 i32 i64 i128 isize str u8 u16 u32 u64 u128 usize String Vec Option Result Box",
+		"true false Some None Ok Err println print format vec panic assert assert_eq")
+
+	ruby = newLanguage(Language{
+		Name: "ruby", LineComment: []string{"#"}, BlockComments: [][2]string{{"=begin", "=end"}}, Quotes: `"'`, CaseSensitive: true,
+	},
+		"alias and begin break case class def defined do else elsif end ensure for if in module next not or redo rescue retry return self super then undef unless until when while yield",
+		"",
+		"true false nil puts require attr_accessor attr_reader private protected public")
+
+	java = newLanguage(Language{
+		Name: "java", LineComment: []string{"//"}, BlockComments: cComments,
+		MultiLineStrings: [][2]string{{`"""`, `"""`}}, Quotes: `"'`, CaseSensitive: true,
+	},
+		"abstract assert break case catch class continue default do else enum extends final finally for if implements import instanceof interface native new package private protected public return static super switch synchronized this throw throws try var void volatile while def val object trait",
+		"boolean byte char double float int long short String Integer Object List Map",
+		"true false null")
+
+	kotlin = newLanguage(Language{
+		Name: "kotlin", LineComment: []string{"//"}, BlockComments: cComments,
+		MultiLineStrings: [][2]string{{`"""`, `"""`}}, Quotes: `"'`, CaseSensitive: true,
+	},
+		"as break class continue data do else enum fun for if import in interface is object override package private protected public return sealed suspend this throw try val var when while",
+		"Any Boolean Byte Char Double Float Int List Long Map Set Short String Unit",
+		"true false null println listOf mapOf setOf")
+
+	clang = newLanguage(Language{
+		Name: "c", LineComment: []string{"//"}, BlockComments: cComments, Quotes: `"'`, CaseSensitive: true,
+	},
+		"break case const continue default do else enum extern for goto if inline register return sizeof static struct switch typedef union volatile while",
+		"bool char double float int long short signed unsigned void size_t int8_t int16_t int32_t int64_t uint8_t uint16_t uint32_t uint64_t",
+		"true false NULL")
+
+	cpp = newLanguage(Language{
+		Name: "cpp", LineComment: []string{"//"}, BlockComments: cComments, Quotes: `"'`, CaseSensitive: true,
+	},
+		"auto break case catch class const constexpr continue default delete do else enum explicit export extern for friend goto if inline namespace new noexcept operator override private protected public return sizeof static struct switch template this throw try typedef typename union using virtual volatile while async await base foreach in is let lock out ref var",
+		"bool char double float int long short signed unsigned void string vector map size_t",
+		"true false nullptr NULL null std")
+
+	swift = newLanguage(Language{
+		Name: "swift", LineComment: []string{"//"}, BlockComments: cComments,
+		MultiLineStrings: [][2]string{{`"""`, `"""`}}, Quotes: `"`, CaseSensitive: true,
+	},
+		"as break case catch class continue default defer do else enum extension fileprivate for func guard if import in init inout internal let private protocol public return self static struct switch throw throws try var where while",
+		"Any Bool Character Double Float Int String Array Dictionary Optional",
+		"true false nil print")
+
+	php = newLanguage(Language{
+		Name: "php", LineComment: []string{"//", "#"}, BlockComments: cComments, Quotes: `"'`, CaseSensitive: false,
+	},
+		"abstract and as break case catch class const continue default do echo else elseif extends final for foreach function if implements interface namespace new or private protected public return static switch throw trait try use while",
+		"array bool float int string void",
+		"true false null")
+
+	shell = newLanguage(Language{
+		Name: "shell", LineComment: []string{"#"}, Quotes: `"'`, CaseSensitive: true,
+	},
+		"case do done elif else esac export fi for function if in local return then until while",
+		"",
+		"echo cd exit set unset source read printf test")
+
+	sql = newLanguage(Language{
+		Name: "sql", LineComment: []string{"--"}, BlockComments: cComments, Quotes: `'"`, CaseSensitive: false,
+	},
+		"add alter and as asc by case create default delete desc distinct drop else end exists from group having if in index inner insert into is join key left like limit not null on or order outer primary references right select set table then union unique update values view when where with model enum",
+		"bigint boolean char date decimal float int integer serial text timestamp varchar",
+		"true false count sum avg min max now")
+
+	lua = newLanguage(Language{
+		Name: "lua", LineComment: []string{"--"}, MultiLineStrings: [][2]string{{"[[", "]]"}}, Quotes: `"'`, CaseSensitive: true,
+	},
+		"and break do else elseif end for function goto if in local not or repeat return then until while",
+		"",
+		"true false nil print pairs ipairs require self")
+
+	css = newLanguage(Language{
+		Name: "css", LineComment: []string{"//"}, BlockComments: cComments, Quotes: `"'`, CaseSensitive: false,
+	},
+		"@media @import @keyframes @font-face @supports",
+		"",
+		"inherit initial none auto")
+
+	yaml = newLanguage(Language{
+		Name: "yaml", LineComment: []string{"#"}, Quotes: `"'`, CaseSensitive: true,
+	},
+		"", "", "true false null yes no on off")
+
+	toml = newLanguage(Language{
+		Name: "toml", LineComment: []string{"#"},
+		MultiLineStrings: [][2]string{{`"""`, `"""`}, {"'''", "'''"}}, Quotes: `"'`, CaseSensitive: true,
+	},
+		"", "", "true false")
+
+	json = newLanguage(Language{
+		Name: "json", LineComment: []string{"//"}, Quotes: `"`, CaseSensitive: true,
+	},
+		"", "", "true false null")
+
+	proto = newLanguage(Language{
+		Name: "proto", LineComment: []string{"//"}, BlockComments: cComments, Quotes: `"'`, CaseSensitive: true,
+	},
+		"enum import message oneof option package repeated reserved returns rpc service syntax optional",
+		"bool bytes double fixed32 fixed64 float int32 int64 sint32 sint64 string uint32 uint64",
+		"true false")
+
+	elixir = newLanguage(Language{
+		Name: "elixir", LineComment: []string{"#"}, MultiLineStrings: [][2]string{{`"""`, `"""`}}, Quotes: `"'`, CaseSensitive: true,
+	},
+		"after alias case catch cond def defmodule defp defstruct do else end fn for if import quote receive require rescue try unless use when with",
+		"",
+		"true false nil")
+
+	graphql = newLanguage(Language{
+		Name: "graphql", LineComment: []string{"#"}, MultiLineStrings: [][2]string{{`"""`, `"""`}}, Quotes: `"`, CaseSensitive: true,
+	},
+		"enum extend fragment implements input interface mutation on query scalar schema subscription type union",
+		"Boolean Float ID Int String",
+		"true false null")
+
+	lisp = newLanguage(Language{
+		Name: "lisp", LineComment: []string{";"}, BlockComments: [][2]string{{"#|", "|#"}}, Quotes: `"`, CaseSensitive: true,
+	},
+		"cond def define defmacro defn defun defvar do if lambda let loop ns progn quote setq unless when",
+		"",
+		"car cdr cons first list map nil rest t true false")
+
+	csharp = newLanguage(Language{
+		Name: "csharp", LineComment: []string{"//"}, BlockComments: cComments,
+		MultiLineStrings: [][2]string{{`@"`, `"`}, {`"""`, `"""`}}, Quotes: `"'`, CaseSensitive: true,
+	},
+		"abstract as async await base break case catch class const continue default delegate do else enum event explicit extern finally fixed for foreach get goto if implicit in init interface internal is lock namespace new operator out override params private protected public readonly record ref return sealed set sizeof stackalloc static struct switch this throw try typeof unsafe using var virtual void volatile when where while yield",
+		"bool byte char decimal double dynamic float int long nint nuint object sbyte short string uint ulong ushort List Dictionary Task",
+		"true false null value nameof")
+
+	scala = newLanguage(Language{
+		Name: "scala", LineComment: []string{"//"}, BlockComments: cComments,
+		MultiLineStrings: [][2]string{{`"""`, `"""`}}, Quotes: `"'`, CaseSensitive: true,
+	},
+		"abstract case catch class def do else enum extends final finally for given if implicit import lazy match new object override package private protected return sealed super then this throw trait try type using val var while with yield",
+		"Any AnyRef Boolean Byte Char Double Float Int List Long Map Nothing Option Seq Set Short String Unit Vector",
+		"true false null None Some println")
+
+	groovy = newLanguage(Language{
+		Name: "groovy", LineComment: []string{"//"}, BlockComments: cComments,
+		MultiLineStrings: [][2]string{{`"""`, `"""`}, {", "}}, Quotes: `"'`, CaseSensitive: true,
+	},
+		"as assert break case catch class def default do else enum extends final finally for if implements import in instanceof interface new package private protected public return static super switch this throw throws trait try var while",
+		"boolean byte char double float int long short void Closure List Map Object String",
+		"true false null it println")
+
+	makefile = newLanguage(Language{
+		Name: "make", LineComment: []string{"#"}, Quotes: `"'`, CaseSensitive: true,
+	},
+		"define else endef endif export ifdef ifeq ifndef ifneq include override private undefine unexport vpath",
+		"",
+		"abspath addprefix addsuffix basename call dir error eval filter filter-out findstring firstword foreach info notdir patsubst realpath shell sort strip subst suffix warning wildcard word words")
+
+	dockerfile = newLanguage(Language{
+		Name: "dockerfile", LineComment: []string{"#"}, Quotes: `"'`, CaseSensitive: false,
+	},
+		"add arg as cmd copy entrypoint env expose from healthcheck label maintainer onbuild run shell stopsignal user volume workdir",
+		"",
+		"")
+)
+
+// byExtension maps file extensions, as recognized by the file tree icons,
+// to languages
+var byExtension = map[string]*Language{
+	".go": golang,
+	".js": javascript, ".mjs": javascript, ".cjs": javascript, ".jsx": javascript, ".vue": javascript, ".svelte": javascript,
+	".ts": typescript, ".mts": typescript, ".cts": typescript, ".tsx": typescript,
+	".py": python, ".pyw": python, ".pyi": python,
+	".rs": rust,
+	".rb": ruby, ".rake": ruby,
+	".java":  java,
+	".scala": scala, ".sc": scala,
+	".groovy": groovy, ".gradle": groovy,
+	".kt": kotlin, ".kts": kotlin,
+	".c": clang, ".h": clang, ".m": clang,
+	".cpp": cpp, ".hpp": cpp, ".cc": cpp, ".cxx": cpp, ".hxx": cpp, ".mm": cpp,
+	".cs":    csharp,
+	".swift": swift,
+	".php":   php,
+	".sh":    shell, ".bash": shell, ".zsh": shell, ".fish": shell,
+	".sql": sql,
+	".lua": lua,
+	".css": css, ".scss": css, ".sass": css, ".less": css,
+	".yaml": yaml, ".yml": yaml,
+	".toml": toml,
+	".json": json, ".jsonc": json,
+	".proto": proto,
+	".ex":    elixir, ".exs": elixir,
+	".graphql": graphql, ".gql": graphql,
+	".lisp": lisp, ".lsp": lisp, ".el": lisp, ".scm": lisp, ".ss": lisp, ".rkt": lisp,
+	".clj": lisp, ".cljs": lisp, ".cljc": lisp, ".edn": lisp, ".fnl": lisp,
+	".mk": makefile, ".mak": makefile,
+	".dockerfile": dockerfile,
+}
+
+// byName maps well-known file names without a useful extension
+var byName = map[string]*Language{
+	"makefile":      makefile,
+	"gnumakefile":   makefile,
+	"dockerfile":    dockerfile,
+	"containerfile": dockerfile,
+	".env":          shell,
+	"go.mod":        golang,
+	"gemfile":       ruby,
+	"rakefile":      ruby,
+}
+
+// Detect returns the language for a path, or nil if it is not recognized
+func Detect(path string) *Language {
+	base := strings.ToLower(filepath.Base(path))
+	if l, ok := byName[base]; ok {
+		return l
+	}
+	return byExtension[strings.ToLower(filepath.Ext(path))]
+}
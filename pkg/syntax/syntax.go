@@ -0,0 +1,215 @@
+// Package syntax provides lightweight, dependency-free syntax highlighting
+// for the languages gdiff recognizes. Lexers are keyword and delimiter
+// tables rather than full grammars: good enough to color diff fragments,
+// which are rarely complete programs anyway.
+package syntax
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Kind classifies a highlighted token
+type Kind int
+
+const (
+	Plain Kind = iota
+	Keyword
+	Type
+	Builtin
+	Function
+	String
+	Number
+	Comment
+)
+
+// Token is a highlighted span of a line. Start and End are rune indices.
+type Token struct {
+	Kind  Kind
+	Start int
+	End   int
+}
+
+// State carries an unterminated block comment or multi-line string from one
+// line to the next. The zero value means no construct is open.
+type State struct {
+	Kind  Kind
+	Close string
+}
+
+// MaxLineLength is the longest line that is tokenized; longer lines (usually
+// minified or generated code) are left plain
+const MaxLineLength = 2000
+
+// Tokenize highlights one line, starting in state, and returns the tokens
+// along with the state at the end of the line. A nil language yields no tokens.
+func (l *Language) Tokenize(line string, state State) ([]Token, State) {
+	if l == nil || len(line) > MaxLineLength {
+		return nil, State{}
+	}
+
+	runes := []rune(line)
+	var tokens []Token
+	i := 0
+
+	// Finish a construct left open by a previous line
+	if state.Close != "" {
+		end, closed := findClose(runes, 0, state.Close, state.Kind == String)
+		tokens = append(tokens, Token{Kind: state.Kind, Start: 0, End: end})
+		if !closed {
+			return tokens, state
+		}
+		i = end
+		state = State{}
+	}
+
+	for i < len(runes) {
+		r := runes[i]
+
+		if unicode.IsSpace(r) {
+			i++
+			continue
+		}
+
+		if hasPrefixAt(runes, i, l.LineComment...) != "" {
+			tokens = append(tokens, Token{Kind: Comment, Start: i, End: len(runes)})
+			break
+		}
+
+		if open, close := l.blockAt(runes, i); open != "" {
+			end, closed := findClose(runes, i+runeLen(open), close, false)
+			tokens = append(tokens, Token{Kind: Comment, Start: i, End: end})
+			if !closed {
+				return tokens, State{Kind: Comment, Close: close}
+			}
+			i = end
+			continue
+		}
+
+		if open, close := l.multiStringAt(runes, i); open != "" {
+			end, closed := findClose(runes, i+runeLen(open), close, false)
+			tokens = append(tokens, Token{Kind: String, Start: i, End: end})
+			if !closed {
+				return tokens, State{Kind: String, Close: close}
+			}
+			i = end
+			continue
+		}
+
+		if strings.ContainsRune(l.Quotes, r) {
+			end, _ := findClose(runes, i+1, string(r), true)
+			tokens = append(tokens, Token{Kind: String, Start: i, End: end})
+			i = end
+			continue
+		}
+
+		if unicode.IsDigit(r) {
+			start := i
+			for i < len(runes) && isNumberRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, Token{Kind: Number, Start: start, End: i})
+			continue
+		}
+
+		if isIdentStart(r) {
+			start := i
+			for i < len(runes) && isIdentRune(runes[i]) {
+				i++
+			}
+			if kind := l.classify(string(runes[start:i]), runes, i); kind != Plain {
+				tokens = append(tokens, Token{Kind: kind, Start: start, End: i})
+			}
+			continue
+		}
+
+		i++
+	}
+
+	return tokens, State{}
+}
+
+// classify returns the kind of an identifier ending at rune index end
+func (l *Language) classify(word string, runes []rune, end int) Kind {
+	if !l.CaseSensitive {
+		word = strings.ToLower(word)
+	}
+	switch {
+	case l.keywords[word]:
+		return Keyword
+	case l.types[word]:
+		return Type
+	case l.builtins[word]:
+		return Builtin
+	}
+	for end < len(runes) && runes[end] == ' ' {
+		end++
+	}
+	if end < len(runes) && runes[end] == '(' {
+		return Function
+	}
+	return Plain
+}
+
+func (l *Language) blockAt(runes []rune, i int) (open, close string) {
+	for _, pair := range l.BlockComments {
+		if hasPrefixAt(runes, i, pair[0]) != "" {
+			return pair[0], pair[1]
+		}
+	}
+	return "", ""
+}
+
+func (l *Language) multiStringAt(runes []rune, i int) (open, close string) {
+	for _, pair := range l.MultiLineStrings {
+		if hasPrefixAt(runes, i, pair[0]) != "" {
+			return pair[0], pair[1]
+		}
+	}
+	return "", ""
+}
+
+// findClose scans from start for close and returns the index just past it.
+// If close is not found the construct runs to the end of the line.
+func findClose(runes []rune, start int, close string, escapes bool) (end int, closed bool) {
+	for i := start; i < len(runes); i++ {
+		if escapes && runes[i] == '\\' {
+			i++
+			continue
+		}
+		if hasPrefixAt(runes, i, close) != "" {
+			return i + runeLen(close), true
+		}
+	}
+	return len(runes), false
+}
+
+// hasPrefixAt returns the first prefix found at rune index i, or ""
+func hasPrefixAt(runes []rune, i int, prefixes ...string) string {
+	for _, p := range prefixes {
+		pr := []rune(p)
+		if len(pr) == 0 || i+len(pr) > len(runes) {
+			continue
+		}
+		if string(runes[i:i+len(pr)]) == p {
+			return p
+		}
+	}
+	return ""
+}
+
+func runeLen(s string) int {
+	return len([]rune(s))
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || r == '$' || r == '@' || unicode.IsLetter(r)
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isNumberRune(r rune) bool {
+	return r == '.' || r == '_' || unicode.IsDigit(r) || unicode.IsLetter(r)
+}
@@ -0,0 +1,99 @@
+package syntax
+
+import (
+	"strings"
+	"testing"
+)
+
+// spans renders tokens as "kind:text" pairs for compact comparisons
+func spans(line string, tokens []Token) string {
+	names := map[Kind]string{
+		Keyword: "kw", Type: "type", Builtin: "builtin", Function: "fn",
+		String: "str", Number: "num", Comment: "comment",
+	}
+	runes := []rune(line)
+	var parts []string
+	for _, t := range tokens {
+		parts = append(parts, names[t.Kind]+":"+string(runes[t.Start:t.End]))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		line string
+		want string
+	}{
+		{"go func", "main.go", `func main() { return len(x) }`, "kw:func fn:main kw:return builtin:len"},
+		{"go string and comment", "main.go", `s := "a \"q\"" // done`, `str:"a \"q\"" comment:// done`},
+		{"go number and type", "main.go", `var n int = 42`, "kw:var type:int num:42"},
+		{"python", "app.py", `def f(x): return None  # hi`, "kw:def fn:f kw:return builtin:None comment:# hi"},
+		{"sql is case insensitive", "q.sql", `SELECT id FROM users`, "kw:SELECT kw:FROM"},
+		{"unicode offsets", "main.go", `x := "héllo" + y`, `str:"héllo"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, _ := Detect(tt.path).Tokenize(tt.line, State{})
+			if got := spans(tt.line, tokens); got != tt.want {
+				t.Errorf("Tokenize(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizeCarriesState(t *testing.T) {
+	lang := Detect("main.go")
+
+	tokens, state := lang.Tokenize("x := 1 /* start", State{})
+	if state.Kind != Comment {
+		t.Fatalf("expected open comment state, got %+v", state)
+	}
+	if got := spans("x := 1 /* start", tokens); got != "num:1 comment:/* start" {
+		t.Errorf("first line = %q", got)
+	}
+
+	line := "still comment */ return"
+	tokens, state = lang.Tokenize(line, state)
+	if state != (State{}) {
+		t.Errorf("comment should close, got %+v", state)
+	}
+	if got := spans(line, tokens); got != "comment:still comment */ kw:return" {
+		t.Errorf("second line = %q", got)
+	}
+}
+
+func TestDetectUnknown(t *testing.T) {
+	if lang := Detect("notes.txt"); lang != nil {
+		t.Errorf("Detect(notes.txt) = %s, want nil", lang.Name)
+	}
+	tokens, _ := Detect("notes.txt").Tokenize("func main()", State{})
+	if tokens != nil {
+		t.Errorf("unknown language should not produce tokens, got %v", tokens)
+	}
+	if Detect("Makefile") == nil {
+		t.Error("Makefile should be recognized by name")
+	}
+}
+
+func TestDetectNames(t *testing.T) {
+	tests := map[string]string{
+		"main.go":            "go",
+		"Program.cs":         "csharp",
+		"App.scala":          "scala",
+		"build.gradle":       "groovy",
+		"Makefile":           "make",
+		"rules.mk":           "make",
+		"Dockerfile":         "dockerfile",
+		"api.dockerfile":     "dockerfile",
+		"src/widget.hpp":     "cpp",
+		"scripts/install.sh": "shell",
+	}
+	for path, want := range tests {
+		if lang := Detect(path); lang == nil || lang.Name != want {
+			t.Errorf("Detect(%s) = %v, want %s", path, lang, want)
+		}
+	}
+}